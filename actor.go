@@ -0,0 +1,29 @@
+package adapter
+
+import "context"
+
+// actorKey is the context key of the actor set with WithActor.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor performing policy changes, e.g. the
+// user of a request. Changes made with the context-aware methods of the adapter, e.g.
+// AddPolicyCtx, record the actor in their change events, and thus in the change log, the
+// outbox and the payloads of notifiers such as webhooks, instead of Config.Actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set with WithActor, or "" if ctx carries none.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// actor returns the actor of the changes made with ctx: the actor set with WithActor, or
+// Config.Actor.
+func (a *adapter) actor(ctx context.Context) string {
+	if actor := ActorFrom(ctx); actor != "" {
+		return actor
+	}
+	return a.config.Actor
+}
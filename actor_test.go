@@ -0,0 +1,66 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithActor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_actor/id",
+		Actor:     "policy-service",
+		Notifiers: []Notifier{n},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicyCtx(WithActor(ctx, "alice@corp"), "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicyCtx() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicyCtx(WithActor(ctx, "bob@corp"), "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Expected UpdatePolicyCtx() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	want := []string{"alice@corp", "bob@corp", "policy-service"}
+	if len(n.events) != len(want) {
+		t.Fatalf("Expected %d events; got %v", len(want), n.events)
+	}
+	for i, e := range n.events {
+		if e.Actor != want[i] {
+			t.Errorf("Expected event %d (%s) to record actor %q; got %q", i, e.Operation, want[i], e.Actor)
+		}
+	}
+
+	// Buffered changes record the actor of the change, not of the flush.
+	b, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_actor_buffered/id",
+		WriteBehind: time.Hour,
+		Notifiers:   []Notifier{n},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	n.events = nil
+	if err := b.AddPolicyCtx(WithActor(ctx, "carol@corp"), "p", "p", []string{"carol", "data2", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicyCtx() to be successful; got %v", err)
+	}
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Expected Flush() to be successful; got %v", err)
+	}
+	if len(n.events) != 1 || n.events[0].Actor != "carol@corp" {
+		t.Errorf("Expected the buffered event to record its actor; got %v", n.events)
+	}
+
+	if got := ActorFrom(ctx); got != "" {
+		t.Errorf("Expected no actor; got %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"cmp"
 	"context"
 	//nolint:gosec // we don't need a secure hash, hence we use md5
 	"crypto/md5"
@@ -9,12 +10,17 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
+	"gocloud.dev/blob"
 	"gocloud.dev/docstore"
 )
 
@@ -24,14 +30,40 @@ const (
 
 // CasbinRule represents a rule in Casbin.
 type CasbinRule struct {
-	PType string `docstore:"ptype"`
-	V0    string `docstore:"v0"`
-	V1    string `docstore:"v1,omitempty"`
-	V2    string `docstore:"v2,omitempty"`
-	V3    string `docstore:"v3,omitempty"`
-	V4    string `docstore:"v4,omitempty"`
-	V5    string `docstore:"v5,omitempty"`
-	ID    string `docstore:"id"`
+	PType string `json:"ptype"        docstore:"ptype"`
+	V0    string `json:"v0"           docstore:"v0"`
+	V1    string `json:"v1,omitempty" docstore:"v1,omitempty"`
+	V2    string `json:"v2,omitempty" docstore:"v2,omitempty"`
+	V3    string `json:"v3,omitempty" docstore:"v3,omitempty"`
+	V4    string `json:"v4,omitempty" docstore:"v4,omitempty"`
+	V5    string `json:"v5,omitempty" docstore:"v5,omitempty"`
+	ID    string `json:"id"           docstore:"id"`
+	// the number of rule values, stored only when Config.PreserveEmptyValues is set
+	FieldCount int `json:"n,omitempty" docstore:"n,omitempty"`
+	// the rule values, stored instead of v0, ..., v5 when Config.Schema is SchemaArray
+	Values []string `json:"values,omitempty" docstore:"values,omitempty"`
+	// the model section of the rule (e.g. "p" or "g"); not stored by earlier versions
+	Sec string `json:"sec,omitempty" docstore:"sec,omitempty"`
+	// the Unix time in milliseconds from which the rule is loaded (0 if unbounded)
+	EffectiveFrom int64 `json:"effective_from,omitempty" docstore:"effective_from,omitempty"`
+	// the Unix time in milliseconds from which the rule is no longer loaded (0 if unbounded)
+	ExpiresAt int64 `json:"expires_at,omitempty" docstore:"expires_at,omitempty"`
+	// the expiry read by providers with native TTL, set when Config.RuleTTL is set (see [TTLField])
+	ExpireAt interface{} `json:"expire_at,omitempty" docstore:"expire_at,omitempty"`
+	// arbitrary metadata attached to the rule, such as its owner or a ticket ID
+	Meta map[string]string `json:"meta,omitempty" docstore:"meta,omitempty"`
+	// whether the rule carries data that is not part of the model (a validity window, TTL or metadata)
+	Annotated bool `json:"annotated,omitempty" docstore:"annotated,omitempty"`
+	// the JSON encoded change event of an outbox document, which is not a rule (see Config.Outbox)
+	Event string `json:"event,omitempty" docstore:"event,omitempty"`
+	// the schema version recorded by the schema version marker, which is not a rule (see Migrate)
+	SchemaVersion int `json:"schema_version,omitempty" docstore:"schema_version,omitempty"`
+	// the namespace of the document, set when Config.Namespace is set
+	Namespace string `json:"ns,omitempty" docstore:"ns,omitempty"`
+	// the load order of the rule, lowest first, set when Config.Priorities is set (see UpdatePriority)
+	Priority int64 `json:"priority,omitempty" docstore:"priority,omitempty"`
+	// the revision of a lock lease, which is not a rule (see Config.Lock); unset on rules
+	Revision interface{} `json:"-" docstore:"DocstoreRevision,omitempty"`
 }
 
 // Adapter is the interface for Casbin adapters supporting [batch], [filtered] and [auto-save] features.
@@ -48,16 +80,52 @@ type Adapter interface {
 	persist.UpdatableAdapter
 }
 
-var _ Adapter = (*adapter)(nil)
+var (
+	_ Adapter                         = (*adapter)(nil)
+	_ persist.ContextBatchAdapter     = (*adapter)(nil)
+	_ persist.ContextFilteredAdapter  = (*adapter)(nil)
+	_ persist.ContextUpdatableAdapter = (*adapter)(nil)
+)
 
 // adapter implements [Adapter].
 type adapter struct {
-	collection *docstore.Collection
-	timeout    time.Duration
-	filtered   bool
-	config     *Config
+	collection     *docstore.Collection
+	grouping       *docstore.Collection   // the collection of grouping rules, if stored separately
+	shards         []*docstore.Collection // the collections rules are sharded across, if sharding is enabled
+	filtered       bool
+	config         *Config
+	live           *atomic.Pointer[settings] // the settings in effect, changed by the variable at Config.SettingsURL
+	stopSettings   func()                    // stops watching the variable at Config.SettingsURL, if set
+	lock           *locker
+	history        *docstore.Collection
+	pending        *docstore.Collection
+	archive        *docstore.Collection
+	changes        *docstore.Collection
+	journal        *docstore.Collection
+	models         *docstore.Collection                      // the collection of model versions, if Config.ModelURL is set
+	bucket         *blob.Bucket                              // the bucket of the policy snapshot, if Config.SnapshotURL is set
+	snapshotLoaded atomic.Bool                               // whether the first unfiltered load has tried the snapshot
+	beforeRead     func(asFunc func(interface{}) bool) error // applies Config.ReadConsistency to read requests
+	plan           PlanFunc                                  // reports the query fallbacks of the provider, if Config.QueryFallback is set
+	fallbacks      sync.Map                                  // the query fallbacks already logged
+	duplicates     atomic.Pointer[[]DuplicateRule]           // the duplicates found by the last load, if Config.DedupLoads is set
+	indexError     IndexErrorFunc                            // recognizes the missing-index errors of the provider, if it has any
+	txn            TxnFunc                                   // runs transactions, if Config.Transactions is set
+	throttle       ThrottleFunc                              // recognizes throttling errors of the provider
+	throttleStats  throttleCounters
+	ops            operations   // the operations in progress, awaited by Shutdown
+	buffer         *writeBuffer // the changes not written yet, if Config.WriteBehind is set
+	breaker        *breaker     // the circuit breaker around backend calls, if Config.BreakerThreshold is set
+	priorityClock  atomic.Int64 // the last priority assigned by nextPriority
+	preload        preloadState // the load started by Preload
 }
 
+// CloudAdapter is the adapter returned by the constructors, such as New. Besides [Adapter]
+// and the context-aware adapter interfaces of Casbin, it has the methods of the features
+// configured by [Config], such as Shutdown, HealthCheck or LoadPolicySection, so that
+// packages wrapping the constructors, like the driver packages, can return it.
+type CloudAdapter = adapter
+
 // finalizer is the destructor for adapter.
 func finalizer(a *adapter) {
 	a.close()
@@ -65,8 +133,8 @@ func finalizer(a *adapter) {
 
 // NewFilteredAdapter is the constructor for FilteredAdapter.
 // Casbin will not automatically call LoadPolicy() for a filtered adapter.
-func NewFilteredAdapter(ctx context.Context, url string) (*adapter, error) {
-	a, err := NewWithOption(ctx, &Config{URL: url})
+func NewFilteredAdapter(ctx context.Context, url string, opts ...Option) (*CloudAdapter, error) {
+	a, err := New(ctx, url, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,35 +145,250 @@ func NewFilteredAdapter(ctx context.Context, url string) (*adapter, error) {
 
 // Config is the configuration for Adapter.
 type Config struct {
-	Timeout    time.Duration // the timeout for any operations on the adapter
-	IsFiltered bool          // whether the adapter is filtered
-	URL        string        // the driver url (e.g. mongodb://localhost:27017)
+	Timeout             time.Duration   // the timeout for any operations on the adapter
+	Timeouts            *Timeouts       // the timeouts of reads, writes and bulk operations, overriding Timeout for them (nil applies Timeout)
+	IsFiltered          bool            // whether the adapter is filtered
+	URL                 string          // the driver url (e.g. mongodb://localhost:27017), which may also set Timeout, IsFiltered and Namespace (see New)
+	GroupingURL         string          // the driver url of the collection holding grouping ("g") rules (stored with the other rules if empty)
+	ShardURLs           []string        // the driver urls of additional collections that rules are sharded across with URL by ID hash; changing them requires re-saving the policy
+	Namespace           string          // the namespace of the documents, so several models can share a collection (no namespace if empty)
+	RateLimit           float64         // the maximum number of write operations per second (0 disables rate limiting)
+	RateBurst           int             // the maximum burst of write operations (defaults to RateLimit, at least 1)
+	Lock                bool            // whether SavePolicy holds a lock stored alongside the rules, so that only one instance saves at a time
+	LockTTL             time.Duration   // the duration after which a SavePolicy lock that is no longer renewed expires
+	HistoryURL          string          // the driver url of the collection holding policy versions (disabled if empty)
+	IDStrategy          IDStrategy      // how document IDs are assigned to rules (defaults to IDStrategyHash)
+	InPlaceUpdates      bool            // whether UpdatePolicy updates changed values in place (requires IDStrategyRandom)
+	BatchSize           int             // the maximum number of writes sent in one action list (0 disables the limit; see WithStrategy)
+	WriteMode           WriteMode       // how AddPolicy and AddPolicies write rules (defaults to WriteUpsert)
+	RemoveMode          RemoveMode      // how RemovePolicy and RemovePolicies handle rules that are not stored (defaults to RemoveIgnoreMissing)
+	PreserveEmptyValues bool            // whether to store the number of rule values, so empty values round-trip exactly
+	Schema              Schema          // how rule values are stored in documents (defaults to SchemaColumns)
+	RuleTTL             time.Duration   // the lifetime of rules added with AddPolicy and AddPolicies (0 disables expiry)
+	PendingURL          string          // the driver url of the collection holding staged policy changes (disabled if empty)
+	Notifiers           []Notifier      // the notifiers called after successful policy changes
+	Actor               string          // the actor reported in change events (e.g. the service name), unless set per change with WithActor
+	Outbox              bool            // whether change events are written to an outbox with each change and delivered by RunOutbox
+	SubjectKey          []byte          // if set, subjects (v0) are stored as a keyed hash (see HashSubject) instead of plaintext
+	SubjectResolver     SubjectResolver // maps stored subject hashes back to subjects when loading (hashes are loaded as stored if nil)
+	AutoMigrate         bool            // whether pending schema migrations are run when the adapter is opened (see Migrate)
+	AutoSchema          bool            // whether the provider-side schema, e.g. the indexes, is configured when the adapter is opened (see EnsureSchema)
+	RecommendedIndexes  bool            // whether EnsureSchema creates the indexes that the queries of the adapter need, on providers that know them (MongoDB), so that filtered removals do not scan the collection
+	ArchiveURL          string          // the driver url of the collection removed rules are copied to before deletion (disabled if empty)
+	ChangeLogURL        string          // the driver url of the collection recording change events for Changes (disabled if empty)
+	ReadConsistency     Consistency     // the consistency of reads, applied by the ReadOptionsFunc of the provider (defaults to the provider default)
+	Openers             URLOpeners      // the openers of collection urls by scheme, overriding docstore.DefaultURLMux (e.g. to pass driver options)
+	Transactions        bool            // whether SavePolicy and UpdateFilteredPolicies write in a provider transaction (requires a TxnFunc, e.g. MongoDB replica sets)
+	MaxRetries          int             // the number of times a request throttled by the provider is retried (0 disables retries)
+	RetryBackoff        time.Duration   // the backoff before the first retry of a throttled request, doubled on each retry unless the provider hints a wait (defaults to 100ms)
+	WriteBehind         time.Duration   // if set, rules added and removed with AddPolicy(ies) and RemovePolicy(ies) are buffered and written in batches at this interval (see Flush); buffered changes are lost if the process exits before they are flushed, unless JournalURL is set
+	WriteBehindLimit    int             // the number of buffered rules that triggers an early flush (defaults to 1000)
+	BreakerThreshold    int             // the number of consecutive backend failures that open the circuit breaker, which fails calls with ErrCircuitOpen until a probe succeeds (0 disables the breaker)
+	BreakerCooldown     time.Duration   // how long the open circuit breaker fails calls before a call probes the backend (defaults to 30s)
+	StaleLoads          bool            // whether unfiltered loads serve the last loaded policy while the circuit breaker is open
+	SlowThreshold       time.Duration   // the duration from which operations are logged as slow, with a summary of their filter and the number of rules, to help find missing indexes (0 disables slow-operation logging)
+	Logger              *log.Logger     // the logger of the warnings and background errors of the adapter, such as slow operations, query fallbacks, duplicate rules and failed notifications (defaults to the standard logger)
+	Indexes             []Index         // the secondary indexes of the rule collections that EnsureSchema creates, e.g. DynamoDB global secondary indexes, which serve filtered queries instead of table scans once active; collections opened before an index is active do not use it
+	QueryFallback       Fallback        // how filtered loads, removals and updates that the provider cannot execute natively (e.g. DynamoDB scans) are handled, reported by the PlanFunc of the provider (defaults to FallbackAllow)
+	MaxRules            int             // the maximum number of rules of the namespace, enforced by AddPolicy and AddPolicies with a QuotaError (0 disables the quota)
+	MaxTotalRules       int             // the maximum number of rules of every namespace in the collections, enforced like MaxRules (0 disables the quota)
+	MaxLoadRules        int             // the maximum number of rules a load may load, failing with ErrTooManyRules beyond it, e.g. when the url points at the wrong collection (0 disables the limit)
+	DedupLoads          bool            // whether loads skip rules stored more than once under different IDs, reporting them (see Duplicates)
+	OrderedLoads        bool            // whether loads order the rules of each collection by ID, so that models with priority(p.eff) behave the same across providers (providers may require an index for the order, e.g. Firestore composite indexes for filtered loads)
+	Priorities          bool            // whether rules store a priority that orders them on load, for models with priority(p.eff); added rules are ordered after the stored ones (see UpdatePriority)
+	ReadOnly            bool            // whether the operations that change stored data fail with ErrReadOnly, while loads work normally
+	JournalURL          string          // the driver url of a collection local to the instance (e.g. an sqlitedoc file) journaling buffered changes until they are written, so they are replayed on the next start after a crash (requires WriteBehind; disabled if empty)
+	SnapshotURL         string          // the blob bucket url (e.g. gs://bucket?prefix=casbin/) of a gzipped snapshot of the policy, written after each SavePolicy and loaded by the first unfiltered load, which then reconciles it with the collections in the background, to cut cold-start loads of large policies (disabled if empty)
+	OnLoadProgress      ProgressFunc    // receives the progress of loads (rules loaded so far, elapsed time), e.g. to surface multi-minute loads in logs and health endpoints (disabled if nil; see WithLoadProgress)
+	ProgressInterval    time.Duration   // the interval at which OnLoadProgress is called while a load runs (defaults to 5s)
+	OnSnapshotStale     func()          // called when the reconciliation finds that the policy loaded from the snapshot differs from the stored policy, e.g. to reload the enforcer (logged if nil)
+	ModelURL            string          // the driver url of the collection holding versions of the model definition, so services load the model from the backend of the policy (disabled if empty; see SaveModel)
+	SettingsURL         string          // the runtimevar url of a JSON variable overriding Timeout, Timeouts, RateLimit, RateBurst and ReadOnly, whose changes apply without recreating the adapter, e.g. to tune it during incidents (disabled if empty; see WithSettings)
+	Interceptors        []Interceptor   // the interceptors wrapping the operations of the Casbin adapter interfaces, outermost first (see WithInterceptor)
+	Codec               RuleCodec       // how rules map to the documents of the rule collections, e.g. to use an existing schema (stored as CasbinRule if nil)
+}
+
+// logger returns Config.Logger, or the standard logger if it is not set.
+func (c *Config) logger() *log.Logger {
+	if c.Logger == nil {
+		return log.Default()
+	}
+	return c.Logger
 }
 
+// Option configures an adapter created by New or NewFilteredAdapter.
+type Option func(*Config)
+
 // New is the constructor for Adapter.
-func New(ctx context.Context, url string) (*adapter, error) {
-	return NewWithOption(ctx, &Config{URL: url})
+//
+// Besides the query parameters of the provider, the url may configure the adapter with the
+// following query parameters, which are removed before the url is passed to the provider:
+//
+//   - adapter_timeout: Config.Timeout, as a duration (e.g. 10s)
+//   - adapter_filtered: Config.IsFiltered, as a boolean
+//   - adapter_namespace: Config.Namespace
+//
+// Options take precedence over the url parameters.
+func New(ctx context.Context, url string, opts ...Option) (*CloudAdapter, error) {
+	config := &Config{URL: url}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return NewWithOption(ctx, config)
 }
 
 // NewWithOption is the constructor for Adapter with option.
-func NewWithOption(ctx context.Context, config *Config) (*adapter, error) {
+func NewWithOption(ctx context.Context, config *Config) (*CloudAdapter, error) {
 	if config == nil {
 		config = &Config{}
 	}
+	if err := applyURLParams(config); err != nil {
+		return nil, fmt.Errorf("could not parse url: %v", redactError(err, config.URL))
+	}
 	if config.Timeout == 0 {
 		config.Timeout = defaultTimeout
 	}
+	if config.JournalURL != "" && config.WriteBehind <= 0 {
+		return nil, errors.New("the journal requires write-behind to be enabled")
+	}
+	if config.ReadOnly && (config.AutoMigrate || config.AutoSchema || config.JournalURL != "") {
+		return nil, errors.New("a read-only adapter cannot migrate, configure the schema or replay a journal")
+	}
+	var txn TxnFunc
+	if config.Transactions {
+		var err error
+		if txn, err = txnFunc(config); err != nil {
+			return nil, err
+		}
+	}
 
-	coll, err := docstore.OpenCollection(ctx, config.URL)
+	coll, err := openCollection(ctx, config, config.URL)
 	if err != nil {
-		return nil, fmt.Errorf("could not open collection: %v", err)
+		return nil, fmt.Errorf("could not open collection: %v", redactError(err, config.URL))
 	}
 
 	a := &adapter{
 		collection: coll,
-		timeout:    config.Timeout,
 		filtered:   config.IsFiltered,
 		config:     config,
+		live:       new(atomic.Pointer[settings]),
+		beforeRead: newBeforeRead(config),
+		plan:       newPlanFunc(config),
+		indexError: newIndexErrorFunc(config),
+		txn:        txn,
+		throttle:   newThrottle(config),
+		buffer:     newWriteBuffer(config),
+		breaker:    newBreaker(config),
+	}
+	a.live.Store(newSettings(config))
+
+	if config.SettingsURL != "" {
+		a.stopSettings, err = openSettings(ctx, config, a.live)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open settings: %v", redactError(err, config.SettingsURL))
+		}
+	}
+
+	if config.GroupingURL != "" {
+		a.grouping, err = openCollection(ctx, config, config.GroupingURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open grouping collection: %v", redactError(err, config.GroupingURL))
+		}
+	}
+
+	if len(config.ShardURLs) > 0 {
+		a.shards = append(a.shards, a.collection)
+		for i, shardURL := range config.ShardURLs {
+			shard, err := openCollection(ctx, config, shardURL)
+			if err != nil {
+				a.close()
+				return nil, fmt.Errorf("could not open shard collection %d: %v", i+1, redactError(err, shardURL))
+			}
+			a.shards = append(a.shards, shard)
+		}
+	}
+
+	if config.Lock {
+		if config.LockTTL == 0 {
+			config.LockTTL = defaultLockTTL
+		}
+		a.lock = newLocker(a, config.LockTTL)
+	}
+
+	if config.HistoryURL != "" {
+		a.history, err = openCollection(ctx, config, config.HistoryURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open history collection: %v", redactError(err, config.HistoryURL))
+		}
+	}
+
+	if config.PendingURL != "" {
+		a.pending, err = openCollection(ctx, config, config.PendingURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open pending collection: %v", redactError(err, config.PendingURL))
+		}
+	}
+
+	if config.ArchiveURL != "" {
+		a.archive, err = openCollection(ctx, config, config.ArchiveURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open archive collection: %v", redactError(err, config.ArchiveURL))
+		}
+	}
+
+	if config.ChangeLogURL != "" {
+		a.changes, err = openCollection(ctx, config, config.ChangeLogURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open change log collection: %v", redactError(err, config.ChangeLogURL))
+		}
+	}
+
+	if config.SnapshotURL != "" {
+		a.bucket, err = openSnapshotBucket(ctx, config)
+		if err != nil {
+			a.close()
+			return nil, err
+		}
+	}
+
+	if config.ModelURL != "" {
+		a.models, err = openCollection(ctx, config, config.ModelURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open model collection: %v", redactError(err, config.ModelURL))
+		}
+	}
+
+	if config.AutoMigrate {
+		if err := a.Migrate(ctx); err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not migrate collection: %w", err)
+		}
+	}
+
+	if config.AutoSchema {
+		if err := a.EnsureSchema(ctx); err != nil {
+			a.close()
+			return nil, err
+		}
+	}
+
+	if config.JournalURL != "" {
+		a.journal, err = openCollection(ctx, config, config.JournalURL)
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not open journal collection: %v", redactError(err, config.JournalURL))
+		}
+		if err := a.replayJournal(ctx); err != nil {
+			a.close()
+			return nil, fmt.Errorf("could not replay journal: %w", err)
+		}
 	}
 
 	// Call the destructor when the object is released.
@@ -114,17 +397,205 @@ func NewWithOption(ctx context.Context, config *Config) (*adapter, error) {
 	return a, nil
 }
 
+// URLOpeners are collection url openers by url scheme.
+type URLOpeners map[string]docstore.CollectionURLOpener
+
+// openCollection opens the collection at the url with the opener configured for its
+// scheme, or with docstore.DefaultURLMux.
+func openCollection(ctx context.Context, config *Config, rawURL string) (*docstore.Collection, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if opener, ok := config.Openers[u.Scheme]; ok {
+		return opener.OpenCollectionURL(ctx, u)
+	}
+	return docstore.OpenCollection(ctx, rawURL)
+}
+
 func (a *adapter) close() {
+	a.ops.mu.Lock()
+	a.ops.shutdown = true
+	a.ops.mu.Unlock()
+	if a.stopSettings != nil {
+		a.stopSettings()
+		a.stopSettings = nil
+	}
+	if a.buffer != nil {
+		if batch := a.buffer.take(); len(batch.changes) > 0 && a.journal == nil {
+			a.config.logger().Printf("close discarded %d buffered changes", len(batch.changes))
+		}
+	}
 	if a.collection != nil {
 		err := a.collection.Close()
 		if err != nil {
-			log.Printf("close collection error: %v", err)
+			a.config.logger().Printf("close collection error: %v", a.redact(err))
 		}
 		a.collection = nil
 	}
+	for _, shard := range a.shards[min(1, len(a.shards)):] { // the first shard is the primary collection
+		err := shard.Close()
+		if err != nil {
+			a.config.logger().Printf("close shard collection error: %v", a.redact(err))
+		}
+	}
+	a.shards = nil
+	if a.grouping != nil {
+		err := a.grouping.Close()
+		if err != nil {
+			a.config.logger().Printf("close grouping collection error: %v", a.redact(err))
+		}
+		a.grouping = nil
+	}
+	if a.history != nil {
+		err := a.history.Close()
+		if err != nil {
+			a.config.logger().Printf("close history collection error: %v", a.redact(err))
+		}
+		a.history = nil
+	}
+	if a.pending != nil {
+		err := a.pending.Close()
+		if err != nil {
+			a.config.logger().Printf("close pending collection error: %v", a.redact(err))
+		}
+		a.pending = nil
+	}
+	if a.archive != nil {
+		err := a.archive.Close()
+		if err != nil {
+			a.config.logger().Printf("close archive collection error: %v", a.redact(err))
+		}
+		a.archive = nil
+	}
+	if a.changes != nil {
+		err := a.changes.Close()
+		if err != nil {
+			a.config.logger().Printf("close change log collection error: %v", a.redact(err))
+		}
+		a.changes = nil
+	}
+	if a.journal != nil {
+		err := a.journal.Close()
+		if err != nil {
+			a.config.logger().Printf("close journal collection error: %v", a.redact(err))
+		}
+		a.journal = nil
+	}
+	if a.models != nil {
+		err := a.models.Close()
+		if err != nil {
+			a.config.logger().Printf("close model collection error: %v", a.redact(err))
+		}
+		a.models = nil
+	}
+	if a.bucket != nil {
+		err := a.bucket.Close()
+		if err != nil {
+			a.config.logger().Printf("close snapshot bucket error: %v", a.redact(err))
+		}
+		a.bucket = nil
+	}
+}
+
+// Collection returns the underlying [docstore.Collection] used by the adapter.
+//
+// It is intended as an escape hatch for advanced use cases, such as running custom
+// queries or maintenance tasks, without opening a second connection to the same backend.
+// The collection is owned by the adapter and must not be closed by the caller. If
+// Config.GroupingURL or Config.ShardURLs are set, rules are also stored in other collections.
+func (a *adapter) Collection() *docstore.Collection {
+	return a.collection
+}
+
+// HealthCheck performs a cheap read against the collection and reports whether
+// the policy store is reachable. It is suitable for wiring into readiness probes.
+func (a *adapter) HealthCheck(ctx context.Context) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	for _, coll := range a.ruleCollections() {
+		if err := a.probe(ctx, coll); err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// probe reads at most one document of the collection.
+func (a *adapter) probe(ctx context.Context, coll *docstore.Collection) error {
+	iter := coll.Query().Limit(1).Get(ctx)
+	defer iter.Stop()
+
+	var line CasbinRule
+	if err := a.next(ctx, iter, &line); err != nil && err != io.EOF {
+		return a.redact(err)
+	}
+	return nil
+}
+
+// collectRules reads all rules matched by the query.
+func (a *adapter) collectRules(ctx context.Context, query *docstore.Query) ([]CasbinRule, error) {
+	iter := a.readQuery(query).Get(ctx)
+	defer iter.Stop()
+
+	lines := make([]CasbinRule, 0)
+	for {
+		var line CasbinRule
+		err := a.next(ctx, iter, &line)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, a.queryError(err)
+		}
+		if !a.isRule(&line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// pageRules returns up to limit documents of the collection with IDs after the given ID,
+// ordered by ID, to page through the collection. Unlike [adapter.collectRules], internal
+// documents are included, so that a page shorter than limit marks the end of the collection.
+func (a *adapter) pageRules(ctx context.Context, coll *docstore.Collection, after string, limit int) ([]CasbinRule, error) {
+	query := a.scope(coll.Query())
+	if after != "" {
+		query = query.Where(a.field("id"), ">", after)
+	}
+	iter := a.readQuery(query).OrderBy(string(a.field("id")), docstore.Ascending).Limit(limit).Get(ctx)
+	defer iter.Stop()
+
+	lines := make([]CasbinRule, 0, limit)
+	for {
+		var line CasbinRule
+		err := a.next(ctx, iter, &line)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, a.queryError(err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
 }
 
 func loadPolicyLine(line CasbinRule, model model.Model) error {
+	if line.Sec != "" {
+		return loadPolicyValues(line.Sec, line.PType, line.values(), model)
+	}
+	if line.FieldCount > 0 || line.Values != nil {
+		return persist.LoadPolicyArray(append([]string{line.PType}, line.values()...), model)
+	}
+
 	p := [...]string{line.PType, line.V0, line.V1, line.V2, line.V3, line.V4, line.V5}
 
 	var lineText string
@@ -138,14 +609,75 @@ func loadPolicyLine(line CasbinRule, model model.Model) error {
 	return persist.LoadPolicyLine(lineText, model)
 }
 
+// loadPolicyValues adds a rule to the given model section, skipping duplicates.
+// Unlike [persist.LoadPolicyArray], the section is not derived from the policy type.
+func loadPolicyValues(sec, ptype string, values []string, model model.Model) error {
+	assertions, ok := model[sec]
+	if !ok {
+		return fmt.Errorf("section %q not found in model", sec)
+	}
+	if _, ok := assertions[ptype]; !ok {
+		return fmt.Errorf("policy type %q not found in section %q", ptype, sec)
+	}
+	ok, err := model.HasPolicyEx(sec, ptype, values)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil // skip duplicated policy
+	}
+	return model.AddPolicy(sec, ptype, values)
+}
+
+// sortedKeys returns the keys of the map in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 // LoadPolicy loads policy from database.
 func (a *adapter) LoadPolicy(model model.Model) error {
-	return a.LoadFilteredPolicy(model, nil)
+	return a.LoadPolicyCtx(context.Background(), model)
+}
+
+// LoadPolicyCtx is LoadPolicy with a context. The load stops when ctx is done, returning
+// its error, and ctx is passed to the interceptors.
+func (a *adapter) LoadPolicyCtx(ctx context.Context, model model.Model) error {
+	return a.intercept(ctx, OpInfo{Name: "LoadPolicy"}, func(ctx context.Context) error {
+		return a.loadFilteredPolicy(ctx, model, nil)
+	})
 }
 
 // LoadFilteredPolicy loads matching policy lines from database. If not nil,
 // the filter must be a valid MongoDB selector.
 func (a *adapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	return a.LoadFilteredPolicyCtx(context.Background(), model, filter)
+}
+
+// LoadFilteredPolicyCtx is LoadFilteredPolicy with a context, which bounds the query of the
+// matching rules: the load stops with the error of ctx once it is done.
+func (a *adapter) LoadFilteredPolicyCtx(ctx context.Context, model model.Model, filter interface{}) error {
+	return a.intercept(ctx, OpInfo{Name: "LoadFilteredPolicy"}, func(ctx context.Context) error {
+		return a.loadFilteredPolicy(ctx, model, filter)
+	})
+}
+
+// loadFilteredPolicy runs LoadFilteredPolicy within the interceptors.
+func (a *adapter) loadFilteredPolicy(ctx context.Context, model model.Model, filter interface{}) (err error) {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	op := a.trackSlow("LoadFilteredPolicy", 0)
+	defer op.done()
+	progress := a.trackProgress()
+	defer func() { progress.done(err) }()
+
 	filters := make([]Filter, 0)
 	if filter == nil {
 		a.filtered = false
@@ -165,33 +697,233 @@ func (a *adapter) LoadFilteredPolicy(model model.Model, filter interface{}) erro
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	ctx, cancel := a.withTimeout(ctx, opRead)
 	defer cancel()
+	if err := a.flush(ctx); err != nil {
+		return a.loadStale(model, err)
+	}
+
+	fromSnapshot, writeSnapshot, err := a.loadSnapshot(ctx, model)
+	if err != nil || fromSnapshot {
+		return err
+	}
 
-	query := a.collection.Query()
-	if len(filters) > 0 {
-		for _, f := range filters {
-			fieldPath := docstore.FieldPath(strings.Join(f.FieldPath, ".")) // dot seperated path (e.g. "field.subfield")
-			if f.Op == "" {                                                 // default to ==
-				f.Op = EqualOp
+	op.setFilter(summarizeFilters(filters))
+	build, valueFilters, err := a.filterQuery(filters)
+	if err != nil {
+		return err
+	}
+	var loaded *[]CasbinRule
+	if (a.breaker != nil && a.config.StaleLoads || writeSnapshot) && !a.filtered {
+		loaded = new([]CasbinRule)
+	}
+	var ordered []CasbinRule // the rules to load in priority order, if Config.Priorities is set
+	var seen *dedup
+	if a.config.DedupLoads {
+		seen = newDedup()
+	}
+	load := func(line CasbinRule) error {
+		if seen != nil && seen.skip(line) {
+			return nil
+		}
+		progress.add(1)
+		if a.config.Priorities {
+			ordered = append(ordered, line)
+			return nil
+		}
+		if loaded != nil {
+			*loaded = append(*loaded, line)
+		}
+		return loadPolicyLine(line, model)
+	}
+	now := time.Now()
+	total := 0
+	for _, coll := range a.ruleCollections() {
+		if err := a.breaker.allow(); err != nil {
+			return a.loadStale(model, err)
+		}
+		limit := -1
+		if a.config.MaxLoadRules > 0 {
+			limit = a.config.MaxLoadRules - total
+		}
+		query := a.scope(build(coll.Query()))
+		if a.config.OrderedLoads {
+			query = query.OrderBy(string(a.field("id")), docstore.Ascending)
+		}
+		if a.filtered {
+			if err := a.checkPlan(query, filters); err != nil {
+				return err
+			}
+		}
+		n, err := a.loadQuery(ctx, query, valueFilters, now, limit, load)
+		if errors.Is(err, ErrTooManyRules) {
+			a.breaker.record(nil) // the backend responded
+		} else {
+			a.breaker.record(err)
+		}
+		op.addRules(n)
+		total += n
+		if err != nil {
+			return err
+		}
+	}
+	if a.config.Priorities {
+		sortByPriority(ordered)
+		for _, line := range ordered {
+			if err := loadPolicyLine(line, model); err != nil {
+				return err
 			}
-			query = query.Where(fieldPath, f.Op, f.Value)
 		}
+		if loaded != nil {
+			*loaded = ordered
+		}
+	}
+	if loaded != nil && a.breaker != nil && a.config.StaleLoads {
+		a.breaker.setStale(*loaded)
 	}
+	if writeSnapshot {
+		a.writeSnapshot(ctx, *loaded)
+	}
+	if seen != nil {
+		a.reportDuplicates(seen)
+	}
+
+	return nil
+}
+
+// loadQuery passes the rules matched by the query and the value filters that are active at
+// time now to load, and returns the number of loaded rules. If more than limit rules match,
+// it fails with ErrTooManyRules; a negative limit loads every rule.
+func (a *adapter) loadQuery(ctx context.Context, query *docstore.Query, valueFilters []Filter, now time.Time, limit int, load func(CasbinRule) error) (int, error) {
 	iter := query.Get(ctx)
 	defer iter.Stop()
+	n := 0
 	for {
 		var line CasbinRule
-		err := iter.Next(ctx, &line)
+		err := a.next(ctx, iter, &line)
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return err
+			return n, a.queryError(err)
 		} else {
-			err = loadPolicyLine(line, model)
-			if err != nil {
-				return err
+			if !a.isRule(&line) || !matchesFilters(line, valueFilters) || !line.activeAt(now) {
+				continue
+			}
+			if limit >= 0 && n >= limit {
+				return n, a.tooManyRules()
 			}
+			if err := a.resolveLine(ctx, &line); err != nil {
+				return n, err
+			}
+			if err := load(line); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// tooManyRules returns the error of a load that exceeds Config.MaxLoadRules.
+func (a *adapter) tooManyRules() error {
+	return fmt.Errorf("%w: more than %d rules match, check the collection url and the filter (see Config.MaxLoadRules)", ErrTooManyRules, a.config.MaxLoadRules)
+}
+
+// filterQuery returns the function that adds the filters to a query, and the filters on
+// rule values that must be evaluated client-side with [SchemaArray].
+func (a *adapter) filterQuery(filters []Filter) (queryFunc, []Filter, error) {
+	var wheres []queryFunc
+	var valueFilters []Filter
+	for _, f := range filters {
+		f = a.hashFilter(f)
+		fieldPath := a.field(strings.Join(f.FieldPath, ".")) // dot seperated path (e.g. "field.subfield")
+		if f.Op == "" {                                      // default to ==
+			f.Op = EqualOp
+		}
+		if a.config.Schema == SchemaArray && valueIndex(f.FieldPath) >= 0 {
+			if f.Op != EqualOp {
+				return nil, nil, fmt.Errorf("unsupported operator %q on rule values with the array schema", f.Op)
+			}
+			valueFilters = append(valueFilters, f)
+			continue
+		}
+		wheres = append(wheres, func(query *docstore.Query) *docstore.Query {
+			return query.Where(fieldPath, f.Op, f.Value)
+		})
+	}
+	build := func(query *docstore.Query) *docstore.Query {
+		for _, where := range wheres {
+			query = where(query)
+		}
+		return query
+	}
+	return build, valueFilters, nil
+}
+
+// LoadPolicySection replaces the policies of a single model section (e.g. "g") with the
+// stored rules of that section, leaving the other sections untouched. Only the policy
+// types defined in the model section are read from storage.
+//
+// After reloading a grouping section the caller must rebuild the role links, e.g. with
+// Enforcer.BuildRoleLinks.
+func (a *adapter) LoadPolicySection(ctx context.Context, model model.Model, sec string) error {
+	return a.intercept(ctx, OpInfo{Name: "LoadPolicySection", Sec: sec}, func(ctx context.Context) error {
+		return a.loadPolicySection(ctx, model, sec)
+	})
+}
+
+// loadPolicySection runs LoadPolicySection within the interceptors.
+func (a *adapter) loadPolicySection(ctx context.Context, model model.Model, sec string) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	op := a.trackSlow("LoadPolicySection", 0)
+	defer op.done()
+
+	assertions, ok := model[sec]
+	if !ok {
+		return fmt.Errorf("section %q not found in model", sec)
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
+
+	var lines []CasbinRule
+	for _, ptype := range sortedKeys(assertions) {
+		rules, err := a.collectPType(ctx, ptype, nil)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, rules...)
+		if a.config.MaxLoadRules > 0 && len(lines) > a.config.MaxLoadRules {
+			return a.tooManyRules()
+		}
+	}
+	op.addRules(len(lines))
+	if a.config.OrderedLoads {
+		slices.SortFunc(lines, func(x, y CasbinRule) int { return strings.Compare(x.ID, y.ID) })
+	}
+	if a.config.Priorities {
+		sortByPriority(lines)
+	}
+
+	for _, ast := range assertions {
+		ast.Policy = nil
+		ast.PolicyMap = map[string]int{}
+	}
+	now := time.Now()
+	for _, line := range lines {
+		if !line.activeAt(now) {
+			continue
+		}
+		if err := a.loadLine(ctx, line, model); err != nil {
+			return err
 		}
 	}
 
@@ -203,10 +935,34 @@ func (a *adapter) IsFiltered() bool {
 	return a.filtered
 }
 
+// IsFilteredCtx is IsFiltered with a context.
+func (a *adapter) IsFilteredCtx(context.Context) bool {
+	return a.filtered
+}
+
 // generateID generates an ID for a CasbinRule.
 func generateID(line CasbinRule) string {
-	data := []byte(fmt.Sprint(line))
-	hash := md5.Sum(data) //nolint:gosec // we don't need a secure hash here
+	// The legacy layout of CasbinRule (with an empty ID) is hashed, so that adding fields
+	// does not change existing IDs.
+	cols := line.columns()
+	legacy := struct{ PType, V0, V1, V2, V3, V4, V5, ID string }{
+		line.PType, cols[0], cols[1], cols[2], cols[3], cols[4], cols[5], "",
+	}
+	data := fmt.Sprint(legacy)
+	values := line.values()
+	switch {
+	case len(values) > len(cols):
+		// Values beyond v5 can only be stored with SchemaArray.
+		data += "#" + strings.Join(values[len(cols):], "\x00")
+	case (line.FieldCount > 0 || line.Values != nil) && len(values) > 0 && values[len(values)-1] == "":
+		// Trailing empty values are only distinguishable by the field count.
+		data += fmt.Sprintf("#%d", len(values))
+	}
+	if line.Namespace != "" {
+		// The same rule is stored once per namespace.
+		data = line.Namespace + "\x00" + data
+	}
+	hash := md5.Sum([]byte(data)) //nolint:gosec // we don't need a secure hash here
 	return hex.EncodeToString(hash[:])
 }
 
@@ -226,156 +982,465 @@ func savePolicyLine(ptype string, rule []string) CasbinRule {
 	return line
 }
 
+// ruleLine returns the document for a rule with its content-derived ID, encoded
+// according to the adapter configuration.
+func (a *adapter) ruleLine(ptype string, rule []string) CasbinRule {
+	rule = a.hashRule(rule)
+	var line CasbinRule
+	if a.config.Schema == SchemaArray {
+		line = arrayLine(ptype, rule)
+	} else {
+		line = savePolicyLine(ptype, rule)
+		if a.config.PreserveEmptyValues {
+			line.FieldCount = min(len(rule), 6)
+		}
+	}
+	line.Namespace = a.config.Namespace
+	line.ID = a.contentID(line)
+	return line
+}
+
+// modelLines returns the documents for the policies of every model section, so models
+// with custom sections round-trip.
+func (a *adapter) modelLines(model model.Model) []CasbinRule {
+	var lines []CasbinRule
+	for _, sec := range sortedKeys(model) {
+		for _, ptype := range sortedKeys(model[sec]) {
+			for _, rule := range model[sec][ptype].Policy {
+				lines = append(lines, a.newLine(sec, ptype, rule))
+			}
+		}
+	}
+	return lines
+}
+
 // SavePolicy saves policy to database.
 func (a *adapter) SavePolicy(model model.Model) error {
+	return a.SavePolicyCtx(context.Background(), model)
+}
+
+// SavePolicyCtx is SavePolicy with a context. The change event of the saved policy records
+// the actor of ctx, set with [WithActor].
+func (a *adapter) SavePolicyCtx(ctx context.Context, model model.Model) error {
+	return a.intercept(ctx, OpInfo{Name: "SavePolicy", Write: true}, func(ctx context.Context) error {
+		return a.savePolicy(ctx, model)
+	})
+}
+
+// savePolicy runs SavePolicy within the interceptors.
+func (a *adapter) savePolicy(ctx context.Context, model model.Model) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	op := a.trackSlow("SavePolicy", 0)
+	defer op.done()
+
 	if a.filtered {
 		return errors.New("cannot save a filtered policy")
 	}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	ctx, cancel := a.withTimeout(ctx, opBulk)
 	defer cancel()
-	actionList := a.collection.Actions()
-	for _, typ := range [...]string{"p", "g"} {
-		if ast, ok := model[typ]; ok {
-			for ptype, ast := range ast {
-				for _, rule := range ast.Policy {
-					line := savePolicyLine(ptype, rule)
-					actionList.Put(&line)
-				}
-			}
-		}
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
+
+	ctx, release, err := a.acquireSaveLock(ctx, 0)
+	if err != nil {
+		return err
 	}
+	defer release()
 
-	if err := actionList.Do(ctx); err != nil {
+	lines := a.modelLines(model)
+	op.addRules(len(lines))
+	if err := a.reuseIDs(ctx, lines); err != nil {
+		return err
+	}
+	if err := a.preserveAnnotations(ctx, lines); err != nil {
 		return err
 	}
 
+	event := ChangeEvent{Operation: OpSavePolicy}
+	err = a.commit(ctx, func(ctx context.Context, actions []action) error {
+		return a.inTxn(ctx, func(ctx context.Context) error { return a.do(ctx, actions) })
+	}, putActions(lines), event)
+	if err != nil {
+		return lockError(ctx, err)
+	}
+
+	if a.history != nil {
+		if _, err := a.snapshot(ctx, lines); err != nil {
+			return err
+		}
+	}
+	if a.bucket != nil {
+		a.writeSnapshot(ctx, lines)
+	}
 	return nil
 }
 
 // AddPolicy adds a policy rule to the storage.
 func (a *adapter) AddPolicy(sec string, ptype string, rule []string) error {
-	line := savePolicyLine(ptype, rule)
+	return a.AddPolicyCtx(context.Background(), sec, ptype, rule)
+}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
-	defer cancel()
+// AddPolicyCtx is AddPolicy with a context, whose actor, set with [WithActor], is recorded
+// in the change event of the added rule.
+func (a *adapter) AddPolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
+	return a.intercept(ctx, OpInfo{Name: "AddPolicy", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.addPolicy(ctx, sec, ptype, rule, ruleAttrs{})
+	})
+}
 
-	if err := a.collection.Actions().Put(&line).Do(ctx); err != nil {
+// addPolicy runs AddPolicy within the interceptors, adding the rule with the attributes.
+func (a *adapter) addPolicy(ctx context.Context, sec string, ptype string, rule []string, attrs ruleAttrs) error {
+	if err := a.beginWrite(); err != nil {
 		return err
 	}
+	defer a.end()
 
-	return nil
+	defer a.trackSlow("AddPolicy", 1).done()
+
+	event := ChangeEvent{Operation: OpAddPolicy, Sec: sec, PType: ptype, Rules: [][]string{rule}}
+	if err := a.checkQuota(ctx, ptype, event.Rules); err != nil {
+		return err
+	}
+	if a.buffer != nil {
+		return a.bufferChanges(ctx, event, attrs)
+	}
+
+	line := a.newLine(sec, ptype, rule)
+	a.setTTL(&line, time.Now())
+	a.setAttrs(&line, attrs)
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	return existsError(a.commit(ctx, a.do, []action{{kind: a.addKind(), line: &line}}, event))
 }
 
 // AddPolicies adds policy rules to the storage.
 func (a *adapter) AddPolicies(sec string, ptype string, rules [][]string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
-	defer cancel()
-	actionList := a.collection.Actions()
-	for _, rule := range rules {
-		line := savePolicyLine(ptype, rule)
-		actionList.Put(&line)
+	return a.AddPoliciesCtx(context.Background(), sec, ptype, rules)
+}
+
+// AddPoliciesCtx is AddPolicies with a context. The rules are added in one change event,
+// which records the actor of ctx set with [WithActor].
+func (a *adapter) AddPoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	return a.intercept(ctx, OpInfo{Name: "AddPolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		return a.addPolicies(ctx, sec, ptype, rules)
+	})
+}
+
+// addPolicies runs AddPolicies within the interceptors.
+func (a *adapter) addPolicies(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
 	}
-	if err := actionList.Do(ctx); err != nil {
+	defer a.end()
+
+	defer a.trackSlow("AddPolicies", len(rules)).done()
+
+	event := ChangeEvent{Operation: OpAddPolicies, Sec: sec, PType: ptype, Rules: rules}
+	if err := a.checkQuota(ctx, ptype, rules); err != nil {
 		return err
 	}
+	if a.buffer != nil {
+		return a.bufferChanges(ctx, event, ruleAttrs{})
+	}
 
-	return nil
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	actions := make([]action, 0, len(rules))
+	now := time.Now()
+	for _, rule := range rules {
+		line := a.newLine(sec, ptype, rule)
+		a.setTTL(&line, now)
+		actions = append(actions, action{kind: a.addKind(), line: &line})
+	}
+
+	return existsError(a.commit(ctx, a.do, actions, event))
 }
 
 // RemovePolicies removes policy rules from the storage.
 func (a *adapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	return a.RemovePoliciesCtx(context.Background(), sec, ptype, rules)
+}
+
+// RemovePoliciesCtx is RemovePolicies with a context. The change event of the removed rules
+// records the actor of ctx, set with [WithActor], even when Config.WriteBehind delays it.
+func (a *adapter) RemovePoliciesCtx(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	return a.intercept(ctx, OpInfo{Name: "RemovePolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		return a.removePolicies(ctx, sec, ptype, rules)
+	})
+}
+
+// removePolicies runs RemovePolicies within the interceptors.
+func (a *adapter) removePolicies(ctx context.Context, sec string, ptype string, rules [][]string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	defer a.trackSlow("RemovePolicies", len(rules)).done()
+
+	event := ChangeEvent{Operation: OpRemovePolicies, Sec: sec, PType: ptype, Rules: rules}
+	if a.buffer != nil {
+		return a.bufferChanges(ctx, event, ruleAttrs{})
+	}
+	if err := a.checkRemoved(ctx, ptype, event.Rules); err != nil {
+		return err
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
 	defer cancel()
-	actionList := a.collection.Actions()
+	lines := make([]CasbinRule, 0, len(rules))
 	for _, rule := range rules {
-		line := savePolicyLine(ptype, rule)
-		actionList.Delete(&line)
+		lines = append(lines, a.ruleLine(ptype, rule))
 	}
-	if err := actionList.Do(ctx); err != nil {
+	lines, err := a.resolve(ctx, lines)
+	if err != nil {
 		return err
 	}
+	actions := make([]action, 0, len(lines))
+	for i := range lines {
+		actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+	}
 
-	return nil
+	return a.commit(ctx, a.do, actions, event)
 }
 
 // RemovePolicy removes a policy rule from the storage.
 func (a *adapter) RemovePolicy(sec string, ptype string, rule []string) error {
-	line := savePolicyLine(ptype, rule)
+	return a.RemovePolicyCtx(context.Background(), sec, ptype, rule)
+}
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
-	defer cancel()
-	if err := a.collection.Delete(ctx, &line); err != nil {
+// RemovePolicyCtx is RemovePolicy with a context, whose actor, set with [WithActor], is
+// recorded in the change event of the removed rule.
+func (a *adapter) RemovePolicyCtx(ctx context.Context, sec string, ptype string, rule []string) error {
+	return a.intercept(ctx, OpInfo{Name: "RemovePolicy", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.removePolicy(ctx, sec, ptype, rule)
+	})
+}
+
+// removePolicy runs RemovePolicy within the interceptors.
+func (a *adapter) removePolicy(ctx context.Context, sec string, ptype string, rule []string) error {
+	if err := a.beginWrite(); err != nil {
 		return err
 	}
+	defer a.end()
 
-	return nil
+	defer a.trackSlow("RemovePolicy", 1).done()
+
+	event := ChangeEvent{Operation: OpRemovePolicy, Sec: sec, PType: ptype, Rules: [][]string{rule}}
+	if a.buffer != nil {
+		return a.bufferChanges(ctx, event, ruleAttrs{})
+	}
+	if err := a.checkRemoved(ctx, ptype, event.Rules); err != nil {
+		return err
+	}
+
+	line := a.ruleLine(ptype, rule)
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	lines, err := a.resolve(ctx, []CasbinRule{line})
+	if err != nil {
+		return err
+	}
+	actions := make([]action, 0, len(lines)+1)
+	for i := range lines {
+		actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+	}
+	return a.commit(ctx, a.do, actions, event)
 }
 
 // RemoveFilteredPolicy removes policy rules that match the filter from the storage.
 func (a *adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
-	query := a.collection.Query().Where(docstore.FieldPath("ptype"), EqualOp, ptype)
+	return a.RemoveFilteredPolicyCtx(context.Background(), sec, ptype, fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredPolicyCtx is RemoveFilteredPolicy with a context. The change event lists the
+// rules the filter matched and records the actor of ctx, set with [WithActor].
+func (a *adapter) RemoveFilteredPolicyCtx(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.intercept(ctx, OpInfo{Name: "RemoveFilteredPolicy", Sec: sec, PType: ptype, Write: true}, func(ctx context.Context) error {
+		return a.removeFilteredPolicy(ctx, sec, ptype, fieldIndex, fieldValues...)
+	})
+}
 
-	for i := 0; i <= 5; i++ { // max 6 filters (v0-v5)
-		query = a.addFiltersToQuery(query, i, fieldIndex, fieldValues...)
+// removeFilteredPolicy runs RemoveFilteredPolicy within the interceptors.
+func (a *adapter) removeFilteredPolicy(ctx context.Context, sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
 	}
+	defer a.end()
+
+	op := a.trackSlow("RemoveFilteredPolicy", 0)
+	op.setFilter(summarizeFieldValues(fieldIndex, fieldValues))
+	defer op.done()
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	ctx, cancel := a.withTimeout(ctx, opWrite)
 	defer cancel()
-	iter := query.Get(ctx)
-	defer iter.Stop()
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
+
+	lines, err := a.filteredRules(ctx, ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return err
+	}
+	op.addRules(len(lines))
 
 	// delete the document
-	actionList := a.collection.Actions()
-	for {
-		got := new(CasbinRule)
-		err := iter.Next(ctx, got)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		} else {
-			actionList.Delete(got)
-		}
+	actions := make([]action, 0, len(lines))
+	rules := make([][]string, 0, len(lines))
+	for i := range lines {
+		actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+		rules = append(rules, lines[i].values())
 	}
 
-	if err := actionList.Do(ctx); err != nil {
-		return err
+	event := ChangeEvent{Operation: OpRemoveFilteredPolicy, Sec: sec, PType: ptype, Rules: rules}
+	return a.commit(ctx, a.do, actions, event)
+}
+
+// filteredRules returns the stored rules of the policy type that match the field values,
+// where empty field values match any value.
+func (a *adapter) filteredRules(ctx context.Context, ptype string, fieldIndex int, fieldValues ...string) ([]CasbinRule, error) {
+	fieldValues = a.hashFieldValues(fieldIndex, fieldValues)
+	if a.config.Schema == SchemaArray {
+		lines, err := a.collectPType(ctx, ptype, nil)
+		if err != nil {
+			return nil, err
+		}
+		return slices.DeleteFunc(lines, func(line CasbinRule) bool {
+			return !line.matchesFieldValues(fieldIndex, fieldValues...)
+		}), nil
 	}
 
-	return nil
+	return a.collectPType(ctx, ptype, func(query *docstore.Query) *docstore.Query {
+		// add filters to query
+		for i := 0; i <= 5; i++ { // max 6 filters (v0-v5)
+			query = a.addFiltersToQuery(query, i, fieldIndex, fieldValues...)
+		}
+		return query
+	})
 }
 
 // UpdatePolicy updates a policy rule from storage.
 // This is part of the Auto-Save feature.
+//
+// When InPlaceUpdates is enabled and IDs are not derived from the rule content,
+// only the changed values of the stored document are updated, keeping its ID.
+// Metadata attached to the old rule is kept.
 func (a *adapter) UpdatePolicy(sec string, ptype string, oldRule, newPolicy []string) error {
-	oldLine := savePolicyLine(ptype, oldRule)
-	newLine := savePolicyLine(ptype, newPolicy)
+	return a.UpdatePolicyCtx(context.Background(), sec, ptype, oldRule, newPolicy)
+}
+
+// UpdatePolicyCtx is UpdatePolicy with a context, whose actor, set with [WithActor], is
+// recorded in the change event of the replaced rule.
+func (a *adapter) UpdatePolicyCtx(ctx context.Context, sec string, ptype string, oldRule, newPolicy []string) error {
+	return a.intercept(ctx, OpInfo{Name: "UpdatePolicy", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.updatePolicy(ctx, sec, ptype, oldRule, newPolicy)
+	})
+}
+
+// updatePolicy runs UpdatePolicy within the interceptors.
+func (a *adapter) updatePolicy(ctx context.Context, sec string, ptype string, oldRule, newPolicy []string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
 
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	defer a.trackSlow("UpdatePolicy", 1).done()
+
+	oldLine := a.ruleLine(ptype, oldRule)
+	newLine := a.newLine(sec, ptype, newPolicy)
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
 	defer cancel()
-	if err := a.collection.Actions().Delete(&oldLine).Put(&newLine).Do(ctx); err != nil {
+	if err := a.flush(ctx); err != nil {
 		return err
 	}
 
-	return nil
+	oldLines, err := a.resolve(ctx, []CasbinRule{oldLine})
+	if err != nil {
+		return err
+	}
+
+	var actions []action
+	if a.config.InPlaceUpdates && !a.contentIDs() && len(oldLines) > 0 {
+		mods := updateMods(oldLines[0], newLine)
+		if len(mods) == 0 {
+			return nil
+		}
+		actions = []action{{kind: actionUpdate, line: &oldLines[0], mods: mods}}
+	} else {
+		newLines := []CasbinRule{newLine}
+		if err := a.carryMeta(ctx, []CasbinRule{oldLine}, newLines); err != nil {
+			return err
+		}
+		actions = swapActions(oldLines, newLines)
+	}
+
+	event := ChangeEvent{Operation: OpUpdatePolicy, Sec: sec, PType: ptype, Rules: [][]string{newPolicy}, OldRules: [][]string{oldRule}}
+	return a.commit(ctx, a.do, actions, event)
 }
 
 // UpdatePolicies updates some policy rules to storage, like db, redis.
+//
+// All rules are updated in a single batch. If the batch fails part-way, the changes
+// that were applied are rolled back so the storage is either fully updated or unchanged.
 func (a *adapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), a.timeout)
+	return a.UpdatePoliciesCtx(context.Background(), sec, ptype, oldRules, newRules)
+}
+
+// UpdatePoliciesCtx is UpdatePolicies with a context. The rules are replaced in one change
+// event, which records the actor of ctx set with [WithActor].
+func (a *adapter) UpdatePoliciesCtx(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) error {
+	return a.intercept(ctx, OpInfo{Name: "UpdatePolicies", Sec: sec, PType: ptype, Rules: len(newRules), Write: true}, func(ctx context.Context) error {
+		return a.updatePolicies(ctx, sec, ptype, oldRules, newRules)
+	})
+}
+
+// updatePolicies runs UpdatePolicies within the interceptors.
+func (a *adapter) updatePolicies(ctx context.Context, sec string, ptype string, oldRules, newRules [][]string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	defer a.trackSlow("UpdatePolicies", len(oldRules)).done()
+
+	if len(oldRules) != len(newRules) {
+		return errors.New("the number of old and new rules must match")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
 	defer cancel()
-	for i := range oldRules {
-		oldLine := savePolicyLine(ptype, oldRules[i])
-		newLine := savePolicyLine(ptype, newRules[i])
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
 
-		// delete and put
-		if err := a.collection.Actions().Delete(&oldLine).Put(&newLine).Do(ctx); err != nil {
-			return err
-		}
+	ruleLines := make([]CasbinRule, 0, len(oldRules))
+	for _, rule := range oldRules {
+		ruleLines = append(ruleLines, a.ruleLine(ptype, rule))
+	}
+	oldLines, err := a.resolve(ctx, ruleLines)
+	if err != nil {
+		return err
+	}
+	newLines := make([]CasbinRule, 0, len(newRules))
+	for _, rule := range newRules {
+		newLines = append(newLines, a.newLine(sec, ptype, rule))
+	}
+	if err := a.carryMeta(ctx, ruleLines, newLines); err != nil {
+		return err
 	}
 
-	return nil
+	event := ChangeEvent{Operation: OpUpdatePolicies, Sec: sec, PType: ptype, Rules: newRules, OldRules: oldRules}
+	return a.commit(ctx, a.doWithRollback, swapActions(oldLines, newLines), event)
 }
 
 // addFiltersToQuery adds filters to query.
@@ -390,58 +1455,109 @@ func (a *adapter) UpdatePolicies(sec string, ptype string, oldRules, newRules []
 // - the query with filters added.
 func (a *adapter) addFiltersToQuery(query *docstore.Query, filterIndex, fieldIndex int, fieldValues ...string) *docstore.Query {
 	if fieldIndex <= filterIndex && filterIndex < fieldIndex+len(fieldValues) && fieldValues[filterIndex-fieldIndex] != "" {
-		query = query.Where(docstore.FieldPath(fmt.Sprintf("v%d", filterIndex)), EqualOp, fieldValues[filterIndex-fieldIndex])
+		query = query.Where(a.field(fmt.Sprintf("v%d", filterIndex)), EqualOp, fieldValues[filterIndex-fieldIndex])
 	}
 	return query
 }
 
 // UpdateFilteredPolicies deletes old rules and adds new rules.
+// If writing the new rules fails, the deleted rules are restored. With Config.Transactions
+// the swap runs in a transaction instead.
 func (a *adapter) UpdateFilteredPolicies(sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
-	query := a.collection.Query().Where(docstore.FieldPath("ptype"), EqualOp, ptype)
+	return a.UpdateFilteredPoliciesCtx(context.Background(), sec, ptype, newPolicies, fieldIndex, fieldValues...)
+}
+
+// UpdateFilteredPoliciesCtx is UpdateFilteredPolicies with a context, and returns the
+// replaced rules like it. The change event records the actor of ctx, set with [WithActor].
+func (a *adapter) UpdateFilteredPoliciesCtx(ctx context.Context, sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	var oldRules [][]string
+	err := a.intercept(ctx, OpInfo{Name: "UpdateFilteredPolicies", Sec: sec, PType: ptype, Rules: len(newPolicies), Write: true}, func(ctx context.Context) error {
+		var err error
+		oldRules, err = a.updateFilteredPolicies(ctx, sec, ptype, newPolicies, fieldIndex, fieldValues...)
+		return err
+	})
+	return oldRules, err
+}
 
-	// add filters to query
-	for i := 0; i <= 5; i++ { // max 6 filters (v0-v5)
-		query = a.addFiltersToQuery(query, i, fieldIndex, fieldValues...)
+// updateFilteredPolicies runs UpdateFilteredPolicies within the interceptors.
+func (a *adapter) updateFilteredPolicies(ctx context.Context, sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	if err := a.beginWrite(); err != nil {
+		return nil, err
 	}
+	defer a.end()
+
+	op := a.trackSlow("UpdateFilteredPolicies", len(newPolicies))
+	op.setFilter(summarizeFieldValues(fieldIndex, fieldValues))
+	defer op.done()
 
-	oldLines := make([][]string, 0)
 	newLines := make([]CasbinRule, 0, len(newPolicies))
 	for _, newPolicy := range newPolicies {
-		newLines = append(newLines, savePolicyLine(ptype, newPolicy))
+		newLines = append(newLines, a.newLine(sec, ptype, newPolicy))
 	}
 
 	// Load and delete old policies.
-	actionList := a.collection.Actions()
-	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	ctx, cancel := a.withTimeout(ctx, opWrite)
 	defer cancel()
-	iter := query.Get(ctx)
-	defer iter.Stop()
-	for {
-		var line CasbinRule
-		err := iter.Next(ctx, &line)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
-		} else {
-			oldLines = append(oldLines, line.toStringPolicy())
-			actionList.Delete(&line)
-		}
+	if err := a.flush(ctx); err != nil {
+		return nil, err
 	}
 
-	// Insert new policies.
-	for i := range newLines {
-		actionList.Put(&newLines[i])
+	matched, err := a.filteredRules(ctx, ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return nil, err
+	}
+	op.addRules(len(matched))
+
+	// Return the old rules in a deterministic order, independent of the provider's iteration order.
+	slices.SortFunc(matched, compareRules)
+	oldLines := make([][]string, 0, len(matched))
+	oldRules := make([][]string, 0, len(matched))
+	for i := range matched {
+		oldLines = append(oldLines, matched[i].toStringPolicy())
+		oldRules = append(oldRules, matched[i].values())
 	}
 
-	if err := actionList.Do(ctx); err != nil {
+	// Swap the old policies for the new ones in a transaction if enabled, or otherwise
+	// restoring the old policies if the swap fails part-way.
+	event := ChangeEvent{Operation: OpUpdateFilteredPolicies, Sec: sec, PType: ptype, Rules: newPolicies, OldRules: oldRules}
+	if err := a.commit(ctx, a.doAtomically, swapActions(matched, newLines), event); err != nil {
 		return nil, err
 	}
-
 	return oldLines, nil
 }
 
+// compareRules orders rules by their field values (ptype, v0, v1, ...), then by ID.
+func compareRules(x, y CasbinRule) int {
+	return cmp.Or(
+		cmp.Compare(x.PType, y.PType),
+		slices.Compare(x.values(), y.values()),
+		cmp.Compare(x.ID, y.ID),
+	)
+}
+
+// values returns the rule values. Values stored as an array or with a field count are
+// returned as is; otherwise trailing empty values of v0, ..., v5 are omitted.
+func (c *CasbinRule) values() []string {
+	if c.Values != nil {
+		return slices.Clone(c.Values)
+	}
+	fields := [...]string{c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+	if c.FieldCount > 0 {
+		return slices.Clone(fields[:min(c.FieldCount, len(fields))])
+	}
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i] != "" {
+			return slices.Clone(fields[:i+1])
+		}
+	}
+	return []string{}
+}
+
 func (c *CasbinRule) toStringPolicy() []string {
+	if c.FieldCount > 0 || c.Values != nil {
+		return append([]string{c.PType}, c.values()...)
+	}
+
 	fields := [...]string{c.PType, c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
 	policy := make([]string, 0, len(fields))
 
@@ -3,14 +3,19 @@ package adapter
 import (
 	"cmp"
 	"context"
+	"errors"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/util"
-
-	// Enable In-Memory driver.
-	_ "github.com/bartventer/casbin-go-cloud-adapter/drivers/memdocstore"
+	"gocloud.dev/docstore"
+	// The import also registers the In-Memory driver.
+	"gocloud.dev/docstore/memdocstore"
 )
 
 var (
@@ -77,7 +82,9 @@ func arrayEqualsWithoutOrder(a [][]string, b [][]string) bool {
 	return true
 }
 
-func initPolicy(t *testing.T, dbURL string) {
+// initPolicy saves the policy of testdata/rbac_policy.csv with the adapter, which the test
+// closes once it is done, as the adapters of a mem:// URL share its collection.
+func initPolicy(t *testing.T, a *adapter) {
 	t.Helper()
 	// Because the DB is empty at first,
 	// so we need to load the policy from the file adapter (.CSV) first.
@@ -86,13 +93,6 @@ func initPolicy(t *testing.T, dbURL string) {
 		panic(err)
 	}
 
-	// a, err := NewAdapter(dbURL)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	a, err := New(ctx, dbURL)
-	if err != nil {
-		panic(err)
-	}
 	// This is a trick to save the current policy to the DB.
 	// We can't call e.SavePolicy() because the adapter in the enforcer is still the file adapter.
 	// The current policy means the policy in the Casbin enforcer (aka in memory).
@@ -133,6 +133,7 @@ func TestInMemoryAdapter(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
 	// This is a trick to save the current policy to the DB.
 	// We can't call e.SavePolicy() because the adapter in the enforcer is still the file adapter.
 	// The current policy means the policy in the Casbin enforcer (aka in memory).
@@ -162,20 +163,21 @@ func TestInMemoryAdapter(t *testing.T) {
 // Other tests assumes Mongo connection is available.
 
 func TestAdapter(t *testing.T) {
-	initPolicy(t, testDBURL)
-
-	// Note: you don't need to look at the above code
-	// if you already have a working DB with policy inside.
-
-	// Now the DB has policy, so we can provide a normal use case.
-	// Create an adapter and an enforcer.
-	// NewEnforcer() will load the policy automatically.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	a, err := New(ctx, testDBURL)
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
+	initPolicy(t, a)
+
+	// Note: you don't need to look at the above code
+	// if you already have a working DB with policy inside.
+
+	// Now the DB has policy, so we can provide a normal use case.
+	// Create an enforcer over the adapter.
+	// NewEnforcer() will load the policy automatically.
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -270,7 +272,6 @@ func TestAdapter(t *testing.T) {
 }
 
 func TestAddPolicies(t *testing.T) {
-	initPolicy(t, testDBURL)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -278,6 +279,8 @@ func TestAddPolicies(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
+	initPolicy(t, a)
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -347,6 +350,7 @@ func TestDeleteFilteredAdapter(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
 
 	e, err := casbin.NewEnforcer("testdata/rbac_tenant_service.conf", a)
 	if err != nil {
@@ -396,6 +400,7 @@ func TestFilteredAdapter(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -476,7 +481,6 @@ func TestFilteredAdapter(t *testing.T) {
 }
 
 func TestUpdatePolicy(t *testing.T) {
-	initPolicy(t, testDBURL)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -484,6 +488,8 @@ func TestUpdatePolicy(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
+	initPolicy(t, a)
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -544,7 +550,6 @@ func initUpdateFilteredPolicies(ptype string, newPolicies [][]string, fieldIndex
 }
 
 func TestUpdateFilteredPolicies(t *testing.T) {
-	initPolicy(t, testDBURL)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -552,6 +557,8 @@ func TestUpdateFilteredPolicies(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
+	initPolicy(t, a)
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -577,7 +584,6 @@ func TestUpdateFilteredPolicies(t *testing.T) {
 }
 
 func TestUpdateFilteredPoliciesTxn(t *testing.T) {
-	initPolicy(t, replicaSetURL)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -585,6 +591,8 @@ func TestUpdateFilteredPoliciesTxn(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	defer a.close()
+	initPolicy(t, a)
 
 	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
 	if err != nil {
@@ -607,9 +615,372 @@ func TestUpdateFilteredPoliciesTxn(t *testing.T) {
 	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "write"}, {"bob", "data2", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
 }
 
+// closeAll closes the adapters, closing the collection shared by the adapters opened on the
+// same mem:// URL once.
+func closeAll(adapters ...*adapter) {
+	closed := make(map[*docstore.Collection]bool)
+	for _, a := range adapters {
+		if closed[a.collection] {
+			a.collection = nil
+		} else {
+			closed[a.collection] = true
+		}
+		a.close()
+	}
+}
+
 func dropCollection(e *casbin.Enforcer) {
 	e.RemoveFilteredPolicy(2, "read")
 	e.RemoveFilteredPolicy(2, "write")
 	e.RemoveFilteredGroupingPolicy(1, "data2_admin")
 	e.RemoveFilteredGroupingPolicy(1, "data1_admin")
 }
+
+func TestCollection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_collection/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	coll := a.Collection()
+	if coll == nil {
+		t.Fatal("Expected Collection() to return a non-nil collection")
+	}
+	line := CasbinRule{ID: savePolicyLine("p", []string{"alice", "data1", "read"}).ID}
+	if err := coll.Get(ctx, &line); err != nil {
+		t.Fatalf("Expected Get() on the underlying collection to be successful; got %v", err)
+	}
+	if line.V0 != "alice" {
+		t.Errorf("Expected v0 to be alice; got %q", line.V0)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_health/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.HealthCheck(ctx); err != nil {
+		t.Errorf("Expected HealthCheck() on an empty collection to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.HealthCheck(ctx); err != nil {
+		t.Errorf("Expected HealthCheck() to be successful; got %v", err)
+	}
+
+	a.close()
+	if err := a.HealthCheck(ctx); err == nil {
+		t.Error("Expected HealthCheck() to fail on a closed adapter")
+	}
+}
+
+func TestUpdateFilteredPoliciesOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_update_order/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	rules := [][]string{
+		{"carol", "data3", "write"},
+		{"alice", "data2", "read"},
+		{"bob", "data1", "read"},
+		{"alice", "data1", "read"},
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		oldRules, err := a.UpdateFilteredPolicies("p", "p", nil, 2, "read")
+		if err != nil {
+			t.Fatalf("Expected UpdateFilteredPolicies() to be successful; got %v", err)
+		}
+		want := [][]string{
+			{"p", "alice", "data1", "read"},
+			{"p", "alice", "data2", "read"},
+			{"p", "bob", "data1", "read"},
+		}
+		if !util.Array2DEquals(want, oldRules) {
+			t.Errorf("Expected old rules %v; got %v", want, oldRules)
+		}
+		// Put the rules back for the next iteration.
+		if err := a.AddPolicies("p", "p", rules); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPreserveEmptyValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:                 "mem://casbin_rule_empty_values/id",
+		PreserveEmptyValues: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	rules := [][]string{
+		{"alice", "", "read"},
+		{"bob", "data2", ""},
+		{"bob", "data2"},
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+
+	lines, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected rules with and without trailing empty values to be distinct; got %d rules", len(lines))
+	}
+	got := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		got = append(got, line.values())
+	}
+	if !arrayEqualsWithoutOrder(got, rules) {
+		t.Errorf("Expected rules %v to round-trip; got %v", rules, got)
+	}
+
+	oldRules, err := a.UpdateFilteredPolicies("p", "p", nil, 0, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"p", "alice", "", "read"}}; !util.Array2DEquals(want, oldRules) {
+		t.Errorf("Expected old rules %v; got %v", want, oldRules)
+	}
+
+	// Legacy documents without a field count keep their IDs.
+	if got, want := a.ruleLine("p", []string{"alice", "data1", "read"}).ID, savePolicyLine("p", []string{"alice", "data1", "read"}).ID; got != want {
+		t.Errorf("Expected the ID of a rule without empty values to be unchanged; got %s, want %s", got, want)
+	}
+}
+
+func TestSaveAllSections(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_sections/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	newModel := func() model.Model {
+		m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.AddDef("p", "p2", "sub, act")
+		m.AddDef("x", "x", "key, value")
+		return m
+	}
+
+	m := newModel()
+	for _, r := range []struct {
+		sec, ptype string
+		rule       []string
+	}{
+		{"p", "p", []string{"alice", "data1", "read"}},
+		{"p", "p2", []string{"bob", "write"}},
+		{"g", "g", []string{"alice", "admin"}},
+		{"x", "x", []string{"region", "eu"}},
+	} {
+		if err := m.AddPolicy(r.sec, r.ptype, r.rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	loaded := newModel()
+	if err := a.LoadPolicy(loaded); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	for _, key := range [][2]string{{"p", "p"}, {"p", "p2"}, {"g", "g"}, {"x", "x"}} {
+		want, _ := m.GetPolicy(key[0], key[1])
+		got, _ := loaded.GetPolicy(key[0], key[1])
+		if !util.Array2DEquals(want, got) {
+			t.Errorf("Expected policy %v of %s.%s to round-trip; got %v", want, key[0], key[1], got)
+		}
+	}
+
+	// Loading into a model without the custom section fails instead of dropping rules.
+	plain, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.AddDef("p", "p2", "sub, act")
+	if err := a.LoadPolicy(plain); err == nil {
+		t.Error("Expected LoadPolicy() to fail for a section missing from the model")
+	}
+}
+
+func TestLoadPolicySection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_load_section/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"bob", "data2_admin"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+
+	m := e.GetModel()
+	if err := a.LoadPolicySection(ctx, m, "g"); err != nil {
+		t.Fatalf("Expected LoadPolicySection() to be successful; got %v", err)
+	}
+	if err := e.BuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	grouping, _ := m.GetPolicy("g", "g")
+	if want := [][]string{{"alice", "data2_admin"}, {"bob", "data2_admin"}}; !arrayEqualsWithoutOrder(want, grouping) {
+		t.Errorf("Expected grouping policy %v; got %v", want, grouping)
+	}
+	// The "p" section is not reloaded, so the removed rule is still present.
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+	if ok, _ := e.Enforce("bob", "data2", "read"); !ok {
+		t.Error("Expected the reloaded role assignment to take effect")
+	}
+
+	if err := a.LoadPolicySection(ctx, m, "x"); err == nil {
+		t.Error("Expected LoadPolicySection() to fail for a section missing from the model")
+	}
+}
+
+// countingOpener opens memdocstore collections and counts the opened urls.
+type countingOpener struct {
+	opened []string
+}
+
+func (o *countingOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	o.opened = append(o.opened, u.String())
+	return (&memdocstore.URLOpener{}).OpenCollectionURL(ctx, u)
+}
+
+func TestOpeners(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opener := &countingOpener{}
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_openers/id",
+		HistoryURL: "mem://casbin_history_openers/id",
+		Openers:    URLOpeners{"mem": opener},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if !util.ArrayEquals(opener.opened, []string{"mem://casbin_rule_openers/id", "mem://casbin_history_openers/id"}) {
+		t.Errorf("Expected the collections to be opened by the configured opener; got %v", opener.opened)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+}
+
+func TestMaxLoadRules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_max_load/id", MaxLoadRules: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful within the limit; got %v", err)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.LoadPolicy(e.GetModel()); !errors.Is(err, ErrTooManyRules) {
+		t.Errorf("Expected LoadPolicy() to fail with ErrTooManyRules; got %v", err)
+	}
+	if err := a.LoadPolicySection(ctx, e.GetModel(), "p"); err != nil {
+		t.Errorf("Expected LoadPolicySection() to be successful within the limit; got %v", err)
+	}
+}
+
+func TestOrderedLoads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_ordered/id", OrderedLoads: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}, {"dave", "data4", "write"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	lines := make([]CasbinRule, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, a.ruleLine("p", rule))
+	}
+	slices.SortFunc(lines, func(x, y CasbinRule) int { return strings.Compare(x.ID, y.ID) })
+	want := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		want = append(want, line.values())
+	}
+
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadPolicy(m); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	if got, _ := m.GetPolicy("p", "p"); !slices.EqualFunc(want, got, slices.Equal[[]string]) {
+		t.Errorf("Expected the rules in ID order %v; got %v", want, got)
+	}
+}
@@ -0,0 +1,195 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// ruleAttrs are the attributes of a rule added by AddPolicyWithMeta or AddPolicyWithWindow,
+// besides its values. They are JSON-encoded in the journal of buffered changes.
+type ruleAttrs struct {
+	Meta          map[string]string `json:"meta,omitempty"`
+	EffectiveFrom int64             `json:"effective_from,omitempty"` // Unix milliseconds
+	ExpiresAt     int64             `json:"expires_at,omitempty"`     // Unix milliseconds
+}
+
+// setAttrs sets the attributes of an added rule, after its TTL. A validity window ending
+// before the TTL expires the rule at the end of the window.
+func (a *adapter) setAttrs(line *CasbinRule, attrs ruleAttrs) {
+	if len(attrs.Meta) > 0 {
+		line.Meta = maps.Clone(attrs.Meta)
+		line.Annotated = true
+	}
+	if attrs.EffectiveFrom != 0 {
+		line.EffectiveFrom = attrs.EffectiveFrom
+		line.Annotated = true
+	}
+	if attrs.ExpiresAt != 0 && (line.ExpiresAt == 0 || attrs.ExpiresAt < line.ExpiresAt) {
+		line.ExpiresAt = attrs.ExpiresAt
+		if line.ExpireAt != nil {
+			line.ExpireAt = ttlValue(a.config.URL, time.UnixMilli(attrs.ExpiresAt))
+		}
+		line.Annotated = true
+	}
+}
+
+// AddPolicyWithMeta adds a policy rule with metadata attached, such as its owner, a ticket
+// ID or a description. The metadata is kept when the rule is saved again by SavePolicy or
+// changed by UpdatePolicy and UpdatePolicies, and can be read with GetPolicyMeta.
+//
+// The rule is added like AddPolicy: through the interceptors, with Config.WriteMode, the
+// quotas, Config.WriteBehind and the change notifications.
+func (a *adapter) AddPolicyWithMeta(ctx context.Context, sec, ptype string, rule []string, meta map[string]string) error {
+	return a.intercept(ctx, OpInfo{Name: "AddPolicyWithMeta", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.addPolicy(ctx, sec, ptype, rule, ruleAttrs{Meta: meta})
+	})
+}
+
+// GetPolicyMeta returns the metadata attached to a stored rule, or nil if it has none.
+func (a *adapter) GetPolicyMeta(ctx context.Context, ptype string, rule []string) (map[string]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	lines, err := a.storedLines(ctx, []CasbinRule{a.ruleLine(ptype, rule)})
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("rule not found")
+	}
+	return lines[0].Meta, nil
+}
+
+// storedLines returns the stored documents for the lines, omitting lines that are not stored.
+func (a *adapter) storedLines(ctx context.Context, lines []CasbinRule) ([]CasbinRule, error) {
+	if !a.contentIDs() {
+		// The documents are looked up by content, so they are read in full.
+		return a.resolve(ctx, lines)
+	}
+	if a.config.IDStrategy == IDStrategyCanonical {
+		lines = append(slices.Clip(lines), legacyLines(lines)...)
+	}
+	return a.getLines(ctx, lines)
+}
+
+// getLines reads the stored documents with the IDs of the lines, omitting lines that are not stored.
+func (a *adapter) getLines(ctx context.Context, lines []CasbinRule) ([]CasbinRule, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	stored := make([]CasbinRule, len(lines))
+	gets := make([]action, len(lines))
+	for i := range lines {
+		stored[i].ID = lines[i].ID
+		stored[i].PType = lines[i].PType
+		gets[i] = action{kind: actionGet, line: &stored[i]}
+	}
+	missing := make(map[int]bool)
+	if err := a.doActions(ctx, gets); err != nil {
+		var alerr docstore.ActionListError
+		if !errors.As(err, &alerr) {
+			return nil, err
+		}
+		for _, e := range alerr {
+			if e.Index < 0 || gcerrors.Code(e.Err) != gcerrors.NotFound {
+				return nil, err
+			}
+			missing[e.Index] = true
+		}
+	}
+
+	found := make([]CasbinRule, 0, len(stored))
+	for i := range stored {
+		if !missing[i] {
+			found = append(found, stored[i])
+		}
+	}
+	return found, nil
+}
+
+// carryMeta copies the metadata, and the priority if Config.Priorities is set, of the stored
+// old rules to the new rules at the same positions, so that updates keep the metadata
+// attached to a rule and its place in the load order.
+func (a *adapter) carryMeta(ctx context.Context, oldLines, newLines []CasbinRule) error {
+	stored, err := a.storedLines(ctx, oldLines)
+	if err != nil {
+		return err
+	}
+	carried := make(map[string]CasbinRule)
+	for _, line := range stored {
+		if line.Meta != nil || (a.config.Priorities && line.Priority != 0) {
+			carried[ruleKey(line)] = line
+		}
+	}
+	if len(carried) == 0 {
+		return nil
+	}
+	for i := range newLines {
+		line, ok := carried[ruleKey(oldLines[i])]
+		if !ok {
+			continue
+		}
+		if line.Meta != nil {
+			newLines[i].Meta = line.Meta
+			newLines[i].Annotated = true
+		}
+		if a.config.Priorities && line.Priority != 0 {
+			newLines[i].Priority = line.Priority
+		}
+	}
+	return nil
+}
+
+// annotatedRules returns the stored rules that carry data which is not part of the model,
+// indexed by [ruleKey].
+func (a *adapter) annotatedRules(ctx context.Context) (map[string]CasbinRule, error) {
+	lines, err := a.collectAll(ctx, func(q *docstore.Query) *docstore.Query {
+		return q.Where(a.field("annotated"), EqualOp, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	annotated := make(map[string]CasbinRule, len(lines))
+	for _, line := range lines {
+		annotated[ruleKey(line)] = line
+	}
+	return annotated, nil
+}
+
+// preserveAnnotations copies the metadata, and the validity windows of active rules, from
+// the stored rules to the lines with the same content, so that saving the loaded policy
+// does not drop them. Inactive rules are not loaded, so a line matching one was granted
+// again and does not inherit its window.
+func (a *adapter) preserveAnnotations(ctx context.Context, lines []CasbinRule) error {
+	annotated, err := a.annotatedRules(ctx)
+	if err != nil || len(annotated) == 0 {
+		return err
+	}
+	now := time.Now()
+	for i := range lines {
+		stored, ok := annotated[ruleKey(lines[i])]
+		if !ok {
+			continue
+		}
+		lines[i].Meta = stored.Meta
+		if stored.activeAt(now) {
+			lines[i].EffectiveFrom = stored.EffectiveFrom
+			lines[i].ExpiresAt = stored.ExpiresAt
+			lines[i].ExpireAt = stored.ExpireAt
+		}
+		lines[i].Annotated = lines[i].Meta != nil || lines[i].EffectiveFrom != 0 || lines[i].ExpiresAt != 0
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestPolicyMeta(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, strategy := range []IDStrategy{IDStrategyHash, IDStrategyRandom} {
+		a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_meta/id", IDStrategy: strategy})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		meta := map[string]string{"owner": "security", "ticket": "SEC-42"}
+		if err := a.AddPolicyWithMeta(ctx, "p", "p", []string{"alice", "data1", "read"}, meta); err != nil {
+			t.Fatalf("Expected AddPolicyWithMeta() to be successful; got %v", err)
+		}
+		if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+			t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+		}
+
+		got, err := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "read"})
+		if err != nil {
+			t.Fatalf("Expected GetPolicyMeta() to be successful; got %v", err)
+		}
+		if !maps.Equal(meta, got) {
+			t.Errorf("Expected metadata %v; got %v", meta, got)
+		}
+		if got, err := a.GetPolicyMeta(ctx, "p", []string{"bob", "data2", "write"}); err != nil || got != nil {
+			t.Errorf("Expected no metadata for a plain rule; got %v, %v", got, err)
+		}
+		if _, err := a.GetPolicyMeta(ctx, "p", []string{"carol", "data3", "read"}); err == nil {
+			t.Error("Expected GetPolicyMeta() to fail for a missing rule")
+		}
+
+		// Saving the loaded policy keeps the metadata.
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SavePolicy(); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+		if got, _ := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "read"}); !maps.Equal(meta, got) {
+			t.Errorf("Expected SavePolicy() to keep metadata %v; got %v", meta, got)
+		}
+
+		// Updating the rule keeps the metadata.
+		if err := a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+			t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+		}
+		if got, _ := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "write"}); !maps.Equal(meta, got) {
+			t.Errorf("Expected UpdatePolicy() to keep metadata %v; got %v", meta, got)
+		}
+		if err := a.UpdatePolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"alice", "data1", "write"}}, [][]string{{"bob", "data2", "read"}, {"alice", "data1", "read"}}); err != nil {
+			t.Fatalf("Expected UpdatePolicies() to be successful; got %v", err)
+		}
+		if got, _ := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "read"}); !maps.Equal(meta, got) {
+			t.Errorf("Expected UpdatePolicies() to keep metadata %v; got %v", meta, got)
+		}
+		if got, _ := a.GetPolicyMeta(ctx, "p", []string{"bob", "data2", "read"}); got != nil {
+			t.Errorf("Expected UpdatePolicies() not to attach metadata to other rules; got %v", got)
+		}
+		a.close()
+	}
+}
+
+func TestPolicyMetaWritePath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	n := &recordingNotifier{}
+	var ops []string
+	config := func() *Config {
+		return &Config{
+			URL:         "sqlitedoc://" + filepath.Join(dir, "policy.db"),
+			WriteBehind: time.Hour,
+			JournalURL:  "sqlitedoc://" + filepath.Join(dir, "journal.db") + "?table=journal",
+			MaxRules:    1,
+			Notifiers:   []Notifier{n},
+			Interceptors: []Interceptor{func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+				ops = append(ops, op.Name)
+				return next(ctx)
+			}},
+		}
+	}
+	a, err := NewWithOption(ctx, config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := map[string]string{"owner": "security"}
+	if err := a.AddPolicyWithMeta(ctx, "p", "p", []string{"alice", "data1", "read"}, meta); err != nil {
+		t.Fatalf("Expected AddPolicyWithMeta() to be successful; got %v", err)
+	}
+	if !slices.Equal(ops, []string{"AddPolicyWithMeta"}) {
+		t.Errorf("Expected AddPolicyWithMeta() to run the interceptors; got %v", ops)
+	}
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 0 {
+		t.Fatalf("Expected the rule to be buffered; got %v, %v", lines, err)
+	}
+	// The process crashes before the buffered rule is written; the journal keeps its metadata.
+	a.close()
+
+	a, err = NewWithOption(ctx, config())
+	if err != nil {
+		t.Fatalf("Expected the journal to be replayed; got %v", err)
+	}
+	defer a.close()
+	if got, err := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "read"}); err != nil || !maps.Equal(meta, got) {
+		t.Errorf("Expected the replayed rule to keep metadata %v; got %v, %v", meta, got, err)
+	}
+	if len(n.events) != 1 || n.events[0].Operation != OpAddPolicy {
+		t.Errorf("Expected an AddPolicy event; got %v", n.events)
+	}
+
+	var quotaErr *QuotaError
+	if err := a.AddPolicyWithMeta(ctx, "p", "p", []string{"bob", "data2", "write"}, meta); !errors.As(err, &quotaErr) {
+		t.Errorf("Expected AddPolicyWithMeta() to enforce the quota; got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+// ErrArchiveDisabled is returned by the archive APIs when no archive collection is configured.
+var ErrArchiveDisabled = errors.New("rule archival is disabled")
+
+// ArchivedRule is a copy of a rule that was removed from the storage, recorded in the
+// archive collection before the rule was deleted.
+type ArchivedRule struct {
+	ID         string     `docstore:"id"`
+	ArchivedAt time.Time  `docstore:"archived_at"`
+	Rule       CasbinRule `docstore:"rule"`
+	Namespace  string     `docstore:"ns,omitempty"`
+}
+
+// archiveRemoved copies the rules deleted by the actions to the archive collection, if
+// archival is enabled. Internal documents and deletes that move a rule are not archived.
+func (a *adapter) archiveRemoved(ctx context.Context, actions []action) error {
+	if a.archive == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	actionList := a.archive.Actions()
+	n := 0
+	for _, act := range actions {
+		if act.kind != actionDelete || act.moved || !a.isRule(act.line) {
+			continue
+		}
+		actionList.Put(&ArchivedRule{
+			ID:         randomID(),
+			ArchivedAt: now,
+			Rule:       *act.line,
+			Namespace:  a.config.Namespace,
+		})
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return actionList.Do(ctx)
+}
+
+// archived returns the archived rules of the configured namespace that were removed at or
+// after from and before to, oldest first. A zero from or to leaves that end unbounded.
+func (a *adapter) archived(ctx context.Context, from, to time.Time) ([]ArchivedRule, error) {
+	query := a.scopeOther(a.archive.Query())
+	if !from.IsZero() {
+		query = query.Where("archived_at", ">=", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("archived_at", "<", to)
+	}
+	iter := query.Get(ctx)
+	defer iter.Stop()
+
+	archived := make([]ArchivedRule, 0)
+	for {
+		var r ArchivedRule
+		err := nextDoc(ctx, iter, &r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, a.redact(err)
+		}
+		if r.Namespace == a.config.Namespace {
+			archived = append(archived, r)
+		}
+	}
+	sortArchived(archived)
+
+	return archived, nil
+}
+
+// sortArchived sorts archived rules by the time they were removed, oldest first.
+func sortArchived(archived []ArchivedRule) {
+	slices.SortStableFunc(archived, func(x, y ArchivedRule) int {
+		return x.ArchivedAt.Compare(y.ArchivedAt)
+	})
+}
+
+// ListArchived returns the rules removed at or after from and before to, oldest first.
+// A zero from or to leaves that end of the range unbounded.
+func (a *adapter) ListArchived(ctx context.Context, from, to time.Time) ([]ArchivedRule, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.archive == nil {
+		return nil, ErrArchiveDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	return a.archived(ctx, from, to)
+}
+
+// RestoreArchived puts back the rules removed at or after from and before to, and returns
+// the number of restored rules. A zero from or to leaves that end of the range unbounded.
+//
+// Restored rules are removed from the archive. A rule that was removed more than once in
+// the range is restored once. With content-derived IDs (see Config.IDStrategy), restoring a
+// rule that has since been added again leaves a single copy.
+func (a *adapter) RestoreArchived(ctx context.Context, from, to time.Time) (int, error) {
+	var restored int
+	err := a.intercept(ctx, OpInfo{Name: "RestoreArchived", Write: true}, func(ctx context.Context) error {
+		var err error
+		restored, err = a.restoreArchived(ctx, from, to)
+		return err
+	})
+	return restored, err
+}
+
+// restoreArchived runs RestoreArchived within the interceptors.
+func (a *adapter) restoreArchived(ctx context.Context, from, to time.Time) (int, error) {
+	if err := a.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	if a.archive == nil {
+		return 0, ErrArchiveDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+
+	archived, err := a.archived(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	// The most recently removed copy of each rule wins.
+	lines := make([]CasbinRule, 0, len(archived))
+	index := make(map[string]int, len(archived))
+	for _, r := range archived {
+		if i, ok := index[r.Rule.ID]; ok {
+			lines[i] = r.Rule
+			continue
+		}
+		index[r.Rule.ID] = len(lines)
+		lines = append(lines, r.Rule)
+	}
+	if err := a.putRules(ctx, ChangeEvent{Operation: OpRestoreArchived}, lines); err != nil {
+		return 0, err
+	}
+
+	actionList := a.archive.Actions()
+	for i := range archived {
+		actionList.Delete(&archived[i])
+	}
+	if len(archived) > 0 {
+		if err := actionList.Do(ctx); err != nil {
+			return len(lines), a.redact(err)
+		}
+	}
+
+	return len(lines), nil
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_archive/id",
+		ArchiveURL: "mem://casbin_rule_archive_bin/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	before := time.Now().UTC()
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+	if _, err := a.MigrateIDs(ctx, IDStrategyHash, IDStrategyCanonical); err != nil {
+		t.Fatalf("Expected MigrateIDs() to be successful; got %v", err)
+	}
+
+	archived, err := a.ListArchived(ctx, before, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected ListArchived() to be successful; got %v", err)
+	}
+	got := make([][]string, 0, len(archived))
+	for _, r := range archived {
+		got = append(got, r.Rule.values())
+	}
+	if !arrayEqualsWithoutOrder(got, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}) {
+		t.Errorf("Expected only the removed rules to be archived; got %v", got)
+	}
+
+	if archived, _ := a.ListArchived(ctx, time.Time{}, before); len(archived) != 0 {
+		t.Errorf("Expected no rules archived before the removal; got %v", archived)
+	}
+
+	n, err := a.RestoreArchived(ctx, before, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected RestoreArchived() to be successful; got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 restored rules; got %d", n)
+	}
+	if archived, _ := a.ListArchived(ctx, time.Time{}, time.Time{}); len(archived) != 0 {
+		t.Errorf("Expected the restored rules to be removed from the archive; got %v", archived)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
+
+func TestArchiveDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_archive_disabled/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if _, err := a.RestoreArchived(ctx, time.Time{}, time.Time{}); !errors.Is(err, ErrArchiveDisabled) {
+		t.Errorf("Expected ErrArchiveDisabled; got %v", err)
+	}
+}
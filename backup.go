@@ -0,0 +1,146 @@
+package adapter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/docstore"
+)
+
+// restoreChunkSize is the number of rules written per batch during a restore.
+const restoreChunkSize = 500
+
+// Backup writes the stored policy to the blob with the given key in the bucket at bucketURL.
+//
+// The backup is a gzip-compressed stream of JSON-encoded [CasbinRule] documents, one per line.
+// The blob driver for the bucket URL scheme must be registered by the caller
+// (e.g. by importing gocloud.dev/blob/s3blob).
+func (a *adapter) Backup(ctx context.Context, bucketURL, key string) (err error) {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	bucket, err := blob.OpenBucket(ctx, bucketURL)
+	if err != nil {
+		return fmt.Errorf("could not open bucket: %v", err)
+	}
+	defer bucket.Close()
+
+	w, err := bucket.NewWriter(ctx, key, &blob.WriterOptions{ContentType: "application/gzip"})
+	if err != nil {
+		return fmt.Errorf("could not create backup: %w", err)
+	}
+	defer func() {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+
+	for _, coll := range a.ruleCollections() {
+		if err := a.backupCollection(ctx, coll, enc); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// backupCollection writes the rules of the collection to the encoder.
+func (a *adapter) backupCollection(ctx context.Context, coll *docstore.Collection, enc *json.Encoder) error {
+	iter := a.scope(coll.Query()).Get(ctx)
+	defer iter.Stop()
+	for {
+		var line CasbinRule
+		err := a.next(ctx, iter, &line)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return a.redact(err)
+		}
+		if !a.isRule(&line) {
+			continue
+		}
+		if err := enc.Encode(&line); err != nil {
+			return fmt.Errorf("could not write backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// Restore replaces the stored policy with the backup written by [adapter.Backup] to the blob
+// with the given key in the bucket at bucketURL.
+//
+// Rules are written in chunks while the backup is read; stored rules that are not part of
+// the backup are deleted once all rules have been written.
+func (a *adapter) Restore(ctx context.Context, bucketURL, key string) error {
+	return a.intercept(ctx, OpInfo{Name: "Restore", Write: true}, func(ctx context.Context) error {
+		return a.restore(ctx, bucketURL, key)
+	})
+}
+
+// restore runs Restore within the interceptors.
+func (a *adapter) restore(ctx context.Context, bucketURL, key string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	bucket, err := blob.OpenBucket(ctx, bucketURL)
+	if err != nil {
+		return fmt.Errorf("could not open bucket: %v", err)
+	}
+	defer bucket.Close()
+
+	r, err := bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return fmt.Errorf("could not open backup: %w", err)
+	}
+	defer r.Close()
+
+	zr, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return fmt.Errorf("could not read backup: %w", err)
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(zr)
+	event := ChangeEvent{Operation: OpRestore}
+	keep := make(map[string]struct{})
+	chunk := make([]CasbinRule, 0, restoreChunkSize)
+	for {
+		var line CasbinRule
+		err := dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read backup: %w", err)
+		}
+		line.Namespace = a.config.Namespace
+		if line.ID == "" || a.contentIDs() {
+			line.ID = a.contentID(line)
+		}
+		keep[line.ID] = struct{}{}
+		chunk = append(chunk, line)
+		if len(chunk) == restoreChunkSize {
+			if err := a.putRules(ctx, event, chunk); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err := a.putRules(ctx, event, chunk); err != nil {
+		return err
+	}
+
+	return a.deleteRulesExcept(ctx, event, keep)
+}
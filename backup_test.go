@@ -0,0 +1,60 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+
+	// Enable the file blob driver.
+	_ "gocloud.dev/blob/fileblob"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_backup/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	bucketURL := "file://" + t.TempDir()
+	if err := a.Backup(ctx, bucketURL, "policy.jsonl.gz"); err != nil {
+		t.Fatalf("Expected Backup() to be successful; got %v", err)
+	}
+
+	// Diverge from the backup, then restore it.
+	if err := a.AddPolicy("p", "p", []string{"mallory", "data1", "write"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Restore(ctx, bucketURL, "policy.jsonl.gz"); err != nil {
+		t.Fatalf("Expected Restore() to be successful; got %v", err)
+	}
+
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	if err := a.Restore(ctx, bucketURL, "missing.jsonl.gz"); err == nil {
+		t.Error("Expected Restore() of a missing backup to fail")
+	}
+}
@@ -0,0 +1,346 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+	"golang.org/x/time/rate"
+)
+
+// actionKind is the kind of a write action.
+type actionKind int
+
+const (
+	actionPut actionKind = iota
+	actionCreate
+	actionDelete
+	actionUpdate
+	actionGet
+)
+
+// action is a single write operation on a [CasbinRule].
+type action struct {
+	kind  actionKind
+	line  *CasbinRule
+	mods  docstore.Mods // the modifications of an update
+	moved bool          // whether a delete moves the rule to another document, so it is not archived
+}
+
+// newLimiter returns a rate limiter for the given rate limit and burst (see Config.RateLimit
+// and Config.RateBurst), or nil if rate limiting is disabled.
+func newLimiter(limit float64, burst int) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = max(1, int(limit))
+	}
+	return rate.NewLimiter(rate.Limit(limit), burst)
+}
+
+// wait blocks until the rate limiter permits n write operations.
+func (a *adapter) wait(ctx context.Context, n int) error {
+	limiter := a.limiter()
+	if limiter == nil {
+		return nil
+	}
+	burst := limiter.Burst()
+	for n > 0 {
+		k := min(n, burst)
+		if err := limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
+
+// chunkSize returns the maximum number of actions to send in a single action list: the
+// limiter burst if rate limiting is enabled, or n, and at most Config.BatchSize.
+func (a *adapter) chunkSize(n int) int {
+	size := max(1, n)
+	if limiter := a.limiter(); limiter != nil {
+		size = limiter.Burst()
+	}
+	if a.config.BatchSize > 0 {
+		size = min(size, a.config.BatchSize)
+	}
+	return size
+}
+
+// runActions runs the actions as a single action list of the collection. The configured
+// read consistency is applied to lists that read documents.
+func (a *adapter) runActions(ctx context.Context, coll *docstore.Collection, actions []action) error {
+	actionList := coll.Actions()
+	if a.beforeRead != nil && slices.ContainsFunc(actions, func(act action) bool { return act.kind == actionGet }) {
+		actionList.BeforeDo(a.beforeRead)
+	}
+	docs := make([]docstore.Document, len(actions))
+	for i, act := range actions {
+		doc, err := a.document(act.line)
+		if err != nil {
+			return err
+		}
+		docs[i] = doc
+		switch act.kind {
+		case actionPut:
+			actionList.Put(doc)
+		case actionCreate:
+			actionList.Create(doc)
+		case actionDelete:
+			actionList.Delete(doc)
+		case actionUpdate:
+			actionList.Update(doc, a.mods(act.mods))
+		case actionGet:
+			actionList.Get(doc)
+		}
+	}
+	err := actionList.Do(ctx)
+	for i, act := range actions {
+		if act.kind != actionGet {
+			continue
+		}
+		if decodeErr := a.decode(docs[i], act.line); decodeErr != nil && err == nil {
+			err = decodeErr
+		}
+	}
+	return err
+}
+
+// do executes the actions against the collection.
+//
+// When rate limiting is enabled the actions are split into chunks no larger than the
+// limiter burst, and each chunk waits for the limiter before it is sent. If archival is
+// enabled, the rules deleted by each chunk are archived before it is sent. Chunks throttled
+// by the provider are retried as configured by Config.MaxRetries.
+func (a *adapter) do(ctx context.Context, actions []action) error {
+	_, err := a.run(ctx, actions)
+	return err
+}
+
+// run executes the actions like [adapter.do], and additionally reports for each action
+// whether it may have been applied. Actions in a failed chunk are reported as applied
+// unless the failure can be attributed to them; actions in later chunks are not sent.
+// Deletions of documents that do not exist succeed, on providers that report them as
+// NotFound too.
+//
+// On failure the returned error is a [*BatchError] whose indices refer to the actions.
+func (a *adapter) run(ctx context.Context, actions []action) ([]bool, error) {
+	applied := make([]bool, len(actions))
+	size := a.chunkSize(len(actions))
+	for start := 0; start < len(actions); start += size {
+		chunk := actions[start:min(start+size, len(actions))]
+		if err := a.wait(ctx, len(chunk)); err != nil {
+			return applied, newBatchError(err, nil, actions, start)
+		}
+		if err := a.archiveRemoved(ctx, chunk); err != nil {
+			return applied, newBatchError(fmt.Errorf("could not archive removed rules: %w", a.redact(err)), nil, actions, start)
+		}
+		err := a.redact(a.retry(ctx, func() error { return a.doActions(ctx, chunk) }))
+		for i := range chunk {
+			applied[start+i] = true
+		}
+		if err != nil {
+			var failures []RuleError
+			var alerr docstore.ActionListError
+			if errors.As(err, &alerr) {
+				for _, e := range alerr {
+					if e.Index < 0 {
+						failures = append(failures, RuleError{Index: -1, Err: a.redact(e.Err)})
+						continue
+					}
+					applied[start+e.Index] = false
+					act := actions[start+e.Index]
+					if act.kind == actionDelete && gcerrors.Code(e.Err) == gcerrors.NotFound {
+						// The rule is already gone; see RemoveMode.
+						continue
+					}
+					ruleErr := a.redact(e.Err)
+					if act.kind == actionCreate && gcerrors.Code(e.Err) == gcerrors.AlreadyExists {
+						ruleErr = fmt.Errorf("%w: %w", ErrRuleExists, ruleErr)
+					}
+					failures = append(failures, RuleError{Index: start + e.Index, PType: act.line.PType, Rule: act.line.values(), Err: ruleErr})
+				}
+				if len(failures) == 0 {
+					continue
+				}
+			}
+			return applied, newBatchError(err, failures, actions, start+len(chunk))
+		}
+	}
+
+	return applied, nil
+}
+
+// newBatchError returns a [*BatchError] for err with the given failures, reporting
+// the actions from index notAttempted onwards as not attempted.
+func newBatchError(err error, failures []RuleError, actions []action, notAttempted int) *BatchError {
+	for i := notAttempted; i < len(actions); i++ {
+		line := actions[i].line
+		failures = append(failures, RuleError{Index: i, PType: line.PType, Rule: line.values(), Err: ErrNotAttempted})
+	}
+	return &BatchError{Err: err, Failures: failures}
+}
+
+// exists reports which of the rules are currently stored, keyed by ID.
+func (a *adapter) exists(ctx context.Context, lines []*CasbinRule) (map[string]bool, error) {
+	found := make(map[string]bool, len(lines))
+	if len(lines) == 0 {
+		return found, nil
+	}
+
+	gets := make([]action, 0, len(lines))
+	for _, line := range lines {
+		found[line.ID] = true
+		gets = append(gets, action{kind: actionGet, line: &CasbinRule{ID: line.ID, PType: line.PType}})
+	}
+	if err := a.retry(ctx, func() error { return a.doActions(ctx, gets) }); err != nil {
+		var alerr docstore.ActionListError
+		if !errors.As(err, &alerr) {
+			return nil, err
+		}
+		for _, e := range alerr {
+			if e.Index < 0 || gcerrors.Code(e.Err) != gcerrors.NotFound {
+				return nil, err
+			}
+			found[lines[e.Index].ID] = false
+		}
+	}
+
+	return found, nil
+}
+
+// doWithRollback executes the actions and, if any of them fail, attempts to undo the
+// ones that may have been applied: deleted rules are put back and rules that did not
+// exist before are deleted again.
+func (a *adapter) doWithRollback(ctx context.Context, actions []action) error {
+	puts := make([]*CasbinRule, 0, len(actions))
+	for _, act := range actions {
+		if act.kind == actionPut {
+			puts = append(puts, act.line)
+		}
+	}
+	existed, err := a.exists(ctx, puts)
+	if err != nil {
+		return err
+	}
+
+	applied, err := a.run(ctx, actions)
+	if err == nil {
+		return nil
+	}
+
+	var undo []action
+	for i, act := range actions {
+		if !applied[i] {
+			continue
+		}
+		switch act.kind {
+		case actionPut:
+			if !existed[act.line.ID] {
+				undo = append(undo, action{kind: actionDelete, line: act.line})
+			}
+		case actionDelete:
+			undo = append(undo, action{kind: actionPut, line: act.line})
+		}
+	}
+	if len(undo) == 0 {
+		return err
+	}
+
+	// The rollback bypasses the rate limiter and outlives a cancelled or expired
+	// context, since leaving the storage half-updated is worse than a late write.
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.timeout())
+	defer cancel()
+	if rollbackErr := a.doActions(rollbackCtx, undo); rollbackErr != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+	}
+
+	return err
+}
+
+// putActions returns the actions writing the rules to the collection.
+func putActions(lines []CasbinRule) []action {
+	actions := make([]action, 0, len(lines))
+	for i := range lines {
+		actions = append(actions, action{kind: actionPut, line: &lines[i]})
+	}
+	return actions
+}
+
+// putRules writes the rules to the collection as a change reported with the event, if
+// there are any.
+func (a *adapter) putRules(ctx context.Context, event ChangeEvent, lines []CasbinRule) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	return a.commit(ctx, a.do, putActions(lines), event)
+}
+
+// deleteRulesExcept deletes every stored rule whose ID is not in keep, as a change reported
+// with the event if any rule is deleted.
+func (a *adapter) deleteRulesExcept(ctx context.Context, event ChangeEvent, keep map[string]struct{}) error {
+	current, err := a.collectAll(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var actions []action
+	for i := range current {
+		if _, ok := keep[current[i].ID]; !ok {
+			actions = append(actions, action{kind: actionDelete, line: &current[i]})
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return a.commit(ctx, a.do, actions, event)
+}
+
+// replaceRules makes the stored policy equal to the given rules, as changes reported with
+// the event.
+func (a *adapter) replaceRules(ctx context.Context, event ChangeEvent, lines []CasbinRule) error {
+	if err := a.putRules(ctx, event, lines); err != nil {
+		return err
+	}
+
+	keep := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		keep[line.ID] = struct{}{}
+	}
+	return a.deleteRulesExcept(ctx, event, keep)
+}
+
+// swapActions returns the actions that delete the old rules and put the new rules.
+//
+// Duplicate rules are written once, and a rule that is both deleted and put is left
+// in place, since a document may only appear once in an action list.
+func swapActions(oldLines, newLines []CasbinRule) []action {
+	actions := make([]action, 0, len(oldLines)+len(newLines))
+	putIDs := make(map[string]struct{}, len(newLines))
+	var puts []action
+	for i := range newLines {
+		if _, ok := putIDs[newLines[i].ID]; ok {
+			continue
+		}
+		putIDs[newLines[i].ID] = struct{}{}
+		puts = append(puts, action{kind: actionPut, line: &newLines[i]})
+	}
+
+	deleteIDs := make(map[string]struct{}, len(oldLines))
+	for i := range oldLines {
+		_, isPut := putIDs[oldLines[i].ID]
+		_, isDeleted := deleteIDs[oldLines[i].ID]
+		if isPut || isDeleted {
+			continue
+		}
+		deleteIDs[oldLines[i].ID] = struct{}{}
+		actions = append(actions, action{kind: actionDelete, line: &oldLines[i]})
+	}
+
+	return append(actions, puts...)
+}
@@ -0,0 +1,190 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestRateLimit(t *testing.T) {
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_rate_limit/id",
+		RateLimit: 50,
+		RateBurst: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if got := a.chunkSize(10); got != 2 {
+		t.Errorf("Expected chunk size to equal the burst; got %d", got)
+	}
+
+	// 5 rules with a burst of 2 require at least 3 refills at 50 ops/sec.
+	start := time.Now()
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected SavePolicy() to be rate limited; took %v", elapsed)
+	}
+
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
+
+func TestUpdatePoliciesRollback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// With one write per second and a short timeout, only the first chunk can be sent.
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_update_rollback/id",
+		Timeout:   200 * time.Millisecond,
+		RateLimit: 1,
+		RateBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	err = a.UpdatePolicies("p", "p",
+		[][]string{{"alice", "data1", "read"}},
+		[][]string{{"alice", "data1", "write"}},
+	)
+	if err == nil {
+		t.Fatal("Expected UpdatePolicies() to fail")
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+}
+
+func TestUpdatePoliciesSingleBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_update_batch/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Swapping two rules touches each document twice, which a single action list does not allow.
+	if err := a.UpdatePolicies("p", "p",
+		[][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}},
+		[][]string{{"bob", "data2", "write"}, {"alice", "data1", "read"}},
+	); err != nil {
+		t.Fatalf("Expected UpdatePolicies() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicies("p", "p", [][]string{{"alice", "data1", "read"}}, nil); err == nil {
+		t.Error("Expected UpdatePolicies() with mismatched rules to fail")
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+}
+
+func TestUpdateFilteredPoliciesRollback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_update_filtered_rollback/id",
+		Timeout:   200 * time.Millisecond,
+		RateLimit: 1,
+		RateBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data1", "write"}}, 0, "alice"); err == nil {
+		t.Fatal("Expected UpdateFilteredPolicies() to fail")
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+}
+
+func TestBatchError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_batch_error/id",
+		Timeout:   200 * time.Millisecond,
+		RateLimit: 1,
+		RateBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	err = a.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a *BatchError; got %v", err)
+	}
+	if len(batchErr.Failures) != 2 {
+		t.Fatalf("Expected 2 failures; got %+v", batchErr.Failures)
+	}
+	for i, failure := range batchErr.Failures {
+		if failure.Index != i+1 || !errors.Is(failure.Err, ErrNotAttempted) {
+			t.Errorf("Expected rule %d to be not attempted; got %+v", i+1, failure)
+		}
+	}
+	if got := batchErr.Failures[1].Rule; !util.ArrayEquals(got, []string{"carol", "data3", "read"}) {
+		t.Errorf("Expected the failed rule to be reported; got %v", got)
+	}
+}
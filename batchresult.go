@@ -0,0 +1,164 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+)
+
+// RuleStatus is the outcome of a rule of a batch change, reported in a [BatchResult].
+type RuleStatus int
+
+const (
+	// RuleCreated is the status of an added rule that was not stored.
+	RuleCreated RuleStatus = iota
+	// RuleExisted is the status of an added rule that was already stored.
+	RuleExisted
+	// RuleDeleted is the status of a removed rule that was stored.
+	RuleDeleted
+	// RuleNotFound is the status of a removed rule that was not stored.
+	RuleNotFound
+)
+
+// String returns the name of the status.
+func (s RuleStatus) String() string {
+	switch s {
+	case RuleCreated:
+		return "created"
+	case RuleExisted:
+		return "existed"
+	case RuleDeleted:
+		return "deleted"
+	case RuleNotFound:
+		return "not_found"
+	default:
+		return fmt.Sprintf("RuleStatus(%d)", int(s))
+	}
+}
+
+// RuleResult is the outcome of a rule of a batch change.
+type RuleResult struct {
+	Rule   []string
+	Status RuleStatus
+}
+
+// BatchResult summarizes a batch change rule by rule, so that callers can reconcile their
+// own state without loading the policy again.
+type BatchResult struct {
+	Rules []RuleResult // in the order of the rules of the change
+}
+
+// Count returns the number of rules with the status.
+func (r BatchResult) Count(status RuleStatus) int {
+	var n int
+	for _, rule := range r.Rules {
+		if rule.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// RulesWith returns the rules with the status.
+func (r BatchResult) RulesWith(status RuleStatus) [][]string {
+	var rules [][]string
+	for _, rule := range r.Rules {
+		if rule.Status == status {
+			rules = append(rules, rule.Rule)
+		}
+	}
+	return rules
+}
+
+// AddPoliciesWithResult is AddPoliciesCtx, and additionally reports which rules were
+// created and which were already stored.
+//
+// The statuses are read before the change, so a rule added concurrently by another
+// instance may be reported as created. With Config.WriteBehind they reflect the stored
+// rules, not the buffered changes.
+func (a *adapter) AddPoliciesWithResult(ctx context.Context, sec string, ptype string, rules [][]string) (BatchResult, error) {
+	var result BatchResult
+	err := a.intercept(ctx, OpInfo{Name: "AddPolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		stored, err := a.storedKeys(ctx, ptype, rules)
+		if err != nil {
+			return err
+		}
+		if err := a.addPolicies(ctx, sec, ptype, rules); err != nil {
+			return err
+		}
+		result = a.batchResult(ptype, rules, stored, RuleExisted, RuleCreated)
+		return nil
+	})
+	return result, err
+}
+
+// RemovePoliciesWithResult is RemovePoliciesCtx, and additionally reports which rules
+// were deleted and which were not stored.
+//
+// The statuses are read before the change, like those of AddPoliciesWithResult.
+func (a *adapter) RemovePoliciesWithResult(ctx context.Context, sec string, ptype string, rules [][]string) (BatchResult, error) {
+	var result BatchResult
+	err := a.intercept(ctx, OpInfo{Name: "RemovePolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		stored, err := a.storedKeys(ctx, ptype, rules)
+		if err != nil {
+			return err
+		}
+		if err := a.removePolicies(ctx, sec, ptype, rules); err != nil {
+			return err
+		}
+		result = a.batchResult(ptype, rules, stored, RuleDeleted, RuleNotFound)
+		return nil
+	})
+	return result, err
+}
+
+// batchResult returns the result of a change of the rules, with the status found for the
+// rules whose [ruleKey] is stored, and the status missing for the others.
+func (a *adapter) batchResult(ptype string, rules [][]string, stored map[string]bool, found, missing RuleStatus) BatchResult {
+	result := BatchResult{Rules: make([]RuleResult, len(rules))}
+	for i, rule := range rules {
+		status := missing
+		if stored[ruleKey(a.ruleLine(ptype, rule))] {
+			status = found
+		}
+		result.Rules[i] = RuleResult{Rule: rule, Status: status}
+	}
+	return result
+}
+
+// storedKeys returns the [ruleKey] of the rules of the policy type that are stored.
+func (a *adapter) storedKeys(ctx context.Context, ptype string, rules [][]string) (map[string]bool, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	keys := make(map[string]bool, len(rules))
+	if !a.contentIDs() {
+		stored, err := a.storedRules(ctx, map[string]struct{}{ptype: {}})
+		if err != nil {
+			return nil, err
+		}
+		for key := range stored {
+			keys[key] = true
+		}
+		return keys, nil
+	}
+
+	lines := make([]CasbinRule, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, a.ruleLine(ptype, rule))
+	}
+	if a.config.IDStrategy == IDStrategyCanonical {
+		lines = append(lines, legacyLines(lines)...)
+	}
+	found, err := a.getLines(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range found {
+		keys[ruleKey(line)] = true
+	}
+	return keys, nil
+}
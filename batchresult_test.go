@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBatchResult(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for name, strategy := range map[string]IDStrategy{"Hash": IDStrategyHash, "Random": IDStrategyRandom, "Canonical": IDStrategyCanonical} {
+		t.Run(name, func(t *testing.T) {
+			a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_batch_result_" + name + "/id", IDStrategy: strategy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.close()
+			if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatal(err)
+			}
+
+			added, err := a.AddPoliciesWithResult(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+			if err != nil {
+				t.Fatalf("Expected AddPoliciesWithResult() to be successful; got %v", err)
+			}
+			want := []RuleResult{
+				{Rule: []string{"alice", "data1", "read"}, Status: RuleExisted},
+				{Rule: []string{"bob", "data2", "write"}, Status: RuleCreated},
+			}
+			if !reflect.DeepEqual(added.Rules, want) {
+				t.Errorf("Expected %v; got %v", want, added.Rules)
+			}
+
+			removed, err := a.RemovePoliciesWithResult(ctx, "p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}})
+			if err != nil {
+				t.Fatalf("Expected RemovePoliciesWithResult() to be successful; got %v", err)
+			}
+			if removed.Count(RuleDeleted) != 1 || removed.Count(RuleNotFound) != 1 {
+				t.Errorf("Expected one deleted and one missing rule; got %v", removed.Rules)
+			}
+			if got := removed.RulesWith(RuleNotFound); !reflect.DeepEqual(got, [][]string{{"carol", "data3", "read"}}) {
+				t.Errorf("Expected the missing rule; got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// defaultBreakerCooldown is how long an open circuit breaker fails calls before a probe.
+const defaultBreakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned by the operations of an adapter whose circuit breaker is open,
+// without calling the backend.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breaker is the circuit breaker around backend calls, enabled by Config.BreakerThreshold.
+//
+// The breaker opens after Config.BreakerThreshold consecutive backend failures. While it is
+// open, calls fail with ErrCircuitOpen. Once the cooldown has passed, a single call probes
+// the backend: the breaker closes if it succeeds, and stays open for another cooldown
+// otherwise.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex   // guards the fields below
+	failures  int          // the number of consecutive backend failures
+	openUntil time.Time    // when the next call may probe the backend, if the breaker is open
+	stale     []CasbinRule // the rules of the last successful unfiltered load, if Config.StaleLoads is set
+}
+
+// newBreaker returns the circuit breaker for the configuration, or nil if it is disabled.
+func newBreaker(config *Config) *breaker {
+	if config.BreakerThreshold <= 0 {
+		return nil
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = defaultBreakerCooldown
+	}
+	return &breaker{threshold: config.BreakerThreshold, cooldown: config.BreakerCooldown}
+}
+
+// allow returns ErrCircuitOpen if the breaker is open and the call must fail fast.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	// This call probes the backend; the others fail fast until it reports, or until another
+	// cooldown has passed if it never does.
+	b.openUntil = now.Add(b.cooldown)
+	return nil
+}
+
+// record records the outcome of a backend call allowed by allow.
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isBackendFailure(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// isBackendFailure reports whether err indicates that the backend is unavailable, rather
+// than rejecting the request, such as a document that is not found.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var alerr docstore.ActionListError
+	if errors.As(err, &alerr) {
+		for _, e := range alerr {
+			if isBackendFailure(e.Err) {
+				return true
+			}
+		}
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch gcerrors.Code(err) {
+	case gcerrors.DeadlineExceeded, gcerrors.Internal, gcerrors.ResourceExhausted, gcerrors.Unknown:
+		return true
+	}
+	return false
+}
+
+// BreakerOpen reports whether the circuit breaker is open, e.g. for readiness probes.
+func (a *adapter) BreakerOpen() bool {
+	b := a.breaker
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// setStale keeps the rules of a successful unfiltered load, to serve while the breaker is
+// open if Config.StaleLoads is set.
+func (b *breaker) setStale(lines []CasbinRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stale = lines
+}
+
+// loadStale loads the rules of the last successful unfiltered load into the model if err
+// is ErrCircuitOpen and Config.StaleLoads is set, and returns err otherwise.
+func (a *adapter) loadStale(model model.Model, err error) error {
+	if !errors.Is(err, ErrCircuitOpen) || !a.config.StaleLoads || a.filtered {
+		return err
+	}
+	a.breaker.mu.Lock()
+	lines := a.breaker.stale
+	a.breaker.mu.Unlock()
+	if lines == nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := loadPolicyLine(line, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:              "mem://casbin_rule_breaker/id",
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+		StaleLoads:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Consecutive backend failures open the breaker.
+	errUnavailable := errors.New("unavailable")
+	for i := 0; i < 2; i++ {
+		if err := a.retry(ctx, func() error { return errUnavailable }); !errors.Is(err, errUnavailable) {
+			t.Fatalf("Expected the backend error; got %v", err)
+		}
+	}
+	if !a.BreakerOpen() {
+		t.Fatal("Expected the breaker to be open")
+	}
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected AddPolicy() to fail fast; got %v", err)
+	}
+
+	// Loads serve the last loaded policy while the breaker is open.
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to serve the stale policy; got %v", err)
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("Expected the stale policy to be loaded")
+	}
+
+	// Once the cooldown has passed, a successful probe closes the breaker.
+	time.Sleep(60 * time.Millisecond)
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected the probe to be successful; got %v", err)
+	}
+	if a.BreakerOpen() {
+		t.Error("Expected the breaker to be closed")
+	}
+}
+
+func TestIsBackendFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_breaker_failure/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	// A missing document is not a backend failure.
+	if err := a.Collection().Get(ctx, &CasbinRule{ID: "missing"}); err == nil || isBackendFailure(err) {
+		t.Errorf("Expected a missing document not to be a backend failure; got %v", err)
+	}
+	if !isBackendFailure(context.DeadlineExceeded) {
+		t.Error("Expected a deadline to be a backend failure")
+	}
+}
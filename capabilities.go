@@ -0,0 +1,25 @@
+package adapter
+
+import "net/url"
+
+// Capabilities reports what the provider of the collection supports, so that applications
+// can make informed decisions at runtime, e.g. to enable Config.Transactions or to rely on
+// Config.RuleTTL.
+type Capabilities struct {
+	AtomicBatches     bool // whether SavePolicy and UpdateFilteredPolicies apply their writes all or nothing, which requires Config.Transactions
+	DeleteByQuery     bool // whether the provider deletes the rules matching a filter in one request, rather than by ID after querying them
+	Transactions      bool // whether the provider runs transactions (see Config.Transactions)
+	TTL               bool // whether the provider deletes expired rules natively (see TTLField)
+	StrongConsistency bool // whether reads can reflect every completed write (see Config.ReadConsistency)
+}
+
+// Capabilities returns the capabilities of the provider of the collection. Providers whose
+// [Driver] sets none report no capabilities, the conservative default.
+func (a *adapter) Capabilities() Capabilities {
+	var caps Capabilities
+	if u, err := url.Parse(a.config.URL); err == nil {
+		caps = driverFor(u.Scheme).Capabilities
+	}
+	caps.AtomicBatches = a.txn != nil
+	return caps
+}
@@ -0,0 +1,41 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"gocloud.dev/docstore"
+)
+
+func TestCapabilities(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_capabilities/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if got := a.Capabilities(); got != (Capabilities{}) {
+		t.Errorf("Expected no capabilities for a provider without registered capabilities; got %+v", got)
+	}
+
+	registerDriver(t, "mem", Driver{
+		Capabilities: Capabilities{Transactions: true, StrongConsistency: true},
+		Txn: func(ctx context.Context, _ *docstore.Collection, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	})
+
+	if got, want := a.Capabilities(), (Capabilities{Transactions: true, StrongConsistency: true}); got != want {
+		t.Errorf("Expected %+v; got %+v", want, got)
+	}
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_capabilities_txn/id", Transactions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if got := b.Capabilities(); !got.AtomicBatches {
+		t.Errorf("Expected atomic batches with transactions; got %+v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gocloud.dev/docstore"
+)
+
+// ErrChangeLogDisabled is returned by Changes when no change log collection is configured.
+var ErrChangeLogDisabled = errors.New("change log is disabled")
+
+// changeRecord is a change event recorded in the change log collection.
+type changeRecord struct {
+	ID        string `docstore:"id"`
+	Key       string `docstore:"key"` // sorts by the time of the change, and is the token of the change
+	Event     string `docstore:"event"`
+	Namespace string `docstore:"ns,omitempty"`
+}
+
+// Change is a policy change read from the change log.
+type Change struct {
+	Token string // pass to Changes to read the changes after this one
+	Event ChangeEvent
+}
+
+// changeKey returns a new key for an event, which sorts by the time of the event.
+func changeKey(event ChangeEvent) string {
+	return fmt.Sprintf("%020d_%s", event.Timestamp.UnixNano(), randomID())
+}
+
+// record writes the stamped event to the change log with the given key, if the change log
+// is enabled. Writing a key again overwrites the record, so redelivered events are recorded once.
+func (a *adapter) record(ctx context.Context, key string, event ChangeEvent) error {
+	if a.changes == nil {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	r := changeRecord{ID: a.namespacedID(key), Key: key, Event: string(data), Namespace: a.config.Namespace}
+	if err := a.changes.Put(ctx, &r); err != nil {
+		return fmt.Errorf("could not record change: %w", a.redact(err))
+	}
+	return nil
+}
+
+// ChangeIterator iterates over the changes returned by Changes.
+type ChangeIterator struct {
+	iter *docstore.DocumentIterator
+	a    *adapter
+}
+
+// Next returns the next change, or io.EOF if there are no more changes.
+func (it *ChangeIterator) Next(ctx context.Context) (Change, error) {
+	for {
+		var r changeRecord
+		if err := nextDoc(ctx, it.iter, &r); err != nil {
+			if err == io.EOF {
+				return Change{}, err
+			}
+			return Change{}, it.a.redact(err)
+		}
+		if r.Namespace != it.a.config.Namespace {
+			continue
+		}
+		var event ChangeEvent
+		if err := json.Unmarshal([]byte(r.Event), &event); err != nil {
+			return Change{}, fmt.Errorf("could not decode change %s: %w", r.Key, err)
+		}
+		return Change{Token: r.Key, Event: event}, nil
+	}
+}
+
+// Stop releases the resources of the iterator. It must be called when done.
+func (it *ChangeIterator) Stop() {
+	it.iter.Stop()
+}
+
+// Changes returns the policy changes recorded in the change log after the change with the
+// given token, oldest first, so external systems can tail policy changes. An empty token
+// starts at the oldest recorded change. The iteration is bounded by ctx rather than by the
+// adapter timeout.
+//
+// Changes are recorded when the notifiers are called: after each successful change, or when
+// the event is delivered with Config.Outbox, in which case they are recorded at least once.
+// Changes are ordered by the clock of the instance that made them, so with several writing
+// instances a consumer may want to re-read from a token somewhat before its last one.
+func (a *adapter) Changes(ctx context.Context, sinceToken string) (*ChangeIterator, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+	return a.changesSince(ctx, sinceToken)
+}
+
+// changesSince queries the change log for the changes after the change with the given token.
+func (a *adapter) changesSince(ctx context.Context, sinceToken string) (*ChangeIterator, error) {
+	if a.changes == nil {
+		return nil, ErrChangeLogDisabled
+	}
+
+	iter := a.scopeOther(a.changes.Query()).
+		Where("key", ">", sinceToken).
+		OrderBy("key", docstore.Ascending).
+		Get(ctx)
+	return &ChangeIterator{iter: iter, a: a}, nil
+}
+
+// outboxKey returns the change key of an outbox document ID.
+func outboxKey(id string) string {
+	_, key, _ := strings.Cut(id, "outbox_")
+	return key
+}
@@ -0,0 +1,98 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// readChanges reads all the changes after the token.
+func readChanges(t *testing.T, a *adapter, since string) []Change {
+	t.Helper()
+	ctx := context.Background()
+	it, err := a.Changes(ctx, since)
+	if err != nil {
+		t.Fatalf("Expected Changes() to be successful; got %v", err)
+	}
+	defer it.Stop()
+	var changes []Change
+	for {
+		c, err := it.Next(ctx)
+		if err == io.EOF {
+			return changes
+		} else if err != nil {
+			t.Fatalf("Expected Next() to be successful; got %v", err)
+		}
+		changes = append(changes, c)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		outbox bool
+	}{{"direct", false}, {"outbox", true}} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			a, err := NewWithOption(ctx, &Config{
+				URL:          "mem://casbin_rule_changes_" + tt.name + "/id",
+				ChangeLogURL: "mem://casbin_rule_changes_log_" + tt.name + "/id",
+				Outbox:       tt.outbox,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.close()
+
+			if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+			}
+			if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+			}
+			if err := a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}}); err != nil {
+				t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+			}
+			if tt.outbox {
+				if len(readChanges(t, a, "")) != 0 {
+					t.Errorf("Expected changes to be recorded when the outbox is delivered")
+				}
+				if _, err := a.DeliverOutbox(ctx); err != nil {
+					t.Fatalf("Expected DeliverOutbox() to be successful; got %v", err)
+				}
+			}
+
+			changes := readChanges(t, a, "")
+			if len(changes) != 3 {
+				t.Fatalf("Expected 3 changes; got %v", changes)
+			}
+			for i, op := range []Operation{OpAddPolicy, OpRemovePolicy, OpAddPolicies} {
+				if changes[i].Event.Operation != op {
+					t.Errorf("Expected change %d to be %s; got %+v", i, op, changes[i])
+				}
+			}
+			if rest := readChanges(t, a, changes[0].Token); len(rest) != 2 || rest[0].Token != changes[1].Token {
+				t.Errorf("Expected the changes after the first token; got %v", rest)
+			}
+			if rest := readChanges(t, a, changes[2].Token); len(rest) != 0 {
+				t.Errorf("Expected no changes after the last token; got %v", rest)
+			}
+		})
+	}
+}
+
+func TestChangesDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_changes_disabled/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if _, err := a.Changes(ctx, ""); !errors.Is(err, ErrChangeLogDisabled) {
+		t.Errorf("Expected ErrChangeLogDisabled; got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gocloud.dev/pubsub"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents specification version of the emitted events.
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventTypePrefix prefixes the type of the emitted events, which is followed by the
+	// [Operation] (e.g. "io.casbin.policy.v1.add_policy"). The version is bumped on
+	// incompatible changes to the event data, which is a [ChangeEvent].
+	CloudEventTypePrefix = "io.casbin.policy.v1."
+	// CloudEventsContentType is the media type of events in structured content mode.
+	CloudEventsContentType = "application/cloudevents+json"
+
+	defaultCloudEventSource = "/casbin-go-cloud-adapter"
+)
+
+// CloudEvent is a CloudEvents 1.0 event describing a policy change, in its JSON format.
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"` // the policy type
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            ChangeEvent `json:"data"`
+}
+
+// NewCloudEvent returns the CloudEvent for a policy change.
+func NewCloudEvent(source string, event ChangeEvent) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              randomID(),
+		Source:          source,
+		Type:            CloudEventTypePrefix + string(event.Operation),
+		Subject:         event.PType,
+		Time:            event.Timestamp,
+		DataContentType: "application/json",
+		Data:            event,
+	}
+}
+
+// CloudEventsNotifier is a [Notifier] that emits each change as a [CloudEvent] in
+// structured content mode, to a pubsub topic and/or HTTP endpoints.
+type CloudEventsNotifier struct {
+	Source string        // the event source (defaults to "/casbin-go-cloud-adapter")
+	Topic  *pubsub.Topic // the topic events are published to (not used if nil)
+	URLs   []string      // the HTTP endpoints events are POSTed to
+	Client *http.Client  // the HTTP client (defaults to http.DefaultClient)
+}
+
+var _ Notifier = (*CloudEventsNotifier)(nil)
+
+// Notify implements [Notifier].
+func (c *CloudEventsNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	source := c.Source
+	if source == "" {
+		source = defaultCloudEventSource
+	}
+	ce := NewCloudEvent(source, event)
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if c.Topic != nil {
+		msg := &pubsub.Message{
+			Body:     body,
+			Metadata: map[string]string{"content-type": CloudEventsContentType},
+		}
+		if err := c.Topic.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("could not publish event %s: %w", ce.ID, err))
+		}
+	}
+	if len(c.URLs) > 0 {
+		w := WebhookNotifier{Client: c.Client}
+		for _, url := range c.URLs {
+			if err := w.deliver(ctx, url, CloudEventsContentType, body); err != nil {
+				errs = append(errs, fmt.Errorf("could not send event %s to %s: %w", ce.ID, url, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gocloud.dev/pubsub"
+	_ "gocloud.dev/pubsub/mempubsub"
+)
+
+func TestCloudEventsNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic, err := pubsub.OpenTopic(ctx, "mem://casbin-policy-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer topic.Shutdown(ctx)
+	sub, err := pubsub.OpenSubscription(ctx, "mem://casbin-policy-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Shutdown(ctx)
+
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_cloudevents/id",
+		Notifiers: []Notifier{&CloudEventsNotifier{Source: "/tests", Topic: topic}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.RemovePolicies("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("Expected RemovePolicies() to be successful; got %v", err)
+	}
+
+	msg, err := sub.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Ack()
+	if got := msg.Metadata["content-type"]; got != CloudEventsContentType {
+		t.Errorf("Expected content type %s; got %s", CloudEventsContentType, got)
+	}
+	var ce CloudEvent
+	if err := json.Unmarshal(msg.Body, &ce); err != nil {
+		t.Fatal(err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID == "" || ce.Source != "/tests" || ce.Type != "io.casbin.policy.v1.remove_policies" || ce.Subject != "p" {
+		t.Errorf("Unexpected event attributes %+v", ce)
+	}
+	if ce.Data.Operation != OpRemovePolicies || len(ce.Data.Rules) != 1 || !ce.Time.Equal(ce.Data.Timestamp) {
+		t.Errorf("Unexpected event data %+v", ce.Data)
+	}
+}
@@ -0,0 +1,291 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gocloud.dev/docstore"
+)
+
+// RuleCodec maps rules to the documents of an existing schema, set by Config.Codec, so
+// that the adapter can use a collection without a data migration. Documents are encoded
+// as maps, which every provider supports.
+//
+// The fields of a rule are named by their docstore names, e.g. "ptype", "v0" or "id".
+// The key field of the collection (e.g. _id for MongoDB) must hold the rule ID.
+type RuleCodec interface {
+	// Encode returns the document storing the rule.
+	Encode(rule *CasbinRule) (map[string]interface{}, error)
+	// Decode sets the rule from a document read from the collection.
+	Decode(doc map[string]interface{}, rule *CasbinRule) error
+	// Field returns the dot-separated path of the document field storing the rule field,
+	// which queries, updates and sorts use. The field may be followed by a path within
+	// it, e.g. "meta.owner".
+	Field(field string) string
+}
+
+// FieldCodec is a [RuleCodec] renaming fields, and adding constant attributes to the
+// documents, e.g. to store v0 in subject and the ID in _id:
+//
+//	adapter.FieldCodec{
+//		Fields: map[string]string{"id": "_id", "v0": "subject", "v1": "object", "v2": "action"},
+//		Extra:  map[string]interface{}{"kind": "casbin"},
+//	}
+type FieldCodec struct {
+	Fields map[string]string      // the dot-separated document field path of each renamed rule field (other fields keep their names)
+	Extra  map[string]interface{} // the attributes added to every document, which are ignored when decoding
+}
+
+var _ RuleCodec = FieldCodec{}
+
+// Encode implements [RuleCodec].
+func (c FieldCodec) Encode(rule *CasbinRule) (map[string]interface{}, error) {
+	doc := make(map[string]interface{}, len(c.Extra))
+	for name, value := range c.Extra {
+		doc[name] = value
+	}
+	for name, value := range ruleFields(rule) {
+		setPath(doc, strings.Split(c.Field(name), "."), value)
+	}
+	return doc, nil
+}
+
+// Decode implements [RuleCodec].
+func (c FieldCodec) Decode(doc map[string]interface{}, rule *CasbinRule) error {
+	fields := make(map[string]interface{})
+	for _, name := range ruleFieldNames {
+		if value, ok := getPath(doc, strings.Split(c.Field(name), ".")); ok {
+			fields[name] = value
+		}
+	}
+	return setRuleFields(rule, fields)
+}
+
+// Field implements [RuleCodec].
+func (c FieldCodec) Field(field string) string {
+	if path, ok := c.Fields[field]; ok {
+		return path
+	}
+	name, rest, ok := strings.Cut(field, ".")
+	if path, found := c.Fields[name]; found && ok {
+		return path + "." + rest
+	}
+	return field
+}
+
+// setPath sets the value at the field path of the document, creating nested maps.
+func setPath(doc map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := doc[name].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			doc[name] = nested
+		}
+		doc = nested
+	}
+	doc[path[len(path)-1]] = value
+}
+
+// getPath returns the value at the field path of the document.
+func getPath(doc map[string]interface{}, path []string) (interface{}, bool) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := doc[name].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc = nested
+	}
+	value, ok := doc[path[len(path)-1]]
+	return value, ok
+}
+
+// ruleFieldNames are the docstore names of the fields of CasbinRule.
+var ruleFieldNames = func() []string {
+	t := reflect.TypeOf(CasbinRule{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("docstore"), ",")
+	}
+	return names
+}()
+
+// ruleFields returns the fields of the rule by docstore name, leaving out the empty fields
+// that are omitted from documents.
+func ruleFields(rule *CasbinRule) map[string]interface{} {
+	v := reflect.ValueOf(rule).Elem()
+	fields := make(map[string]interface{}, len(ruleFieldNames))
+	for i, name := range ruleFieldNames {
+		f := v.Field(i)
+		if strings.HasSuffix(v.Type().Field(i).Tag.Get("docstore"), ",omitempty") && f.IsZero() {
+			continue
+		}
+		fields[name] = f.Interface()
+	}
+	return fields
+}
+
+// setRuleFields sets the fields of the rule from values by docstore name, as decoded by
+// the provider, e.g. int64 for int fields or []interface{} for slices.
+func setRuleFields(rule *CasbinRule, fields map[string]interface{}) error {
+	*rule = CasbinRule{}
+	v := reflect.ValueOf(rule).Elem()
+	for i, name := range ruleFieldNames {
+		value, ok := fields[name]
+		if !ok || value == nil {
+			continue
+		}
+		if err := setValue(v.Field(i), reflect.ValueOf(value)); err != nil {
+			return fmt.Errorf("could not decode field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue sets dst to src, converting between the types of provider values and the
+// types of CasbinRule fields.
+func setValue(dst, src reflect.Value) error {
+	for src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	switch dst.Kind() {
+	case reflect.Interface:
+		dst.Set(src)
+		return nil
+	case reflect.String, reflect.Bool:
+		if src.Kind() == dst.Kind() {
+			dst.Set(src.Convert(dst.Type()))
+			return nil
+		}
+	case reflect.Int, reflect.Int64:
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetInt(src.Int())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetInt(int64(src.Uint()))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetInt(int64(src.Float()))
+			return nil
+		}
+	case reflect.Slice:
+		if src.Kind() == reflect.Slice {
+			s := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				if err := setValue(s.Index(i), src.Index(i)); err != nil {
+					return err
+				}
+			}
+			dst.Set(s)
+			return nil
+		}
+	case reflect.Map:
+		if src.Kind() == reflect.Map && src.Type().Key().Kind() == reflect.String {
+			m := reflect.MakeMapWithSize(dst.Type(), src.Len())
+			iter := src.MapRange()
+			for iter.Next() {
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := setValue(elem, iter.Value()); err != nil {
+					return err
+				}
+				m.SetMapIndex(iter.Key().Convert(dst.Type().Key()), elem)
+			}
+			dst.Set(m)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot decode %s into %s", src.Type(), dst.Type())
+}
+
+// RuleField returns the dot-separated path of the document field storing the rule field
+// (e.g. [TTLField]) in the rule collections, as mapped by Codec. It is intended for the
+// [SchemaFunc] of the drivers.
+func (c *Config) RuleField(field string) string {
+	if c.Codec == nil {
+		return field
+	}
+	return c.Codec.Field(field)
+}
+
+// field returns the field path of the rule field in the documents of the rule collections.
+func (a *adapter) field(name string) docstore.FieldPath {
+	return docstore.FieldPath(a.config.RuleField(name))
+}
+
+// document returns the document of the rule in the rule collections. Only leases are
+// written with their revision, so that rules read from the collections are written back
+// unconditionally.
+func (a *adapter) document(line *CasbinRule) (docstore.Document, error) {
+	if !line.isLease() {
+		line.Revision = nil
+	}
+	if a.config.Codec == nil {
+		return line, nil
+	}
+	doc, err := a.config.Codec.Encode(line)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode rule %s: %w", line.ID, err)
+	}
+	if line.isLease() {
+		// The provider only assigns revisions to documents with a revision field.
+		doc[docstore.DefaultRevisionField] = line.Revision
+	}
+	return doc, nil
+}
+
+// mods returns the modifications with the field paths of the rule collections.
+func (a *adapter) mods(mods docstore.Mods) docstore.Mods {
+	if a.config.Codec == nil {
+		return mods
+	}
+	mapped := make(docstore.Mods, len(mods))
+	for fp, value := range mods {
+		mapped[a.field(string(fp))] = value
+	}
+	return mapped
+}
+
+// decode sets the rule from a document read from the rule collections.
+func (a *adapter) decode(doc docstore.Document, line *CasbinRule) error {
+	if m, ok := doc.(map[string]interface{}); ok && a.config.Codec != nil {
+		if err := a.config.Codec.Decode(m, line); err != nil {
+			return fmt.Errorf("could not decode document: %w", err)
+		}
+		if line.isLease() {
+			line.Revision = m[docstore.DefaultRevisionField]
+		}
+	}
+	if !line.isLease() {
+		line.Revision = nil
+	}
+	return nil
+}
+
+// next reads the next rule of the query iterator of a rule collection.
+func (a *adapter) next(ctx context.Context, iter *docstore.DocumentIterator, line *CasbinRule) error {
+	if a.config.Codec == nil {
+		if err := nextDoc(ctx, iter, line); err != nil {
+			return err
+		}
+		return a.decode(line, line)
+	}
+	doc := map[string]interface{}{}
+	if err := nextDoc(ctx, iter, doc); err != nil {
+		return err
+	}
+	return a.decode(doc, line)
+}
+
+// get reads the rule with the ID of line from a rule collection.
+func (a *adapter) get(ctx context.Context, coll *docstore.Collection, line *CasbinRule, opts ...docstore.FieldPath) error {
+	doc, err := a.document(line)
+	if err != nil {
+		return err
+	}
+	if err := coll.Get(ctx, doc, opts...); err != nil {
+		return err
+	}
+	return a.decode(doc, line)
+}
@@ -0,0 +1,99 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestCodec(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_codec/_id",
+		Namespace: "billing",
+		Codec: FieldCodec{
+			Fields: map[string]string{"id": "_id", "ptype": "kind", "v0": "subject", "v1": "object", "v2": "action", "ns": "scope.namespace"},
+			Extra:  map[string]interface{}{"source": "casbin"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("g", "g", 1, "admin"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+
+	// The documents follow the schema of the codec.
+	iter := a.Collection().Query().Get(ctx)
+	defer iter.Stop()
+	n := 0
+	for {
+		doc := map[string]interface{}{}
+		err := iter.Next(ctx, doc)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		scope, _ := doc["scope"].(map[string]interface{})
+		if doc["kind"] != "p" || doc["subject"] == nil || doc["source"] != "casbin" || scope["namespace"] != "billing" || doc["v0"] != nil {
+			t.Errorf("Expected the document to follow the codec; got %v", doc)
+		}
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 documents; got %d", n)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.Enforce("bob", "data2", "read"); !ok {
+		t.Error("Expected the updated rule to be loaded")
+	}
+	if err := e.LoadFilteredPolicy(Filter{FieldPath: []string{"v0"}, Op: EqualOp, Value: "alice"}); err != nil {
+		t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+	}
+	if policy, _ := e.GetPolicy(); len(policy) != 1 || policy[0][0] != "alice" {
+		t.Errorf("Expected the filter to apply to the codec fields; got %v", policy)
+	}
+}
+
+func TestFieldCodecDecode(t *testing.T) {
+	codec := FieldCodec{Fields: map[string]string{"values": "rule.values"}}
+	doc := map[string]interface{}{
+		"id":       "1",
+		"ptype":    "p",
+		"rule":     map[string]interface{}{"values": []interface{}{"alice", "data1", "read"}},
+		"n":        int64(3),
+		"priority": float64(7),
+		"meta":     map[string]interface{}{"owner": "team-a"},
+	}
+	var line CasbinRule
+	if err := codec.Decode(doc, &line); err != nil {
+		t.Fatalf("Expected Decode() to be successful; got %v", err)
+	}
+	if len(line.Values) != 3 || line.FieldCount != 3 || line.Priority != 7 || line.Meta["owner"] != "team-a" {
+		t.Errorf("Expected the provider values to be converted; got %+v", line)
+	}
+
+	doc["n"] = "three"
+	if err := codec.Decode(doc, &line); err == nil {
+		t.Error("Expected Decode() to fail with a value of the wrong type")
+	}
+}
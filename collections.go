@@ -0,0 +1,188 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"gocloud.dev/docstore"
+)
+
+// queryFunc adds conditions to a query of a rule collection.
+type queryFunc func(*docstore.Query) *docstore.Query
+
+// isGroupingPType reports whether rules of the policy type are grouping rules, e.g. "g" or "g2".
+func isGroupingPType(ptype string) bool {
+	return strings.HasPrefix(ptype, "g")
+}
+
+// ruleCollections returns the collections holding rules: the primary collection or the
+// shards, followed by the grouping collection if grouping rules are stored separately.
+func (a *adapter) ruleCollections() []*docstore.Collection {
+	colls := a.policyCollections()
+	if a.grouping != nil {
+		colls = append(slices.Clip(colls), a.grouping)
+	}
+	return colls
+}
+
+// policyCollections returns the collections holding rules other than separately stored
+// grouping rules: the shards if sharding is enabled, or the primary collection.
+func (a *adapter) policyCollections() []*docstore.Collection {
+	if len(a.shards) > 0 {
+		return a.shards
+	}
+	return []*docstore.Collection{a.collection}
+}
+
+// shardFor returns the shard of the document ID.
+func (a *adapter) shardFor(id string) *docstore.Collection {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return a.shards[h.Sum32()%uint32(len(a.shards))]
+}
+
+// collectionFor returns the collection that stores the document. Internal documents are
+// always stored in the primary collection.
+func (a *adapter) collectionFor(line *CasbinRule) *docstore.Collection {
+	switch {
+	case line.isInternal():
+		return a.collection
+	case a.grouping != nil && isGroupingPType(line.PType):
+		return a.grouping
+	case len(a.shards) > 0:
+		return a.shardFor(line.ID)
+	default:
+		return a.collection
+	}
+}
+
+// ptypeCollections returns the collections that may hold rules of the policy type.
+func (a *adapter) ptypeCollections(ptype string) []*docstore.Collection {
+	if a.grouping != nil && isGroupingPType(ptype) {
+		return []*docstore.Collection{a.grouping}
+	}
+	return a.policyCollections()
+}
+
+// collectFrom reads the rules matched by the query built by build in each collection.
+// A nil build matches all rules.
+func (a *adapter) collectFrom(ctx context.Context, colls []*docstore.Collection, build queryFunc) ([]CasbinRule, error) {
+	var lines []CasbinRule
+	for _, coll := range colls {
+		query := a.scope(coll.Query())
+		if build != nil {
+			query = build(query)
+			if err := a.checkPlan(query, nil); err != nil {
+				return nil, err
+			}
+		}
+		var matched []CasbinRule
+		err := a.retry(ctx, func() (err error) {
+			matched, err = a.collectRules(ctx, query)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, matched...)
+	}
+	if lines == nil {
+		lines = make([]CasbinRule, 0)
+	}
+	return lines, nil
+}
+
+// collectAll reads the rules matched by the query built by build in every rule collection.
+func (a *adapter) collectAll(ctx context.Context, build queryFunc) ([]CasbinRule, error) {
+	return a.collectFrom(ctx, a.ruleCollections(), build)
+}
+
+// collectPType reads the rules of the policy type matched by the query built by build.
+func (a *adapter) collectPType(ctx context.Context, ptype string, build queryFunc) ([]CasbinRule, error) {
+	return a.collectFrom(ctx, a.ptypeCollections(ptype), func(q *docstore.Query) *docstore.Query {
+		q = q.Where(a.field("ptype"), EqualOp, ptype)
+		if build != nil {
+			q = build(q)
+		}
+		return q
+	})
+}
+
+// forEachPage pages through every rule collection in ID order, calling fn with each page
+// of up to limit documents, including internal documents. Each page gets the adapter
+// timeout, so large collections are not bounded by a single timeout.
+func (a *adapter) forEachPage(ctx context.Context, limit int, fn func(ctx context.Context, lines []CasbinRule) error) error {
+	for _, coll := range a.ruleCollections() {
+		last := ""
+		for {
+			n, err := a.page(ctx, coll, &last, limit, fn)
+			if err != nil {
+				return err
+			}
+			if n < limit {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// page calls fn with the page of documents of the collection following the ID last,
+// advances last, and returns the number of documents read.
+func (a *adapter) page(ctx context.Context, coll *docstore.Collection, last *string, limit int, fn func(ctx context.Context, lines []CasbinRule) error) (int, error) {
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+
+	lines, err := a.pageRules(ctx, coll, *last, limit)
+	if err != nil || len(lines) == 0 {
+		return 0, err
+	}
+	*last = lines[len(lines)-1].ID
+	return len(lines), fn(ctx, lines)
+}
+
+// doActions runs the actions with one action list per collection. Failures are reported
+// as a single [docstore.ActionListError] whose indices refer to the actions.
+func (a *adapter) doActions(ctx context.Context, actions []action) error {
+	if a.grouping == nil && len(a.shards) == 0 {
+		return a.runActions(ctx, a.collection, actions)
+	}
+
+	indexes := make(map[*docstore.Collection][]int)
+	for i := range actions {
+		coll := a.collectionFor(actions[i].line)
+		indexes[coll] = append(indexes[coll], i)
+	}
+	var alerr docstore.ActionListError
+	for _, coll := range a.ruleCollections() {
+		idx := indexes[coll]
+		if len(idx) == 0 {
+			continue
+		}
+		collActions := make([]action, len(idx))
+		for i, j := range idx {
+			collActions[i] = actions[j]
+		}
+		err := a.runActions(ctx, coll, collActions)
+		if err == nil {
+			continue
+		}
+		var collErr docstore.ActionListError
+		if !errors.As(err, &collErr) {
+			return err
+		}
+		for _, e := range collErr {
+			if e.Index >= 0 {
+				e.Index = idx[e.Index]
+			}
+			alerr = append(alerr, e)
+		}
+	}
+	if len(alerr) > 0 {
+		return alerr
+	}
+	return nil
+}
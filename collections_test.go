@@ -0,0 +1,154 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestGroupingURL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_split_p/id",
+		GroupingURL: "mem://casbin_rule_split_g/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"bob", "data2_admin"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		lines func() ([]CasbinRule, error)
+		ptype string
+		want  int
+	}{
+		{"policy", func() ([]CasbinRule, error) { return a.collectRules(ctx, a.collection.Query()) }, "p", 4},
+		{"grouping", func() ([]CasbinRule, error) { return a.collectRules(ctx, a.grouping.Query()) }, "g", 2},
+	} {
+		lines, err := tt.lines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != tt.want {
+			t.Errorf("Expected %d rules in the %s collection; got %d", tt.want, tt.name, len(lines))
+		}
+		for _, line := range lines {
+			if line.PType != tt.ptype {
+				t.Errorf("Expected only %q rules in the %s collection; got %v", tt.ptype, tt.name, line)
+			}
+		}
+	}
+
+	if err := a.UpdatePolicies("g", "g", [][]string{{"bob", "data2_admin"}}, [][]string{{"carol", "data2_admin"}}); err != nil {
+		t.Fatalf("Expected UpdatePolicies() to be successful; got %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("g", "g", 0, "alice"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+	if ok, _ := e.Enforce("carol", "data2", "read"); !ok {
+		t.Error("Expected the grouping rules to be loaded from the grouping collection")
+	}
+
+	var out strings.Builder
+	if err := a.ExportStream(ctx, &out, FormatCSV); err != nil {
+		t.Fatalf("Expected ExportStream() to be successful; got %v", err)
+	}
+	if got := strings.Count(out.String(), "\n"); got != 5 {
+		t.Errorf("Expected the export to contain the rules of both collections; got %q", out.String())
+	}
+	if err := a.HealthCheck(ctx); err != nil {
+		t.Errorf("Expected HealthCheck() to be successful; got %v", err)
+	}
+}
+
+func TestShardURLs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_shard_0/id",
+		ShardURLs:  []string{"mem://casbin_rule_shard_1/id", "mem://casbin_rule_shard_2/id"},
+		IDStrategy: IDStrategyCanonical,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"dave", "data3", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+
+	total := 0
+	for i, shard := range a.shards {
+		lines, err := a.collectRules(ctx, shard.Query())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := range lines {
+			if a.shardFor(lines[j].ID) != shard {
+				t.Errorf("Expected rule %v to be stored in its hash shard; found in shard %d", lines[j], i)
+			}
+		}
+		total += len(lines)
+	}
+	if total != 7 {
+		t.Errorf("Expected 7 rules across the shards; got %d", total)
+	}
+
+	if err := a.RemovePolicy("p", "p", []string{"dave", "data3", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"carol", "data3", "read"}, []string{"carol", "data3", "write"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+		{"carol", "data3", "write"},
+	})
+	if err := a.HealthCheck(ctx); err != nil {
+		t.Errorf("Expected HealthCheck() to be successful; got %v", err)
+	}
+}
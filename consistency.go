@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+
+	"gocloud.dev/docstore"
+)
+
+// Consistency is the read consistency requested from the provider.
+//
+// It is applied by the [ReadOptionsFunc] of the [Driver] registered by the driver package of the provider:
+// the awsdynamodb package sets ConsistentRead, and the gcpfirestore package serves eventual
+// reads as stale reads. MongoDB reads use the read concern and read preference of the
+// client, which cannot be set per read.
+type Consistency int
+
+const (
+	// ConsistencyDefault uses the default read consistency of the provider.
+	ConsistencyDefault Consistency = iota
+	// ConsistencyStrong requests reads that reflect every write completed before the read,
+	// so an enforcer that just wrote a rule can read it back.
+	ConsistencyStrong
+	// ConsistencyEventual allows reads that do not reflect recent writes, which some
+	// providers serve at a lower cost.
+	ConsistencyEventual
+)
+
+// String returns the name of the consistency.
+func (c Consistency) String() string {
+	switch c {
+	case ConsistencyDefault:
+		return "default"
+	case ConsistencyStrong:
+		return "strong"
+	case ConsistencyEventual:
+		return "eventual"
+	default:
+		return fmt.Sprintf("Consistency(%d)", int(c))
+	}
+}
+
+// ReadOptionsFunc configures a provider read request for the consistency. asFunc is the
+// function passed to the Query.BeforeQuery and ActionList.BeforeDo callbacks of the
+// collection; request types the function does not handle must be ignored.
+type ReadOptionsFunc func(asFunc func(interface{}) bool, consistency Consistency) error
+
+// newBeforeRead returns the callback that applies the configured read consistency to
+// provider read requests, or nil if the provider default is used or the provider has no
+// [ReadOptionsFunc].
+func newBeforeRead(config *Config) func(asFunc func(interface{}) bool) error {
+	if config.ReadConsistency == ConsistencyDefault {
+		return nil
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil
+	}
+	fn := driverFor(u.Scheme).ReadOptions
+	if fn == nil {
+		return nil
+	}
+
+	consistency := config.ReadConsistency
+	return func(asFunc func(interface{}) bool) error {
+		return fn(asFunc, consistency)
+	}
+}
+
+// readQuery applies the configured read consistency to the query.
+func (a *adapter) readQuery(query *docstore.Query) *docstore.Query {
+	if a.beforeRead == nil {
+		return query
+	}
+	return query.BeforeQuery(a.beforeRead)
+}
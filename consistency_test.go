@@ -0,0 +1,55 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadConsistency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []Consistency
+	registerDriver(t, "mem", Driver{
+		ReadOptions: func(_ func(interface{}) bool, consistency Consistency) error {
+			calls = append(calls, consistency)
+			return nil
+		},
+	})
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_consistency/id", ReadConsistency: ConsistencyStrong})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected writes not to be given read options; got %v", calls)
+	}
+	if _, err := a.GetPolicyMeta(ctx, "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected GetPolicyMeta() to be successful; got %v", err)
+	}
+	if _, err := a.GetPoliciesForSubject(ctx, "alice"); err != nil {
+		t.Fatalf("Expected GetPoliciesForSubject() to be successful; got %v", err)
+	}
+	if len(calls) == 0 {
+		t.Fatal("Expected the read options function to be called for reads")
+	}
+	for _, c := range calls {
+		if c != ConsistencyStrong {
+			t.Errorf("Expected strong consistency; got %s", c)
+		}
+	}
+
+	b, err := New(ctx, "mem://casbin_rule_consistency_default/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if b.beforeRead != nil {
+		t.Error("Expected no read options with the default consistency")
+	}
+}
@@ -0,0 +1,66 @@
+package adapter
+
+// DuplicateRule is a rule stored in several documents under different IDs, e.g. after a
+// change of the ID strategy or of the rule hashing, found by loads with Config.DedupLoads.
+type DuplicateRule struct {
+	PType string
+	Rule  []string
+	IDs   []string // the IDs of the documents holding the rule; the first was loaded
+}
+
+// dedup tracks the rules passed by a load, to skip and report the rules stored more than
+// once.
+type dedup struct {
+	seen       map[string]int // the index of the rule in rules, by ruleKey
+	rules      []DuplicateRule
+	duplicated []int // the indexes of the rules stored more than once
+}
+
+func newDedup() *dedup {
+	return &dedup{seen: make(map[string]int)}
+}
+
+// skip reports whether the line holds a rule already passed by the load, recording its ID.
+func (d *dedup) skip(line CasbinRule) bool {
+	key := ruleKey(line)
+	i, ok := d.seen[key]
+	if !ok {
+		d.seen[key] = len(d.rules)
+		d.rules = append(d.rules, DuplicateRule{PType: line.PType, Rule: line.values(), IDs: []string{line.ID}})
+		return false
+	}
+	if len(d.rules[i].IDs) == 1 {
+		d.duplicated = append(d.duplicated, i)
+	}
+	d.rules[i].IDs = append(d.rules[i].IDs, line.ID)
+	return true
+}
+
+// duplicates returns the rules stored more than once, in load order.
+func (d *dedup) duplicates() []DuplicateRule {
+	dups := make([]DuplicateRule, 0, len(d.duplicated))
+	for _, i := range d.duplicated {
+		dups = append(dups, d.rules[i])
+	}
+	return dups
+}
+
+// Duplicates returns the rules stored more than once that the last load skipped with
+// Config.DedupLoads, so they can be cleaned up, e.g. by removing all but the first ID of
+// each rule. It returns nil if DedupLoads is disabled or nothing has been loaded.
+func (a *adapter) Duplicates() []DuplicateRule {
+	if dups := a.duplicates.Load(); dups != nil {
+		return *dups
+	}
+	return nil
+}
+
+// reportDuplicates records the duplicates found by a load, and logs them.
+func (a *adapter) reportDuplicates(d *dedup) {
+	dups := d.duplicates()
+	a.duplicates.Store(&dups)
+	if len(dups) == 0 {
+		return
+	}
+	a.config.logger().Printf("load skipped %d rule(s) stored more than once under different IDs (see Duplicates)", len(dups))
+}
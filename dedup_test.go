@@ -0,0 +1,54 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestDedupLoads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf bytes.Buffer
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_dedup/id",
+		IDStrategy: IDStrategyRandom,
+		DedupLoads: true,
+		Logger:     log.New(&buf, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	// Random IDs store every addition in a new document.
+	for i := 0; i < 3; i++ {
+		if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("Expected the policy to load; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+
+	dups := a.Duplicates()
+	if len(dups) != 1 {
+		t.Fatalf("Expected one duplicate rule; got %v", dups)
+	}
+	if d := dups[0]; d.PType != "p" || strings.Join(d.Rule, ",") != "alice,data1,read" || len(d.IDs) != 3 {
+		t.Errorf("Expected the rule stored three times; got %+v", d)
+	}
+	if !strings.Contains(buf.String(), "load skipped 1 rule(s)") {
+		t.Errorf("Expected the duplicates to be logged; got %q", buf.String())
+	}
+}
@@ -0,0 +1,148 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// diff compares the policies of the model with the stored rules. It returns the model
+// rules that are not stored, and the stored rules that are not in the model. Rules are
+// compared by content, so the result does not depend on the ID strategy.
+//
+// It also returns the distinct rules of the model, carrying the IDs of the matching
+// stored documents.
+func (a *adapter) diff(ctx context.Context, model model.Model) (lines, added, removed []CasbinRule, err error) {
+	stored, err := a.collectAll(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Rules outside their validity window are not loaded into the model, so their absence
+	// from the model is not a removal. If the model has such a rule, it was granted again
+	// and is rewritten without the window.
+	now := time.Now()
+	storedIDs := make(map[string]string, len(stored))
+	inactive := make(map[string]struct{})
+	for _, line := range stored {
+		storedIDs[ruleKey(line)] = line.ID
+		if !line.activeAt(now) {
+			inactive[ruleKey(line)] = struct{}{}
+		}
+	}
+	modelKeys := make(map[string]struct{})
+	for _, line := range a.modelLines(model) {
+		key := ruleKey(line)
+		if _, ok := modelKeys[key]; ok {
+			continue
+		}
+		modelKeys[key] = struct{}{}
+		id, isStored := storedIDs[key]
+		if isStored {
+			line.ID = id
+		}
+		if _, isInactive := inactive[key]; !isStored || isInactive {
+			added = append(added, line)
+		}
+		lines = append(lines, line)
+	}
+	for _, line := range stored {
+		if _, ok := modelKeys[ruleKey(line)]; !ok && line.activeAt(now) {
+			removed = append(removed, line)
+		}
+	}
+
+	slices.SortFunc(added, compareRules)
+	slices.SortFunc(removed, compareRules)
+	return lines, added, removed, nil
+}
+
+// Diff compares the policies of the model with the stored rules, e.g. to detect drift
+// caused by out-of-band writes before calling SavePolicy. It returns the rules that are
+// only in the model and the rules that are only in storage, prefixed with their policy type.
+func (a *adapter) Diff(ctx context.Context, model model.Model) (added, removed [][]string, err error) {
+	if err := a.begin(); err != nil {
+		return nil, nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	_, addedLines, removedLines, err := a.diff(ctx, model)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stringPolicies(addedLines), stringPolicies(removedLines), nil
+}
+
+// Sync makes the stored rules match the policies of the model, like SavePolicy, but only
+// writes the rules that were added and deletes the rules that were removed. Unchanged rules
+// are not written, which makes it much cheaper than SavePolicy for large policies on
+// backends billed per write.
+//
+// The writes are not atomic; if Sync fails, calling it again completes the synchronization.
+func (a *adapter) Sync(ctx context.Context, model model.Model) error {
+	return a.intercept(ctx, OpInfo{Name: "Sync", Write: true}, func(ctx context.Context) error {
+		return a.sync(ctx, model)
+	})
+}
+
+// sync runs Sync within the interceptors.
+func (a *adapter) sync(ctx context.Context, model model.Model) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if a.filtered {
+		return errors.New("cannot sync a filtered policy")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+
+	ctx, release, err := a.acquireSaveLock(ctx, 0)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	lines, added, removed, err := a.diff(ctx, model)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]action, 0, len(added)+len(removed))
+	for i := range removed {
+		actions = append(actions, action{kind: actionDelete, line: &removed[i]})
+	}
+	for i := range added {
+		actions = append(actions, action{kind: actionPut, line: &added[i]})
+	}
+	if len(actions) > 0 {
+		if err := a.commit(ctx, a.do, actions, ChangeEvent{Operation: OpSync}); err != nil {
+			return lockError(ctx, err)
+		}
+	}
+
+	if a.history != nil && len(actions) > 0 {
+		if _, err := a.snapshot(ctx, lines); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stringPolicies returns the rules prefixed with their policy type.
+func stringPolicies(lines []CasbinRule) [][]string {
+	rules := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		rules = append(rules, line.toStringPolicy())
+	}
+	return rules
+}
@@ -0,0 +1,119 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestDiff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_diff/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	added, removed, err := a.Diff(ctx, e.GetModel())
+	if err != nil {
+		t.Fatalf("Expected Diff() to be successful; got %v", err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no drift after SavePolicy(); got added %v, removed %v", added, removed)
+	}
+
+	// Out-of-band write, and an in-memory change that was not saved.
+	if err := a.AddPolicy("p", "p", []string{"mallory", "data1", "write"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddGroupingPolicy("bob", "data2_admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed, err = a.Diff(ctx, e.GetModel())
+	if err != nil {
+		t.Fatalf("Expected Diff() to be successful; got %v", err)
+	}
+	if want := [][]string{{"g", "bob", "data2_admin"}}; !util.Array2DEquals(want, added) {
+		t.Errorf("Expected added rules %v; got %v", want, added)
+	}
+	if want := [][]string{{"p", "bob", "data2", "write"}, {"p", "mallory", "data1", "write"}}; !util.Array2DEquals(want, removed) {
+		t.Errorf("Expected removed rules %v; got %v", want, removed)
+	}
+}
+
+func TestSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_sync/id", IDStrategy: IDStrategyRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sync(ctx, e.GetModel()); err != nil {
+		t.Fatalf("Expected Sync() to be successful; got %v", err)
+	}
+	before, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]string, len(before))
+	for _, line := range before {
+		ids[ruleKey(line)] = line.ID
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"mallory", "data1", "write"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sync(ctx, e.GetModel()); err != nil {
+		t.Fatalf("Expected Sync() to be successful; got %v", err)
+	}
+
+	added, removed, err := a.Diff(ctx, e.GetModel())
+	if err != nil {
+		t.Fatalf("Expected Diff() to be successful; got %v", err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no drift after Sync(); got added %v, removed %v", added, removed)
+	}
+
+	after, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 5 {
+		t.Errorf("Expected 5 stored rules; got %d", len(after))
+	}
+	for _, line := range after {
+		if id, ok := ids[ruleKey(line)]; ok && id != line.ID {
+			t.Errorf("Expected unchanged rule %v to keep its ID", line.toStringPolicy())
+		}
+	}
+}
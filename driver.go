@@ -0,0 +1,45 @@
+package adapter
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Driver holds the hooks through which a driver package adapts the adapter to the provider
+// of the collections opened from URLs with a scheme. Every hook is optional; the adapter
+// falls back to its generic behaviour for hooks that are not set.
+type Driver struct {
+	Capabilities Capabilities    // the capabilities of the provider; AtomicBatches is set by the adapter from its configuration
+	Schema       SchemaFunc      // configures the provider-side schema of collections, for EnsureSchema
+	Txn          TxnFunc         // runs transactions, used when Config.Transactions is set
+	Throttle     ThrottleFunc    // recognizes throttling errors, retried when Config.MaxRetries is set
+	ReadOptions  ReadOptionsFunc // applies Config.ReadConsistency to reads
+	Plan         PlanFunc        // reports the query fallbacks, for Config.QueryFallback
+	IndexError   IndexErrorFunc  // recognizes missing-index errors, which queries return as an [IndexError]
+	Strategy     StrategyFunc    // sets the recommended settings, applied by WithStrategy
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// RegisterDriver registers the hooks of the provider of collections opened from URLs with
+// the given scheme. It is intended to be called from the init function of the driver
+// packages; registering a scheme twice panics.
+func RegisterDriver(scheme string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, ok := drivers[scheme]; ok {
+		panic(fmt.Sprintf("driver already registered for scheme %q", scheme))
+	}
+	drivers[scheme] = driver
+}
+
+// driverFor returns the hooks registered for the scheme, which are all unset if the scheme
+// has no registered [Driver].
+func driverFor(scheme string) Driver {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	return drivers[scheme]
+}
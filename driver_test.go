@@ -0,0 +1,31 @@
+package adapter
+
+import "testing"
+
+// registerDriver registers the driver for the scheme until the test completes.
+func registerDriver(t *testing.T, scheme string, driver Driver) {
+	t.Helper()
+	RegisterDriver(scheme, driver)
+	t.Cleanup(func() {
+		driversMu.Lock()
+		delete(drivers, scheme)
+		driversMu.Unlock()
+	})
+}
+
+func TestRegisterDriver(t *testing.T) {
+	registerDriver(t, "test", Driver{Capabilities: Capabilities{TTL: true}})
+	if got := driverFor("test"); !got.Capabilities.TTL || got.Txn != nil {
+		t.Errorf("Expected the registered driver; got %+v", got)
+	}
+	if got := driverFor("unregistered"); got.Capabilities != (Capabilities{}) || got.Schema != nil {
+		t.Errorf("Expected no hooks for an unregistered scheme; got %+v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a scheme twice to panic")
+		}
+	}()
+	RegisterDriver("test", Driver{})
+}
@@ -1,7 +1,311 @@
-// Package awsdynamodb registers the [awsdynamodb] driver with the docstore package.
+// Package awsdynamodb registers the [awsdynamodb] driver with the docstore package,
+// the schema function used by the adapter's EnsureSchema, which enables TTL and creates
+// global secondary indexes, the read options function
+// that applies the adapter's ReadConsistency, and the plan function that reports table
+// scans for the adapter's QueryFallback. [Open] and [OpenCollection] open an adapter
+// over a table with typed [Options], e.g. to use DynamoDB Local or an injected AWS
+// configuration.
 package awsdynamodb
 
 import (
-	// Import the docstore package to register the awsdynamodb driver.
-	_ "gocloud.dev/docstore/awsdynamodb"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"gocloud.dev/docstore"
+
+	// The import registers the awsdynamodb driver with the docstore package.
+	"gocloud.dev/docstore/awsdynamodb"
 )
+
+func init() {
+	adapter.RegisterDriver("dynamodb", adapter.Driver{
+		Capabilities: adapter.Capabilities{TTL: true, StrongConsistency: true},
+		Schema:       ensureSchema,
+		ReadOptions:  readOptions,
+		Plan:         plan,
+		Strategy:     strategy,
+	})
+}
+
+// strategy sets the settings recommended for DynamoDB: action lists of at most 25 writes,
+// the BatchWriteItem limit, and strongly consistent reads, so an instance reads back its
+// own writes.
+func strategy(config *adapter.Config) {
+	config.BatchSize = 25
+	config.ReadConsistency = adapter.ConsistencyStrong
+}
+
+// plan reports queries that scan the table because no key or global secondary index
+// matches their filters.
+func plan(query *docstore.Query, filters []adapter.Filter) (string, error) {
+	p, err := query.Plan()
+	if err != nil {
+		return "", err
+	}
+	if p == "Scan" {
+		return "a table scan", nil
+	}
+	return "", nil
+}
+
+// readOptions sets ConsistentRead on queries, scans and batch gets. Queries served by a
+// global secondary index do not support strongly consistent reads.
+func readOptions(asFunc func(interface{}) bool, consistency adapter.Consistency) error {
+	consistent := aws.Bool(consistency == adapter.ConsistencyStrong)
+	var query *dynamodb.QueryInput
+	if asFunc(&query) {
+		query.ConsistentRead = consistent
+		return nil
+	}
+	var scan *dynamodb.ScanInput
+	if asFunc(&scan) {
+		scan.ConsistentRead = consistent
+		return nil
+	}
+	var get *dynamodb.BatchGetItemInput
+	if asFunc(&get) {
+		for _, keys := range get.RequestItems {
+			keys.ConsistentRead = consistent
+		}
+	}
+	return nil
+}
+
+// ensureSchema enables TTL on [adapter.TTLField] of the table when rule expiry is enabled,
+// and creates the global secondary indexes of the adapter's Indexes.
+func ensureSchema(ctx context.Context, coll *docstore.Collection, config *adapter.Config) error {
+	if config.RuleTTL <= 0 && len(config.Indexes) == 0 {
+		return nil
+	}
+	var db *dynamodb.DynamoDB
+	if !coll.As(&db) {
+		return errors.New("collection is not a DynamoDB table")
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return err
+	}
+	if config.RuleTTL > 0 {
+		if err := ensureTTL(ctx, db, u.Host, config); err != nil {
+			return err
+		}
+	}
+	return ensureIndexes(ctx, db, u.Host, config)
+}
+
+// ensureTTL enables TTL on [adapter.TTLField] of the table.
+func ensureTTL(ctx context.Context, db *dynamodb.DynamoDB, tableName string, config *adapter.Config) error {
+	table := aws.String(tableName)
+	field := config.RuleField(adapter.TTLField)
+
+	// Enabling TTL on a table where it is already enabled is an error.
+	out, err := db.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: table})
+	if err != nil {
+		return err
+	}
+	if desc := out.TimeToLiveDescription; desc != nil && aws.StringValue(desc.TimeToLiveStatus) != dynamodb.TimeToLiveStatusDisabled {
+		if name := aws.StringValue(desc.AttributeName); name != field {
+			return fmt.Errorf("table %s already has TTL on attribute %q", tableName, name)
+		}
+		return nil
+	}
+
+	_, err = db.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: table,
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(field),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	return err
+}
+
+// ensureIndexes creates the first index of the adapter's Indexes that the table lacks, as a
+// global secondary index projecting every attribute, so that the driver can serve queries
+// from it. DynamoDB creates one index at a time, so an index that is still being created
+// defers the next one to a later call.
+func ensureIndexes(ctx context.Context, db *dynamodb.DynamoDB, tableName string, config *adapter.Config) error {
+	if len(config.Indexes) == 0 {
+		return nil
+	}
+	out, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return err
+	}
+	desc := out.Table
+	busy := aws.StringValue(desc.TableStatus) != dynamodb.TableStatusActive
+	existing := make(map[string]bool, len(desc.GlobalSecondaryIndexes))
+	for _, gi := range desc.GlobalSecondaryIndexes {
+		existing[aws.StringValue(gi.IndexName)] = true
+		busy = busy || aws.StringValue(gi.IndexStatus) != dynamodb.IndexStatusActive
+	}
+
+	for _, index := range config.Indexes {
+		if existing[index.Name] {
+			continue
+		}
+		if len(index.Fields) == 0 || len(index.Fields) > 2 {
+			return fmt.Errorf("index %s: a global secondary index has a partition key and an optional sort key", index.Name)
+		}
+		if busy {
+			return nil
+		}
+		create := &dynamodb.CreateGlobalSecondaryIndexAction{
+			IndexName:  aws.String(index.Name),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		}
+		var attrs []*dynamodb.AttributeDefinition
+		for i, field := range index.Fields {
+			keyType := dynamodb.KeyTypeHash
+			if i > 0 {
+				keyType = dynamodb.KeyTypeRange
+			}
+			name := aws.String(config.RuleField(field))
+			create.KeySchema = append(create.KeySchema, &dynamodb.KeySchemaElement{AttributeName: name, KeyType: aws.String(keyType)})
+			attrs = append(attrs, &dynamodb.AttributeDefinition{AttributeName: name, AttributeType: aws.String(attributeType(field))})
+		}
+		if b := desc.BillingModeSummary; b == nil || aws.StringValue(b.BillingMode) == dynamodb.BillingModeProvisioned {
+			// Indexes of provisioned tables need their own throughput; use that of the table.
+			create.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+				ReadCapacityUnits:  desc.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: desc.ProvisionedThroughput.WriteCapacityUnits,
+			}
+		}
+		_, err := db.UpdateTableWithContext(ctx, &dynamodb.UpdateTableInput{
+			TableName:                   aws.String(tableName),
+			AttributeDefinitions:        attrs,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{Create: create}},
+		})
+		if err != nil {
+			return fmt.Errorf("could not create index %s: %w", index.Name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// attributeType returns the DynamoDB attribute type of the rule field: numbers for the
+// priority and expiry of rules, and strings otherwise.
+func attributeType(field string) string {
+	switch field {
+	case "priority", "expires_at":
+		return dynamodb.ScalarAttributeTypeN
+	default:
+		return dynamodb.ScalarAttributeTypeS
+	}
+}
+
+// Options configure the DynamoDB client and the tables opened with it.
+type Options struct {
+	Table          string      // the table of the rules, used by Open
+	PartitionKey   string      // the partition key of the table, used by Open
+	SortKey        string      // the sort key of the table, if it has one, used by Open
+	Endpoint       string      // the endpoint of the DynamoDB service, e.g. http://localhost:8000 for DynamoDB Local (defaults to the endpoint of the region)
+	Region         string      // the AWS region of the table (defaults to the region of the shared configuration)
+	Config         *aws.Config // the AWS configuration, e.g. with credentials, applied over the shared configuration and before Endpoint and Region
+	ConsistentRead bool        // whether reads are strongly consistent unless the adapter's ReadConsistency is set
+	AllowScans     bool        // whether queries that cannot use the keys of the table may scan it
+}
+
+// URL returns the url of the table with the given partition key and, if not empty, sort
+// key, with the query parameters escaped.
+func URL(table, partitionKey, sortKey string) string {
+	q := url.Values{"partition_key": {partitionKey}}
+	if sortKey != "" {
+		q.Set("sort_key", sortKey)
+	}
+	u := url.URL{Scheme: awsdynamodb.Scheme, Host: table, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// OpenCollection opens an adapter over the table with the given partition key and optional
+// sort key, with a client configured by the options. The adapter options are applied to its
+// configuration, whose URL is set to the url of the table.
+func OpenCollection(ctx context.Context, table, partitionKey, sortKey string, opts *Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	if table == "" || partitionKey == "" {
+		return nil, errors.New("the table and partition key are required")
+	}
+	return adapter.New(ctx, URL(table, partitionKey, sortKey), append([]adapter.Option{WithOptions(opts)}, adapterOpts...)...)
+}
+
+// Open opens an adapter over the table of the options, like [OpenCollection].
+func Open(ctx context.Context, opts Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	return OpenCollection(ctx, opts.Table, opts.PartitionKey, opts.SortKey, &opts, adapterOpts...)
+}
+
+// WithOptions returns the adapter option that opens "dynamodb" collection urls with a client
+// configured by the options, instead of the default session of the awsdynamodb driver. The
+// options take precedence over the query parameters of the urls.
+//
+// The session is created when the first collection is opened and is shared by the
+// collections of the adapter. For adapters created with NewWithOption, apply the returned
+// option to the configuration.
+func WithOptions(opts *Options) adapter.Option {
+	if opts == nil {
+		opts = &Options{}
+	}
+	opener := &urlOpener{opts: *opts}
+	return func(c *adapter.Config) {
+		if c.Openers == nil {
+			c.Openers = make(adapter.URLOpeners)
+		}
+		c.Openers[awsdynamodb.Scheme] = opener
+	}
+}
+
+// urlOpener opens collections with a session configured by the options.
+type urlOpener struct {
+	opts Options
+
+	mu     sync.Mutex
+	opener *awsdynamodb.URLOpener
+}
+
+// OpenCollectionURL implements [docstore.CollectionURLOpener].
+func (o *urlOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.opener == nil {
+		sess, err := o.session()
+		if err != nil {
+			return nil, fmt.Errorf("open collection %s: %v", u, err)
+		}
+		o.opener = &awsdynamodb.URLOpener{ConfigProvider: sess}
+	}
+
+	q := u.Query()
+	if o.opts.ConsistentRead {
+		q.Set("consistent_read", "true")
+	}
+	if o.opts.AllowScans {
+		q.Set("allow_scans", "true")
+	}
+	withOpts := *u
+	withOpts.RawQuery = q.Encode()
+	return o.opener.OpenCollectionURL(ctx, &withOpts)
+}
+
+// session creates a session from the shared configuration with the options applied.
+func (o *urlOpener) session() (*session.Session, error) {
+	cfg := aws.NewConfig()
+	if o.opts.Config != nil {
+		cfg.MergeIn(o.opts.Config)
+	}
+	if o.opts.Endpoint != "" {
+		cfg.WithEndpoint(o.opts.Endpoint)
+	}
+	if o.opts.Region != "" {
+		cfg.WithRegion(o.opts.Region)
+	}
+	return session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
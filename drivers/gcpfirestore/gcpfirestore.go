@@ -1,7 +1,251 @@
-// Package gcpfirestore registers the [gcpfirestore] driver with the docstore package.
+// Package gcpfirestore registers the [gcpfirestore] driver with the docstore package, the
+// read options function that applies the adapter's ReadConsistency, and the plan function
+// that reports filters evaluated client-side for the adapter's QueryFallback. Queries that
+// need a missing composite index fail with an [adapter.IndexError] holding its definition. [Open] and
+// [OpenCollection] open an adapter over a collection with typed [Options], e.g. with
+// injected credentials.
 package gcpfirestore
 
 import (
-	// Import the docstore package to register the gcpfirestore driver.
-	_ "gocloud.dev/docstore/gcpfirestore"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	vkit "cloud.google.com/go/firestore/apiv1"
+	"cloud.google.com/go/firestore/apiv1/admin/adminpb"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+	"gocloud.dev/gcp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	// The import registers the gcpfirestore driver with the docstore package.
+	"gocloud.dev/docstore/gcpfirestore"
 )
+
+// staleness is how far in the past eventually consistent reads are served. Firestore
+// recommends at least 15 seconds for stale reads to be served by the nearest replica.
+const staleness = 15 * time.Second
+
+func init() {
+	adapter.RegisterDriver("firestore", adapter.Driver{
+		Capabilities: adapter.Capabilities{TTL: true, StrongConsistency: true},
+		ReadOptions:  readOptions,
+		Plan:         plan,
+		IndexError:   indexError,
+		Strategy:     strategy,
+	})
+}
+
+// strategy sets the settings recommended for Firestore: action lists of at most 500 writes,
+// the limit of a commit, and strong reads rather than stale reads.
+func strategy(config *adapter.Config) {
+	config.BatchSize = 500
+	config.ReadConsistency = adapter.ConsistencyStrong
+}
+
+// createIndexURL matches the link to the console that Firestore includes in the errors of
+// queries that need a missing composite index.
+var createIndexURL = regexp.MustCompile(`https://console\.firebase\.google\.com/\S*create_composite=[A-Za-z0-9_%=+/-]+`)
+
+// indexError returns the definition of the composite index that a query needs, decoded from
+// the link in the FailedPrecondition error of the query.
+func indexError(err error) *adapter.IndexError {
+	if gcerrors.Code(err) != gcerrors.FailedPrecondition {
+		return nil
+	}
+	link := createIndexURL.FindString(err.Error())
+	if link == "" {
+		return nil
+	}
+	u, parseErr := url.Parse(link)
+	if parseErr != nil {
+		return nil
+	}
+	encoded := u.Query().Get("create_composite")
+	data, decodeErr := base64.StdEncoding.DecodeString(encoded)
+	if decodeErr != nil {
+		if data, decodeErr = base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "=")); decodeErr != nil {
+			return nil
+		}
+	}
+	var index adminpb.Index
+	if proto.Unmarshal(data, &index) != nil {
+		return nil
+	}
+
+	// The name is projects/{project}/databases/{database}/collectionGroups/{group}/indexes/{id}.
+	indexErr := &adapter.IndexError{CreateURL: link, Err: err}
+	parts := strings.Split(index.GetName(), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "collectionGroups" {
+			indexErr.Collection = parts[i+1]
+		}
+	}
+	for _, f := range index.GetFields() {
+		field := adapter.IndexField{Path: f.GetFieldPath()}
+		switch {
+		case f.GetOrder() == adminpb.Index_IndexField_ASCENDING:
+			field.Order = "asc"
+		case f.GetOrder() == adminpb.Index_IndexField_DESCENDING:
+			field.Order = "desc"
+		case f.GetArrayConfig() == adminpb.Index_IndexField_CONTAINS:
+			field.Order = "array-contains"
+		}
+		indexErr.Fields = append(indexErr.Fields, field)
+	}
+	return indexErr
+}
+
+// plan reports queries with inequality filters on more than one field. The driver sends
+// the inequality filters of the first field to Firestore, and evaluates the others
+// client-side on every document matched by the rest of the query (which requires the
+// AllowLocalFilters option of the collection).
+func plan(query *docstore.Query, filters []adapter.Filter) (string, error) {
+	var rangeField string
+	for _, f := range filters {
+		if f.Op == adapter.EqualOp {
+			continue
+		}
+		field := strings.Join(f.FieldPath, ".")
+		if rangeField == "" {
+			rangeField = field
+		} else if field != rangeField {
+			return fmt.Sprintf("client-side filtering of %s", field), nil
+		}
+	}
+	return "", nil
+}
+
+// readOptions serves eventually consistent queries and gets as stale reads. Firestore reads
+// are strongly consistent by default, so strong consistency needs no options.
+func readOptions(asFunc func(interface{}) bool, consistency adapter.Consistency) error {
+	if consistency != adapter.ConsistencyEventual {
+		return nil
+	}
+	readTime := timestamppb.New(time.Now().Add(-staleness).Truncate(time.Microsecond))
+	var query *pb.RunQueryRequest
+	if asFunc(&query) {
+		query.ConsistencySelector = &pb.RunQueryRequest_ReadTime{ReadTime: readTime}
+		return nil
+	}
+	var get *pb.BatchGetDocumentsRequest
+	if asFunc(&get) {
+		get.ConsistencySelector = &pb.BatchGetDocumentsRequest_ReadTime{ReadTime: readTime}
+	}
+	return nil
+}
+
+// Options configure the Firestore client and the collections opened with it.
+type Options struct {
+	Credentials    *google.Credentials // the credentials of the client (defaults to Application Default Credentials)
+	TokenSource    oauth2.TokenSource  // the token source of the client, used if Credentials is nil
+	Database       string              // the database of the collection (defaults to "(default)")
+	Project        string              // the project of the database, used by Open
+	CollectionPath string              // the path of the collection, e.g. "casbin_rule" or "tenants/acme/casbin_rule", used by Open
+	NameField      string              // the field naming the documents, used by Open (defaults to "id", the ID of the rules)
+}
+
+// URL returns the url of the collection at the path, e.g. "casbin_rule" or a nested
+// collection like "tenants/acme/casbin_rule", of the database of the project. Documents are
+// named by nameField.
+func URL(project, database, collectionPath, nameField string) string {
+	if database == "" {
+		database = "(default)"
+	}
+	id := gcpfirestore.CollectionResourceIDWithDatabase(project, database, collectionPath)
+	return gcpfirestore.Scheme + "://" + id + "?" + url.Values{"name_field": {nameField}}.Encode()
+}
+
+// OpenCollection opens an adapter over the collection at the path of the project, whose
+// documents are named by nameField, with a client configured by the options. The adapter
+// options are applied to its configuration, whose URL is set to the url of the collection.
+func OpenCollection(ctx context.Context, project, collectionPath, nameField string, opts *Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	if project == "" || collectionPath == "" || nameField == "" {
+		return nil, errors.New("the project, collection path and name field are required")
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	return adapter.New(ctx, URL(project, opts.Database, collectionPath, nameField), append([]adapter.Option{WithOptions(opts)}, adapterOpts...)...)
+}
+
+// Open opens an adapter over the collection of the options, like [OpenCollection].
+func Open(ctx context.Context, opts Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	if opts.NameField == "" {
+		opts.NameField = "id"
+	}
+	return OpenCollection(ctx, opts.Project, opts.CollectionPath, opts.NameField, &opts, adapterOpts...)
+}
+
+// WithOptions returns the adapter option that opens "firestore" collection urls with a client
+// authenticated by the credentials or token source of the options, instead of the
+// Application Default Credentials used by the gcpfirestore driver. Like the driver, the
+// client connects to the emulator at FIRESTORE_EMULATOR_HOST if it is set.
+//
+// The client is created when the first collection is opened and is shared by the
+// collections of the adapter. For adapters created with NewWithOption, apply the returned
+// option to the configuration.
+func WithOptions(opts *Options) adapter.Option {
+	if opts == nil {
+		opts = &Options{}
+	}
+	opener := &urlOpener{opts: *opts}
+	return func(c *adapter.Config) {
+		if c.Openers == nil {
+			c.Openers = make(adapter.URLOpeners)
+		}
+		c.Openers[gcpfirestore.Scheme] = opener
+	}
+}
+
+// urlOpener opens collections with a client authenticated as configured by the options.
+type urlOpener struct {
+	opts Options
+
+	mu     sync.Mutex
+	opener *gcpfirestore.URLOpener
+}
+
+// OpenCollectionURL implements [docstore.CollectionURLOpener].
+func (o *urlOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.opener == nil {
+		client, err := o.dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("open collection %s: %v", u, err)
+		}
+		o.opener = &gcpfirestore.URLOpener{Client: client}
+	}
+	return o.opener.OpenCollectionURL(ctx, u)
+}
+
+// dial creates a client with the token source of the options. The emulator needs no
+// credentials.
+func (o *urlOpener) dial(ctx context.Context) (*vkit.Client, error) {
+	ts := o.opts.TokenSource
+	if o.opts.Credentials != nil {
+		ts = o.opts.Credentials.TokenSource
+	}
+	if ts == nil && os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		creds, err := gcp.DefaultCredentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ts = creds.TokenSource
+	}
+	client, _, err := gcpfirestore.Dial(ctx, ts)
+	return client, err
+}
@@ -1,7 +1,173 @@
 // Package memdocstore registers the [memdocstore] driver with the docstore package.
+// [OpenFile] opens an adapter whose policies are persisted to a file, so that local
+// development retains them across restarts, and [Open] opens an adapter with typed [Options].
 package memdocstore
 
 import (
-	// Import the docstore package to register the memdocstore driver.
-	_ "gocloud.dev/docstore/memdocstore"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"gocloud.dev/docstore"
+
+	// The import registers the memdocstore driver with the docstore package.
+	"gocloud.dev/docstore/memdocstore"
+)
+
+func init() {
+	// Documents hold times, e.g. the expiry of rules, which gob encodes as interface values
+	// only once registered. This also lets memdocstore save them when a collection is closed.
+	gob.Register(time.Time{})
+
+	adapter.RegisterDriver("mem", adapter.Driver{Capabilities: adapter.Capabilities{StrongConsistency: true}})
+}
+
+// collectionName and keyField are the collection name and key field of the collections
+// opened by [OpenFile].
+const (
+	collectionName = "casbin_rule"
+	keyField       = "id"
 )
+
+// URL returns the url of the collection with the given name and key field, persisted to
+// the file at path if it is not empty.
+func URL(collection, keyField, path string) string {
+	u := url.URL{Scheme: memdocstore.Scheme, Host: collection, Path: "/" + keyField}
+	if path != "" {
+		u.RawQuery = url.Values{"filename": {path}}.Encode()
+	}
+	return u.String()
+}
+
+// Options configure the collection opened by Open.
+type Options struct {
+	Collection   string        // the name of the collection (defaults to "casbin_rule")
+	KeyField     string        // the key field of the collection (defaults to "id", the ID of the rules)
+	Path         string        // the file the collection is persisted to, as with OpenFile (not persisted if empty)
+	SyncInterval time.Duration // the interval at which the collection is saved to Path, required with Path
+}
+
+// Open opens an adapter over the in-memory collection of the options. The adapter options
+// are applied to its configuration, whose URL is set to the url of the collection.
+//
+// Collections with a Path are saved every SyncInterval until ctx is done, and once more
+// when it is; use OpenFile to also save them on demand.
+func Open(ctx context.Context, opts Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	if opts.Collection == "" {
+		opts.Collection = collectionName
+	}
+	if opts.KeyField == "" {
+		opts.KeyField = keyField
+	}
+	if opts.Path == "" {
+		return adapter.New(ctx, URL(opts.Collection, opts.KeyField, ""), adapterOpts...)
+	}
+	if opts.SyncInterval <= 0 {
+		return nil, errors.New("the sync interval is required with a path")
+	}
+	a, _, err := openFile(ctx, opts, adapterOpts...)
+	return a, err
+}
+
+// OpenFile opens an adapter over an in-memory collection that is loaded from the file at
+// path if it exists. The adapter options are applied to its configuration, whose URL is set
+// to the url of the collection.
+//
+// The collection is saved to the file every syncInterval until ctx is done, and once more
+// when it is. memdocstore itself only saves the collection when it is closed, which the
+// adapter does not do before the process exits. Call the returned sync function to save the
+// collection immediately, e.g. on shutdown, or to save it when syncInterval is 0.
+//
+// The file is written by a single process; it is not meant to be shared.
+func OpenFile(ctx context.Context, path string, syncInterval time.Duration, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, func(ctx context.Context) error, error) {
+	if path == "" {
+		return nil, nil, errors.New("the path of the file is required")
+	}
+	return openFile(ctx, Options{Collection: collectionName, KeyField: keyField, Path: path, SyncInterval: syncInterval}, adapterOpts...)
+}
+
+// openFile opens an adapter over the in-memory collection of the options, persisted to the
+// file at the path of the options, as described by OpenFile.
+func openFile(ctx context.Context, opts Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, func(ctx context.Context) error, error) {
+	path, syncInterval := opts.Path, opts.SyncInterval
+	a, err := adapter.New(ctx, URL(opts.Collection, opts.KeyField, path), adapterOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	coll := a.Collection()
+	sync := func(ctx context.Context) error {
+		return save(ctx, coll, opts.KeyField, path)
+	}
+
+	if syncInterval > 0 {
+		// Save errors are logged with the Config.Logger of the adapter options.
+		var config adapter.Config
+		for _, opt := range adapterOpts {
+			opt(&config)
+		}
+		logger := config.Logger
+		if logger == nil {
+			logger = log.Default()
+		}
+		go func() {
+			ticker := time.NewTicker(syncInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					if err := sync(context.WithoutCancel(ctx)); err != nil {
+						logger.Printf("memdocstore: could not save %s: %v", path, err)
+					}
+					return
+				case <-ticker.C:
+					if err := sync(ctx); err != nil && ctx.Err() == nil {
+						logger.Printf("memdocstore: could not save %s: %v", path, err)
+					}
+				}
+			}
+		}()
+	}
+
+	return a, sync, nil
+}
+
+// save writes the documents of the collection to the file at path in the format memdocstore
+// loads collections from: a gob encoded map of the documents by the value of their key field.
+// The file is replaced atomically, so a crash while saving leaves the previous contents.
+func save(ctx context.Context, coll *docstore.Collection, keyField, path string) error {
+	docs := make(map[interface{}]map[string]interface{})
+	iter := coll.Query().Get(ctx)
+	defer iter.Stop()
+	for {
+		doc := make(map[string]interface{})
+		err := iter.Next(ctx, doc)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		docs[doc[keyField]] = doc
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(docs); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("could not encode documents: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,43 @@
+package memdocstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gocloud.dev/docstore/memdocstore"
+)
+
+func TestSaveKeyField(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "policy.db")
+
+	coll, err := memdocstore.OpenCollection("name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Close()
+	for _, name := range []string{"alice", "bob"} {
+		if err := coll.Put(ctx, map[string]interface{}{"name": name, "v0": "data1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := save(ctx, coll, "name", path); err != nil {
+		t.Fatalf("Expected save() to be successful; got %v", err)
+	}
+
+	loaded, err := memdocstore.OpenCollection("name", &memdocstore.Options{Filename: path})
+	if err != nil {
+		t.Fatalf("Expected the saved collection to be loaded; got %v", err)
+	}
+	defer loaded.Close()
+	for _, name := range []string{"alice", "bob"} {
+		doc := map[string]interface{}{"name": name}
+		if err := loaded.Get(ctx, doc); err != nil {
+			t.Errorf("Expected %s to be saved by its key field; got %v", name, err)
+		} else if doc["v0"] != "data1" {
+			t.Errorf("Expected the saved document of %s; got %v", name, doc)
+		}
+	}
+}
@@ -1,7 +1,348 @@
-// Package mongodocstore registers the [mongodocstore] driver with the docstore package.
+// Package mongodocstore registers the [mongodocstore] driver with the docstore package,
+// the schema function used by the adapter's EnsureSchema, the transaction function used
+// with the adapter's Transactions option, and the throttle function that retries requests
+// throttled by Azure Cosmos DB after its Retry-After hint. [WithIndexes] creates the indexes
+// of the adapter's queries when it is opened. [WithOptions] configures the MongoDB client,
+// e.g. its read preference and write concern, and [Open] opens an adapter over a collection
+// with typed [Options].
 package mongodocstore
 
 import (
-	// Import the docstore package to register the mongodocstore driver.
-	_ "gocloud.dev/docstore/mongodocstore"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"gocloud.dev/docstore"
+
+	// The import registers the mongodocstore driver with the docstore package.
+	"gocloud.dev/docstore/mongodocstore"
 )
+
+func init() {
+	adapter.RegisterDriver("mongo", adapter.Driver{
+		Capabilities: adapter.Capabilities{Transactions: true, TTL: true, StrongConsistency: true},
+		Schema:       ensureSchema,
+		Txn:          runTxn,
+		Throttle:     cosmosThrottle,
+		Strategy:     strategy,
+	})
+}
+
+// strategy sets the settings recommended for MongoDB: SavePolicy and UpdateFilteredPolicies
+// write in transactions, so they apply all or nothing.
+func strategy(config *adapter.Config) {
+	config.Transactions = true
+}
+
+// runTxn runs fn in a transaction of a new session, which requires a replica set or a
+// sharded cluster. The transaction is retried on transient errors.
+func runTxn(ctx context.Context, coll *docstore.Collection, fn func(ctx context.Context) error) error {
+	var mc *mongo.Collection
+	if !coll.As(&mc) {
+		return errors.New("collection is not a MongoDB collection")
+	}
+	session, err := mc.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// The session context binds the operations of fn to the transaction.
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// ensureSchema creates the TTL index on [adapter.TTLField] when rule expiry is enabled, the
+// indexes of the adapter's Indexes, and the recommended indexes if enabled.
+func ensureSchema(ctx context.Context, coll *docstore.Collection, config *adapter.Config) error {
+	var models []mongo.IndexModel
+	if config.RuleTTL > 0 {
+		// Documents expire at the time stored in the field, hence an expiry of 0 seconds.
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.D{{Key: config.RuleField(adapter.TTLField), Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+	}
+	for _, index := range config.Indexes {
+		models = append(models, indexModel(config, index.Name, index.Fields, false))
+	}
+	if config.RecommendedIndexes {
+		recommended, err := recommendedIndexes(config)
+		if err != nil {
+			return err
+		}
+		models = append(models, recommended...)
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	var mc *mongo.Collection
+	if !coll.As(&mc) {
+		return errors.New("collection is not a MongoDB collection")
+	}
+
+	// Creating an index that exists with the same keys and options is a no-op.
+	_, err := mc.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// recommendedIndexes returns the indexes of the adapter's RecommendedIndexes: the compound
+// index on the policy type and values (led by the namespace if the adapter has one), which
+// serves filtered loads, removals and updates, and the unique index on the rule ID, unless
+// the ID is the _id of the documents.
+func recommendedIndexes(config *adapter.Config) ([]mongo.IndexModel, error) {
+	fields := []string{"ptype"}
+	if config.Schema == adapter.SchemaArray {
+		fields = append(fields, "values")
+	} else {
+		fields = append(fields, "v0", "v1", "v2", "v3", "v4", "v5")
+	}
+	if config.Namespace != "" {
+		fields = append([]string{"ns"}, fields...)
+	}
+	models := []mongo.IndexModel{indexModel(config, "casbin_rule_values", fields, false)}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if id := config.RuleField("id"); id != "_id" && id != u.Query().Get("id_field") {
+		models = append(models, indexModel(config, "casbin_rule_id", []string{"id"}, true))
+	}
+	return models, nil
+}
+
+// indexModel returns the ascending compound index on the rule fields, as mapped by the
+// adapter's Codec.
+func indexModel(config *adapter.Config, name string, fields []string, unique bool) mongo.IndexModel {
+	keys := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: config.RuleField(field), Value: 1})
+	}
+	opts := options.Index().SetName(name)
+	if unique {
+		opts.SetUnique(true)
+	}
+	return mongo.IndexModel{Keys: keys, Options: opts}
+}
+
+// Option configures the MongoDB client used to open collections.
+type Option func(*options.ClientOptions)
+
+// WithReadPreference sets the read preference, e.g. readpref.SecondaryPreferred() to serve
+// reads from secondaries.
+func WithReadPreference(rp *readpref.ReadPref) Option {
+	return func(o *options.ClientOptions) {
+		o.SetReadPreference(rp)
+	}
+}
+
+// WithReadConcern sets the read concern, e.g. readconcern.Majority().
+func WithReadConcern(rc *readconcern.ReadConcern) Option {
+	return func(o *options.ClientOptions) {
+		o.SetReadConcern(rc)
+	}
+}
+
+// WithWriteConcern sets the write concern, e.g. writeconcern.Majority().
+func WithWriteConcern(wc *writeconcern.WriteConcern) Option {
+	return func(o *options.ClientOptions) {
+		o.SetWriteConcern(wc)
+	}
+}
+
+// WithServerSelectionTimeout sets how long to wait for a suitable server to be available
+// before an operation fails.
+func WithServerSelectionTimeout(d time.Duration) Option {
+	return func(o *options.ClientOptions) {
+		o.SetServerSelectionTimeout(d)
+	}
+}
+
+// Options configure the MongoDB client and the collection opened by Open.
+type Options struct {
+	ServerURL  string   // the connection string of the server (defaults to the MONGO_SERVER_URL environment variable)
+	Database   string   // the database of the collection
+	Collection string   // the collection of the rules
+	IDField    string   // the field holding the document IDs (defaults to "id", the ID of the rules, rather than the _id of the driver)
+	Client     []Option // the options of the client, e.g. WithReadPreference
+	Indexes    bool     // whether the recommended indexes are created when the adapter is opened (see WithIndexes)
+}
+
+// URL returns the url of the collection of the database, whose documents are identified by
+// idField (_id if empty).
+func URL(database, collection, idField string) string {
+	u := url.URL{Scheme: mongodocstore.Scheme, Host: database, Path: "/" + collection}
+	if idField != "" {
+		u.RawQuery = url.Values{"id_field": {idField}}.Encode()
+	}
+	return u.String()
+}
+
+// Open opens an adapter over the collection of the options, with a client configured by
+// them. The adapter options are applied to its configuration, whose URL is set to the url
+// of the collection.
+func Open(ctx context.Context, opts Options, adapterOpts ...adapter.Option) (*adapter.CloudAdapter, error) {
+	if opts.Database == "" || opts.Collection == "" {
+		return nil, errors.New("the database and collection are required")
+	}
+	if opts.IDField == "" {
+		opts.IDField = "id"
+	}
+	opener := &urlOpener{serverURL: opts.ServerURL, opts: opts.Client}
+	defaults := []adapter.Option{withOpener(opener)}
+	if opts.Indexes {
+		defaults = append(defaults, WithIndexes())
+	}
+	return adapter.New(ctx, URL(opts.Database, opts.Collection, opts.IDField), append(defaults, adapterOpts...)...)
+}
+
+// WithIndexes returns the adapter option that creates the indexes recommended for the
+// queries of the adapter when it is opened, so that RemoveFilteredPolicy and filtered loads
+// do not scan the collection: the compound index on ptype and v0 to v5 (led by ns for
+// namespaced adapters), and the unique index on the rule ID if it is not the _id of the
+// documents. It sets the adapter's RecommendedIndexes and AutoSchema.
+func WithIndexes() adapter.Option {
+	return func(c *adapter.Config) {
+		c.RecommendedIndexes = true
+		c.AutoSchema = true
+	}
+}
+
+// WithOptions returns the adapter option that opens "mongo" collection urls with a client
+// configured by the options, instead of the default client of the mongodocstore driver. Like
+// the default client, it connects to the server at the MONGO_SERVER_URL environment variable;
+// the options take precedence over those of the connection string.
+//
+// The client is created when the first collection is opened and is shared by the
+// collections of the adapter. For adapters created with NewWithOption, apply the returned
+// option to the configuration.
+func WithOptions(opts ...Option) adapter.Option {
+	return withOpener(&urlOpener{opts: opts})
+}
+
+// withOpener returns the adapter option that opens "mongo" collection urls with the opener.
+func withOpener(opener *urlOpener) adapter.Option {
+	return func(c *adapter.Config) {
+		if c.Openers == nil {
+			c.Openers = make(adapter.URLOpeners)
+		}
+		c.Openers[mongodocstore.Scheme] = opener
+	}
+}
+
+// urlOpener opens collections with a client configured by the options.
+type urlOpener struct {
+	serverURL string // the connection string of the server, if not MONGO_SERVER_URL
+	opts      []Option
+
+	mu     sync.Mutex
+	opener *mongodocstore.URLOpener
+}
+
+// OpenCollectionURL implements [docstore.CollectionURLOpener].
+func (o *urlOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.opener == nil {
+		client, err := o.connect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("open collection %s: %v", u, err)
+		}
+		o.opener = &mongodocstore.URLOpener{Client: client}
+	}
+	return o.opener.OpenCollectionURL(ctx, u)
+}
+
+// connect connects to the server, at MONGO_SERVER_URL by default, with the options applied.
+func (o *urlOpener) connect(ctx context.Context) (*mongo.Client, error) {
+	serverURL := o.serverURL
+	if serverURL == "" {
+		serverURL = os.Getenv("MONGO_SERVER_URL")
+	}
+	if serverURL == "" {
+		return nil, errors.New("MONGO_SERVER_URL environment variable is not set")
+	}
+	clientOpts := options.Client().ApplyURI(serverURL)
+	for _, opt := range o.opts {
+		opt(clientOpts)
+	}
+	if err := clientOpts.Validate(); err != nil {
+		return nil, err
+	}
+	return mongo.Connect(ctx, clientOpts)
+}
+
+// cosmosTooManyRequests is the error code of requests throttled by Azure Cosmos DB for
+// MongoDB because they exceed the provisioned request units (RUs).
+const cosmosTooManyRequests = 16500
+
+// cosmosRetryAfter matches the Retry-After hint in the messages of throttling errors.
+var cosmosRetryAfter = regexp.MustCompile(`RetryAfterMs=(\d+)`)
+
+// cosmosThrottle recognizes the throttling errors of Azure Cosmos DB, which report the time
+// after which the request may be retried in their message.
+func cosmosThrottle(err error) (bool, time.Duration) {
+	var se mongo.ServerError
+	if !errors.As(err, &se) || !se.HasErrorCode(cosmosTooManyRequests) {
+		return false, 0
+	}
+	m := cosmosRetryAfter.FindStringSubmatch(se.Error())
+	if m == nil {
+		return true, 0
+	}
+	ms, err := strconv.Atoi(m[1])
+	if err != nil {
+		return true, 0
+	}
+	return true, time.Duration(ms) * time.Millisecond
+}
+
+// RequestStatistics are the statistics of a request served by Azure Cosmos DB for MongoDB.
+type RequestStatistics struct {
+	CommandName   string        // the name of the command, as reported by the server
+	RequestCharge float64       // the request units (RUs) consumed by the request
+	Duration      time.Duration // the time the server took to serve the request
+}
+
+// LastRequestStatistics returns the statistics of the last request served on the
+// connection, from the getLastRequestStatistics command of Azure Cosmos DB for MongoDB. It
+// fails with other MongoDB servers.
+//
+// The statistics are kept per connection, so they describe a request of the adapter only if
+// the client uses a single connection, e.g. with maxPoolSize=1 in MONGO_SERVER_URL.
+func LastRequestStatistics(ctx context.Context, coll *docstore.Collection) (*RequestStatistics, error) {
+	var mc *mongo.Collection
+	if !coll.As(&mc) {
+		return nil, errors.New("collection is not a MongoDB collection")
+	}
+	var result struct {
+		CommandName                   string  `bson:"CommandName"`
+		RequestCharge                 float64 `bson:"RequestCharge"`
+		RequestDurationInMilliSeconds float64 `bson:"RequestDurationInMilliSeconds"`
+	}
+	cmd := bson.D{{Key: "getLastRequestStatistics", Value: 1}}
+	if err := mc.Database().RunCommand(ctx, cmd).Decode(&result); err != nil {
+		return nil, fmt.Errorf("could not get request statistics: %w", err)
+	}
+	return &RequestStatistics{
+		CommandName:   result.CommandName,
+		RequestCharge: result.RequestCharge,
+		Duration:      time.Duration(result.RequestDurationInMilliSeconds * float64(time.Millisecond)),
+	}, nil
+}
@@ -0,0 +1,213 @@
+// Package sqlitedocstore registers a docstore driver that stores documents as JSON in a
+// table of an embedded SQLite database file, for edge and CLI deployments without an
+// external database. It uses the pure Go [sqlite] driver, so it needs no cgo. Collections
+// are opened from URLs with the "sqlitedoc" scheme, like
+//
+//	sqlitedoc:///var/lib/casbin/policy.db?table=casbin_rule&key_field=id
+//
+// The URL host and path are the path of the database file, which is created if it does not
+// exist: "sqlitedoc://policy.db" is relative to the working directory. The table query
+// parameter is the table of the collection (defaults to "casbin_rule"), which is created if
+// it does not exist, and key_field is the document field holding the primary key (defaults
+// to "id"). Other query parameters are passed to the [sqlite] driver, e.g. _pragma.
+//
+// Write transactions take the database lock when they begin and wait up to five seconds for
+// it by default, so that processes sharing the file serialize their writes.
+package sqlitedocstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bartventer/casbin-go-cloud-adapter/internal/sqldocstore"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Scheme is the URL scheme the driver is registered under with docstore.DefaultURLMux.
+const Scheme = "sqlitedoc"
+
+func init() {
+	docstore.DefaultURLMux().RegisterCollection(Scheme, &URLOpener{})
+}
+
+// OpenCollection opens the collection stored in the table of the database, creating the
+// table if it does not exist. Documents are keyed by the string field keyField. Closing the
+// collection does not close the database.
+func OpenCollection(ctx context.Context, db *sql.DB, table, keyField string) (*docstore.Collection, error) {
+	return sqldocstore.OpenCollection(ctx, db, dialect{}, table, keyField, nil)
+}
+
+// URLOpener opens "sqlitedoc" collection urls. Collections of the same database file share
+// a connection pool, which is closed with the last of them.
+type URLOpener struct {
+	mu  sync.Mutex
+	dbs map[string]*sharedDB // by data source name
+}
+
+type sharedDB struct {
+	db   *sql.DB
+	refs int
+}
+
+// OpenCollectionURL implements [docstore.CollectionURLOpener].
+func (o *URLOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	path := u.Host + u.Path
+	if path == "" {
+		return nil, fmt.Errorf("open collection %s: the database file path is required", u)
+	}
+	q := u.Query()
+	table := q.Get("table")
+	if table == "" {
+		table = "casbin_rule"
+	}
+	keyField := q.Get("key_field")
+	if keyField == "" {
+		keyField = "id"
+	}
+	q.Del("table")
+	q.Del("key_field")
+	if !q.Has("_txlock") {
+		q.Set("_txlock", "immediate")
+	}
+	if !hasPragma(q, "busy_timeout") {
+		q.Add("_pragma", "busy_timeout(5000)")
+	}
+	dsn := "file:" + path + "?" + q.Encode()
+
+	db, release, err := o.open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open collection %s: %v", u, err)
+	}
+	coll, err := sqldocstore.OpenCollection(ctx, db, dialect{}, table, keyField, &sqldocstore.Options{OnClose: release})
+	if err != nil {
+		_ = release()
+		return nil, fmt.Errorf("open collection %s: %v", u, err)
+	}
+	return coll, nil
+}
+
+// hasPragma reports whether the query sets the pragma.
+func hasPragma(q url.Values, name string) bool {
+	for _, v := range q["_pragma"] {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(v)), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// open returns the database of the data source name, and the function that releases it.
+func (o *URLOpener) open(dsn string) (*sql.DB, func() error, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	shared, ok := o.dbs[dsn]
+	if !ok {
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		if o.dbs == nil {
+			o.dbs = make(map[string]*sharedDB)
+		}
+		shared = &sharedDB{db: db}
+		o.dbs[dsn] = shared
+	}
+	shared.refs++
+
+	var once sync.Once
+	release := func() error {
+		var err error
+		once.Do(func() {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if shared.refs--; shared.refs == 0 {
+				delete(o.dbs, dsn)
+				err = shared.db.Close()
+			}
+		})
+		return err
+	}
+	return shared.db, release, nil
+}
+
+// dialect is the SQL of SQLite.
+type dialect struct{}
+
+func (dialect) CreateTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key TEXT PRIMARY KEY, doc TEXT NOT NULL)", table)
+}
+
+func (dialect) Placeholder(n int) string {
+	return fmt.Sprintf("?%d", n)
+}
+
+// Value returns the value at the field path if it has the JSON type of kind. Booleans are
+// the integers 1 and 0, as are boolean statement parameters, and strings compare in byte
+// order with the default BINARY collation.
+func (dialect) Value(fp []string, kind sqldocstore.Kind) string {
+	path := pathLiteral(fp)
+	var types string
+	switch kind {
+	case sqldocstore.KindNumber:
+		types = "'integer', 'real'"
+	case sqldocstore.KindBool:
+		types = "'true', 'false'"
+	default:
+		types = "'text'"
+	}
+	return fmt.Sprintf("(CASE WHEN json_type(doc, %[1]s) IN (%[2]s) THEN json_extract(doc, %[1]s) END)", path, types)
+}
+
+// pathLiteral returns the string literal of the JSON path of the field path.
+func pathLiteral(fp []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, name := range fp {
+		b.WriteString(`."` + name + `"`)
+	}
+	return "'" + strings.ReplaceAll(b.String(), "'", "''") + "'"
+}
+
+// ForUpdate returns "", as write transactions hold the database lock.
+func (dialect) ForUpdate() string {
+	return ""
+}
+
+// ErrorCode maps the primary result codes of SQLite errors to error codes. A database locked
+// by other writers for longer than the busy timeout is a ResourceExhausted error, so that
+// the adapter retries the request.
+func (dialect) ErrorCode(err error) gcerrors.ErrorCode {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		if errors.Is(err, sql.ErrConnDone) {
+			return gcerrors.Internal
+		}
+		return gcerrors.Unknown
+	}
+	code := sqliteErr.Code()
+	switch {
+	case code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return gcerrors.AlreadyExists
+	}
+	switch code & 0xff {
+	case sqlite3.SQLITE_PERM, sqlite3.SQLITE_AUTH, sqlite3.SQLITE_READONLY:
+		return gcerrors.PermissionDenied
+	case sqlite3.SQLITE_INTERRUPT:
+		return gcerrors.Canceled
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_FULL, sqlite3.SQLITE_NOMEM:
+		return gcerrors.ResourceExhausted
+	case sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_NOTADB:
+		return gcerrors.Internal
+	case sqlite3.SQLITE_ERROR, sqlite3.SQLITE_MISMATCH, sqlite3.SQLITE_RANGE, sqlite3.SQLITE_TOOBIG:
+		return gcerrors.InvalidArgument
+	}
+	return gcerrors.Unknown
+}
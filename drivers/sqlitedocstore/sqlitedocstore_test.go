@@ -0,0 +1,230 @@
+package sqlitedocstore_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	_ "github.com/bartventer/casbin-go-cloud-adapter/drivers/sqlitedocstore"
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+)
+
+const modelPath = "../../testdata/rbac_model.conf"
+
+var initialPolicy = [][]string{
+	{"alice", "data1", "read"},
+	{"bob", "data2", "write"},
+	{"data2_admin", "data2", "read"},
+	{"data2_admin", "data2", "write"},
+}
+
+// dbURL returns the url of the rule table of a new SQLite database.
+func dbURL(t *testing.T) string {
+	return "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db") + "?table=casbin_rule&key_field=id"
+}
+
+// newEnforcer returns an enforcer over a new adapter with the configuration, whose database
+// holds the policy of testdata/rbac_policy.csv.
+func newEnforcer(t *testing.T, config *adapter.Config) *casbin.Enforcer {
+	t.Helper()
+	ctx := context.Background()
+	if config.URL == "" {
+		config.URL = dbURL(t)
+	}
+	a, err := adapter.NewWithOption(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := a.Shutdown(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	e, err := casbin.NewEnforcer(modelPath, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range initialPolicy {
+		if _, err := e.AddPolicy(rule); err != nil {
+			t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+		}
+	}
+	return e
+}
+
+func testGetPolicy(t *testing.T, e *casbin.Enforcer, want [][]string) {
+	t.Helper()
+	got, err := e.GetPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !util.SortedArray2DEquals(want, got) {
+		t.Errorf("Policy: %v, supposed to be %v", got, want)
+	}
+}
+
+// TestAdapter runs the scenarios of the adapter tests against a SQLite database.
+func TestAdapter(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		test func(t *testing.T)
+	}{
+		{"LoadPolicy", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{})
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, initialPolicy)
+
+			// Without AutoSave, changes are only stored by SavePolicy.
+			e.EnableAutoSave(false)
+			if _, err := e.AddPolicy("alice", "data1", "write"); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, initialPolicy)
+			if _, err := e.AddPolicy("alice", "data1", "write"); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.SavePolicy(); err != nil {
+				t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, append(slices.Clone(initialPolicy), []string{"alice", "data1", "write"}))
+		}},
+		{"AddRemovePolicies", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{})
+			if _, err := e.AddPolicies([][]string{{"jack", "data4", "read"}, {"jill", "data4", "write"}}); err != nil {
+				t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+			}
+			if _, err := e.RemovePolicies([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+				t.Fatalf("Expected RemovePolicies() to be successful; got %v", err)
+			}
+			if _, err := e.RemoveFilteredPolicy(0, "data2_admin"); err != nil {
+				t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, [][]string{{"jack", "data4", "read"}, {"jill", "data4", "write"}})
+		}},
+		{"LoadFilteredPolicy", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{})
+			if err := e.LoadFilteredPolicy(adapter.Filter{FieldPath: []string{"v0"}, Value: "data2_admin"}); err != nil {
+				t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+			if !e.IsFiltered() {
+				t.Error("Expected the adapter to be filtered")
+			}
+		}},
+		{"UpdatePolicy", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{})
+			if _, err := e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+				t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+			}
+			if _, err := e.UpdatePolicies([][]string{{"alice", "data1", "read"}}, [][]string{{"alice", "data1", "write"}}); err != nil {
+				t.Fatalf("Expected UpdatePolicies() to be successful; got %v", err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, [][]string{
+				{"alice", "data1", "write"},
+				{"bob", "data2", "read"},
+				{"data2_admin", "data2", "read"},
+				{"data2_admin", "data2", "write"},
+			})
+		}},
+		{"UpdateFilteredPolicies", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{})
+			if _, err := e.UpdateFilteredPolicies([][]string{{"data2_admin", "data3", "read"}}, 0, "data2_admin"); err != nil {
+				t.Fatalf("Expected UpdateFilteredPolicies() to be successful; got %v", err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, [][]string{
+				{"alice", "data1", "read"},
+				{"bob", "data2", "write"},
+				{"data2_admin", "data3", "read"},
+			})
+		}},
+		{"PreserveEmptyValues", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{PreserveEmptyValues: true})
+			rules := [][]string{{"carol", "", "read"}, {"dave", "data2", ""}}
+			if _, err := e.AddPolicies(rules); err != nil {
+				t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+			}
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+			}
+			testGetPolicy(t, e, append(slices.Clone(initialPolicy), rules...))
+		}},
+		{"LoadPolicySection", func(t *testing.T) {
+			ctx := context.Background()
+			url := dbURL(t)
+			e := newEnforcer(t, &adapter.Config{URL: url})
+			if _, err := e.AddGroupingPolicy("alice", "data2_admin"); err != nil {
+				t.Fatal(err)
+			}
+			a, err := adapter.New(ctx, url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Shutdown(ctx)
+			m, err := model.NewModelFromFile(modelPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.LoadPolicySection(ctx, m, "g"); err != nil {
+				t.Fatalf("Expected LoadPolicySection() to be successful; got %v", err)
+			}
+			if policy, _ := m.GetPolicy("p", "p"); len(policy) != 0 {
+				t.Errorf("Expected only the grouping policy to be loaded; got %v", policy)
+			}
+			if grouping, _ := m.GetPolicy("g", "g"); !util.Array2DEquals([][]string{{"alice", "data2_admin"}}, grouping) {
+				t.Errorf("Expected the grouping policy to be loaded; got %v", grouping)
+			}
+		}},
+		{"MaxLoadRules", func(t *testing.T) {
+			e := newEnforcer(t, &adapter.Config{MaxLoadRules: 4})
+			if err := e.LoadPolicy(); err != nil {
+				t.Fatalf("Expected LoadPolicy() to be successful within the limit; got %v", err)
+			}
+			if _, err := e.AddPolicy("carol", "data3", "read"); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.LoadPolicy(); !errors.Is(err, adapter.ErrTooManyRules) {
+				t.Errorf("Expected LoadPolicy() to fail with ErrTooManyRules; got %v", err)
+			}
+		}},
+		{"HealthCheck", func(t *testing.T) {
+			ctx := context.Background()
+			a, err := adapter.New(ctx, dbURL(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.HealthCheck(ctx); err != nil {
+				t.Errorf("Expected HealthCheck() to be successful; got %v", err)
+			}
+			if err := a.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.HealthCheck(ctx); err == nil {
+				t.Error("Expected HealthCheck() to fail on a shut down adapter")
+			}
+		}},
+	} {
+		t.Run(tt.name, tt.test)
+	}
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlParamPrefix is the prefix of the URL query parameters configuring the adapter rather
+// than the provider, e.g. mongodb://host/db/coll?id_field=_id&adapter_timeout=10s.
+const urlParamPrefix = "adapter_"
+
+// urlParams are the setters of the Config fields that can be configured in the URL, by
+// query parameter. They leave fields set explicitly unchanged.
+var urlParams = map[string]func(c *Config, value string) error{
+	"adapter_timeout": func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if c.Timeout == 0 {
+			c.Timeout = d
+		}
+		return nil
+	},
+	"adapter_filtered": func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		c.IsFiltered = c.IsFiltered || b
+		return nil
+	},
+	"adapter_namespace": func(c *Config, value string) error {
+		if c.Namespace == "" {
+			c.Namespace = value
+		}
+		return nil
+	},
+}
+
+// applyURLParams sets the configuration from the adapter_ query parameters of Config.URL,
+// and removes them from the URL before it is passed to the provider, so that the whole
+// configuration can live in a single connection string.
+func applyURLParams(config *Config) error {
+	if config.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return err
+	}
+	// Parameters are removed from the raw query, and the rest of the URL is kept as written,
+	// so the provider parameters are passed on exactly as written.
+	var kept []string
+	removed := false
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		rawName, rawValue, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil || !strings.HasPrefix(name, urlParamPrefix) {
+			kept = append(kept, param)
+			continue
+		}
+		removed = true
+		set, ok := urlParams[name]
+		if !ok {
+			return fmt.Errorf("unknown url parameter %q", name)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return fmt.Errorf("invalid url parameter %s: %w", name, err)
+		}
+		if err := set(config, value); err != nil {
+			return fmt.Errorf("invalid url parameter %s: %w", name, err)
+		}
+	}
+	if !removed {
+		return nil
+	}
+	rest, fragment, hasFragment := strings.Cut(config.URL, "#")
+	base, _, _ := strings.Cut(rest, "?")
+	if query := strings.Join(kept, "&"); query != "" {
+		base += "?" + query
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	config.URL = base
+	return nil
+}
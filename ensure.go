@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"gocloud.dev/docstore"
+)
+
+// Index is a secondary index of the rule collections over rule fields, named by their
+// docstore names (e.g. "ptype" or "v0"), for Config.Indexes. On DynamoDB, the first field is
+// the partition key of a global secondary index, and the second, if any, its sort key: the
+// index {Name: "ptype-v0", Fields: []string{"ptype", "v0"}} serves RemoveFilteredPolicy on
+// v0, and {Name: "v0", Fields: []string{"v0"}} serves LoadFilteredPolicy with a v0 filter.
+// On MongoDB, the fields form an ascending compound index.
+type Index struct {
+	Name   string   // the name of the index
+	Fields []string // the indexed rule fields, in key order
+}
+
+// SchemaFunc configures the provider-side schema of a rules collection, such as indexes
+// or time-to-live settings, according to the adapter configuration.
+type SchemaFunc func(ctx context.Context, coll *docstore.Collection, config *Config) error
+
+// EnsureSchema configures the provider-side schema of the rules collection, e.g. the TTL
+// index used by Config.RuleTTL, the indexes of Config.Indexes or those enabled by
+// Config.RecommendedIndexes. It is a no-op for providers whose [Driver] has no [SchemaFunc],
+// and is safe to call on every start. With Config.AutoSchema it is called when the adapter
+// is opened.
+//
+// The schemas of the shard and grouping collections are configured too, each with a copy
+// of the configuration whose URL is that of the collection.
+func (a *adapter) EnsureSchema(ctx context.Context) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout())
+	defer cancel()
+	if err := ensureSchema(ctx, a.collection, a.config); err != nil {
+		return err
+	}
+	for i, shard := range a.shards[min(1, len(a.shards)):] { // the first shard is the primary collection
+		config := *a.config
+		config.URL = a.config.ShardURLs[i]
+		if err := ensureSchema(ctx, shard, &config); err != nil {
+			return err
+		}
+	}
+	if a.grouping != nil {
+		config := *a.config
+		config.URL = a.config.GroupingURL
+		return ensureSchema(ctx, a.grouping, &config)
+	}
+	return nil
+}
+
+// ensureSchema calls the [SchemaFunc] of the driver of the configured URL, if any.
+func ensureSchema(ctx context.Context, coll *docstore.Collection, config *Config) error {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return fmt.Errorf("could not parse url: %w", err)
+	}
+	fn := driverFor(u.Scheme).Schema
+	if fn == nil {
+		return nil
+	}
+
+	if err := fn(ctx, coll, config); err != nil {
+		return fmt.Errorf("could not ensure schema: %w", err)
+	}
+	return nil
+}
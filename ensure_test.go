@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gocloud.dev/docstore"
+)
+
+func TestAutoSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errIndex := errors.New("index build failed")
+	var calls int
+	var fail bool
+	registerDriver(t, "mem", Driver{
+		Schema: func(_ context.Context, _ *docstore.Collection, config *Config) error {
+			calls++
+			if !config.RecommendedIndexes {
+				t.Error("Expected the schema function to receive the configuration")
+			}
+			if fail {
+				return errIndex
+			}
+			return nil
+		},
+	})
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_auto_schema/id", AutoSchema: true, RecommendedIndexes: true})
+	if err != nil {
+		t.Fatalf("Expected NewWithOption() to be successful; got %v", err)
+	}
+	defer a.close()
+	if calls != 1 {
+		t.Errorf("Expected the schema to be configured when the adapter is opened; got %d calls", calls)
+	}
+
+	fail = true
+	if _, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_auto_schema_fail/id", AutoSchema: true, RecommendedIndexes: true}); !errors.Is(err, errIndex) {
+		t.Errorf("Expected the schema error; got %v", err)
+	}
+
+	if _, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_auto_schema_read_only/id", AutoSchema: true, ReadOnly: true}); err == nil {
+		t.Error("Expected a read-only adapter with AutoSchema to be rejected")
+	}
+}
@@ -0,0 +1,47 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// The environment variables read by NewFromEnv.
+const (
+	EnvURL       = "CASBIN_DOCSTORE_URL"       // Config.URL, which may also hold adapter_ query parameters (see New)
+	EnvTimeout   = "CASBIN_DOCSTORE_TIMEOUT"   // Config.Timeout, as a duration (e.g. 10s)
+	EnvNamespace = "CASBIN_DOCSTORE_NAMESPACE" // Config.Namespace
+	EnvTenant    = "CASBIN_DOCSTORE_TENANT"    // the tenant, qualifying the namespace so tenants sharing a collection are isolated
+)
+
+// NewFromEnv is the constructor for Adapter configured by the environment variables
+// EnvURL, EnvTimeout, EnvNamespace and EnvTenant, for applications configured by their
+// environment. Only EnvURL is required. The namespace of a tenant is "<namespace>:<tenant>",
+// or the tenant alone if there is no namespace. Options take precedence over the
+// environment.
+func NewFromEnv(ctx context.Context, opts ...Option) (*CloudAdapter, error) {
+	rawURL := os.Getenv(EnvURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("%s is not set", EnvURL)
+	}
+	config := &Config{URL: rawURL}
+	if v := os.Getenv(EnvTimeout); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvTimeout, err)
+		}
+		config.Timeout = timeout
+	}
+	config.Namespace = os.Getenv(EnvNamespace)
+	if tenant := os.Getenv(EnvTenant); tenant != "" {
+		if config.Namespace != "" {
+			config.Namespace += ":"
+		}
+		config.Namespace += tenant
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return NewWithOption(ctx, config)
+}
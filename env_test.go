@@ -0,0 +1,35 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Setenv(EnvURL, "")
+	if _, err := NewFromEnv(ctx); err == nil {
+		t.Error("Expected NewFromEnv() to fail without a url")
+	}
+
+	t.Setenv(EnvURL, "mem://casbin_rule_env/id")
+	t.Setenv(EnvTimeout, "5s")
+	t.Setenv(EnvNamespace, "billing")
+	t.Setenv(EnvTenant, "acme")
+	a, err := NewFromEnv(ctx)
+	if err != nil {
+		t.Fatalf("Expected NewFromEnv() to be successful; got %v", err)
+	}
+	defer a.close()
+	if a.timeout() != 5*time.Second || a.config.Namespace != "billing:acme" {
+		t.Errorf("Expected the environment to configure the adapter; got %v, %q", a.timeout(), a.config.Namespace)
+	}
+
+	t.Setenv(EnvTimeout, "soon")
+	if _, err := NewFromEnv(ctx); err == nil {
+		t.Error("Expected NewFromEnv() to fail with an invalid timeout")
+	}
+}
@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotAttempted is reported for rules in a batch that were not sent to the backend
+// because an earlier part of the batch failed.
+var ErrNotAttempted = errors.New("not attempted")
+
+// ErrTooManyRules is returned by loads that match more rules than Config.MaxLoadRules.
+var ErrTooManyRules = errors.New("too many rules to load")
+
+// RuleError describes a single rule in a batch that could not be written.
+type RuleError struct {
+	Index int      // the index of the rule in the batch, or -1 if the failure cannot be attributed to a rule
+	PType string   // the policy type of the rule
+	Rule  []string // the rule values, if the failure can be attributed to a rule
+	Err   error    // the reason the rule could not be written
+}
+
+// BatchError is returned by batch operations when some of the rules in the batch could not be written.
+//
+// For AddPolicies and RemovePolicies, Index is the position of the rule in the rules
+// argument, so callers can retry just the failed rules.
+type BatchError struct {
+	Err      error       // the underlying error returned by the backend
+	Failures []RuleError // the rules that failed or were not attempted
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d rule(s) in the batch could not be written: %v", len(e.Failures), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// QuotaScope is the scope of a rule quota.
+type QuotaScope string
+
+const (
+	// QuotaNamespace is the scope of Config.MaxRules, the rules of the configured namespace.
+	QuotaNamespace QuotaScope = "namespace"
+	// QuotaTotal is the scope of Config.MaxTotalRules, the rules of every namespace.
+	QuotaTotal QuotaScope = "total"
+)
+
+// QuotaError is returned by AddPolicy and AddPolicies when adding the rules would exceed
+// a rule quota. No rules are added.
+type QuotaError struct {
+	Scope  QuotaScope // the scope of the exceeded quota
+	Limit  int        // the maximum number of rules
+	Count  int        // the number of stored rules
+	Adding int        // the number of rules that would be added
+}
+
+// Error implements the error interface.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s rule quota exceeded: adding %d rule(s) to %d stored would exceed the limit of %d", e.Scope, e.Adding, e.Count, e.Limit)
+}
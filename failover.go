@@ -0,0 +1,338 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gocloud.dev/gcerrors"
+)
+
+// failoverCodes are the error codes that indicate that a store is unavailable, rather than
+// that the request is invalid, and cause a failover to the next store.
+var failoverCodes = []gcerrors.ErrorCode{
+	gcerrors.DeadlineExceeded,
+	gcerrors.Internal,
+	gcerrors.FailedPrecondition, // returned for closed collections
+}
+
+// isUnavailable reports whether err indicates that a store is unavailable.
+func isUnavailable(err error) bool {
+	return slices.Contains(failoverCodes, gcerrors.Code(err))
+}
+
+// FailoverAdapter is an [Adapter] over a primary store and replicas in other regions. Reads
+// and writes are served by the primary while it is available, and fail over to the first
+// available replica when it is not.
+//
+// The replicas are expected to be kept in sync with the primary, for example by the
+// replication of the provider. Writes served by a replica while the primary is unavailable
+// are replayed on the primary when it recovers, in the order they completed, before it
+// serves requests again. Recovery is detected by CheckHealth, which RunHealthChecks calls
+// periodically. Failovers are logged with the Config.Logger of the primary.
+type FailoverAdapter struct {
+	adapters []*adapter // the primary, followed by the replicas
+	checking sync.Mutex // serializes CheckHealth, which replays the missed writes without holding mu
+
+	mu     sync.Mutex
+	active int           // the index of the adapter serving requests
+	missed []missedWrite // the writes served by a replica, to replay on the primary
+}
+
+// missedWrite replays a write served by a replica on the primary.
+type missedWrite func(a *adapter) error
+
+var _ Adapter = (*FailoverAdapter)(nil)
+
+// NewFailover returns an adapter over the primary store and the replicas, each opened with
+// its own configuration.
+func NewFailover(ctx context.Context, primary *Config, replicas ...*Config) (*FailoverAdapter, error) {
+	f := &FailoverAdapter{}
+	for i, config := range append([]*Config{primary}, replicas...) {
+		a, err := NewWithOption(ctx, config)
+		if err != nil {
+			f.close()
+			if i == 0 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		f.adapters = append(f.adapters, a)
+	}
+	return f, nil
+}
+
+func (f *FailoverAdapter) close() {
+	for _, a := range f.adapters {
+		a.close()
+	}
+	f.adapters = nil
+}
+
+// Shutdown gracefully shuts the adapters of the stores down: new loads and changes return
+// ErrShutdown, and the collections are closed once the operations in progress complete.
+func (f *FailoverAdapter) Shutdown(ctx context.Context) error {
+	var errs []error
+	for i, a := range f.adapters {
+		if err := a.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("store %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Active returns the index of the store serving requests: 0 for the primary, or i for
+// the i-th replica.
+func (f *FailoverAdapter) Active() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+// serve calls fn with the active adapter and, if it is unavailable, with the following
+// adapters in order until one succeeds, which becomes the active adapter. fn is told whether
+// it is a retry. If a replica serves a write, record is called to record it for replay on
+// the primary; record is nil for reads, and copies the arguments of writes, which the caller
+// may change afterwards.
+func (f *FailoverAdapter) serve(record func() missedWrite, fn func(a *adapter, retry bool) error) error {
+	f.mu.Lock()
+	start := f.active
+	f.mu.Unlock()
+
+	var errs []error
+	for i := start; i < len(f.adapters); i++ {
+		err := fn(f.adapters[i], i > start)
+		if err == nil {
+			f.mu.Lock()
+			if i != f.active {
+				f.adapters[0].config.logger().Printf("failover: store %d unavailable, failing over to store %d", f.active, i)
+				f.active = i
+			}
+			if record != nil && i > 0 {
+				f.missed = append(f.missed, record())
+			}
+			f.mu.Unlock()
+			return nil
+		}
+		if !isUnavailable(err) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("store %d: %w", i, err))
+	}
+	return fmt.Errorf("no store available: %w", errors.Join(errs...))
+}
+
+// CheckHealth checks the health of the stores. If the active store is unavailable, it fails
+// over to the first available replica. If a replica is active and the primary is available
+// again, the missed writes are replayed on the primary, which then serves requests again.
+// Requests are served by the replica while the writes are replayed.
+//
+// An error is returned if no store is available, or if the primary becomes unavailable
+// again during the replay, in which case the replay is resumed on the next call. Missed
+// writes that fail because they were already applied to the primary, with AlreadyExists or
+// NotFound, are replayed; writes the primary rejects for another reason are dropped, and
+// returned as an error, rather than keep the primary from serving requests again.
+func (f *FailoverAdapter) CheckHealth(ctx context.Context) error {
+	f.checking.Lock()
+	defer f.checking.Unlock()
+
+	f.mu.Lock()
+	active := f.active
+	f.mu.Unlock()
+
+	if active > 0 && f.adapters[0].HealthCheck(ctx) == nil {
+		return f.failBack()
+	}
+
+	var errs []error
+	for i := active; i < len(f.adapters); i++ {
+		err := f.adapters[i].HealthCheck(ctx)
+		if err == nil {
+			f.mu.Lock()
+			if i != active && f.active == active {
+				f.adapters[0].config.logger().Printf("failover: store %d unavailable, failing over to store %d", f.active, i)
+				f.active = i
+			}
+			f.mu.Unlock()
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("store %d: %w", i, err))
+	}
+	return fmt.Errorf("no store available: %w", errors.Join(errs...))
+}
+
+// failBack replays the missed writes on the primary in order, including those served while
+// replaying, and makes it the active adapter once none are left.
+func (f *FailoverAdapter) failBack() error {
+	var errs []error
+	for {
+		f.mu.Lock()
+		if len(f.missed) == 0 {
+			f.adapters[0].config.logger().Printf("failover: primary recovered, failing back from store %d", f.active)
+			f.active = 0
+			f.mu.Unlock()
+			break
+		}
+		write := f.missed[0]
+		f.mu.Unlock()
+
+		err := write(f.adapters[0])
+		switch {
+		case err == nil, errors.Is(err, ErrRuleExists):
+		case isUnavailable(err):
+			return fmt.Errorf("could not replay missed writes on the primary: %w", err)
+		case gcerrors.Code(err) == gcerrors.AlreadyExists, gcerrors.Code(err) == gcerrors.NotFound:
+		default:
+			errs = append(errs, err)
+		}
+
+		f.mu.Lock()
+		f.missed = f.missed[1:]
+		f.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("dropped %d missed writes rejected by the primary: %w", len(errs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// RunHealthChecks calls CheckHealth every interval until ctx is done, logging errors. It is
+// meant to run in its own goroutine.
+func (f *FailoverAdapter) RunHealthChecks(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := f.CheckHealth(ctx); err != nil && ctx.Err() == nil {
+			f.adapters[0].config.logger().Printf("failover health check error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// LoadPolicy loads all policy rules from the active store. If the store fails while loading,
+// the policies of the model are cleared before loading from the next store.
+func (f *FailoverAdapter) LoadPolicy(model model.Model) error {
+	return f.serve(nil, func(a *adapter, retry bool) error {
+		if retry {
+			model.ClearPolicy()
+		}
+		return a.LoadPolicy(model)
+	})
+}
+
+// LoadFilteredPolicy loads only policy rules that match the filter from the active store.
+func (f *FailoverAdapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	return f.serve(nil, func(a *adapter, retry bool) error {
+		if retry {
+			model.ClearPolicy()
+		}
+		return a.LoadFilteredPolicy(model, filter)
+	})
+}
+
+// IsFiltered returns true if the loaded policy has been filtered.
+func (f *FailoverAdapter) IsFiltered() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.adapters[f.active].IsFiltered()
+}
+
+// SavePolicy saves policy to the active store.
+func (f *FailoverAdapter) SavePolicy(model model.Model) error {
+	return f.serve(func() missedWrite {
+		snapshot := model.Copy()
+		return func(a *adapter) error { return a.SavePolicy(snapshot) }
+	}, func(a *adapter, _ bool) error { return a.SavePolicy(model) })
+}
+
+// AddPolicy adds a policy rule to the active store.
+func (f *FailoverAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return f.serve(func() missedWrite {
+		rule := slices.Clone(rule)
+		return func(a *adapter) error { return a.AddPolicy(sec, ptype, rule) }
+	}, func(a *adapter, _ bool) error { return a.AddPolicy(sec, ptype, rule) })
+}
+
+// AddPolicies adds policy rules to the active store.
+func (f *FailoverAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return f.serve(func() missedWrite {
+		rules := cloneRules(rules)
+		return func(a *adapter) error { return a.AddPolicies(sec, ptype, rules) }
+	}, func(a *adapter, _ bool) error { return a.AddPolicies(sec, ptype, rules) })
+}
+
+// RemovePolicy removes a policy rule from the active store.
+func (f *FailoverAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return f.serve(func() missedWrite {
+		rule := slices.Clone(rule)
+		return func(a *adapter) error { return a.RemovePolicy(sec, ptype, rule) }
+	}, func(a *adapter, _ bool) error { return a.RemovePolicy(sec, ptype, rule) })
+}
+
+// RemovePolicies removes policy rules from the active store.
+func (f *FailoverAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return f.serve(func() missedWrite {
+		rules := cloneRules(rules)
+		return func(a *adapter) error { return a.RemovePolicies(sec, ptype, rules) }
+	}, func(a *adapter, _ bool) error { return a.RemovePolicies(sec, ptype, rules) })
+}
+
+// RemoveFilteredPolicy removes policy rules that match the filter from the active store.
+func (f *FailoverAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return f.serve(func() missedWrite {
+		fieldValues := slices.Clone(fieldValues)
+		return func(a *adapter) error { return a.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...) }
+	}, func(a *adapter, _ bool) error {
+		return a.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	})
+}
+
+// UpdatePolicy updates a policy rule in the active store.
+func (f *FailoverAdapter) UpdatePolicy(sec string, ptype string, oldRule, newPolicy []string) error {
+	return f.serve(func() missedWrite {
+		oldRule, newPolicy := slices.Clone(oldRule), slices.Clone(newPolicy)
+		return func(a *adapter) error { return a.UpdatePolicy(sec, ptype, oldRule, newPolicy) }
+	}, func(a *adapter, _ bool) error { return a.UpdatePolicy(sec, ptype, oldRule, newPolicy) })
+}
+
+// UpdatePolicies updates policy rules in the active store.
+func (f *FailoverAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return f.serve(func() missedWrite {
+		oldRules, newRules := cloneRules(oldRules), cloneRules(newRules)
+		return func(a *adapter) error { return a.UpdatePolicies(sec, ptype, oldRules, newRules) }
+	}, func(a *adapter, _ bool) error { return a.UpdatePolicies(sec, ptype, oldRules, newRules) })
+}
+
+// UpdateFilteredPolicies replaces the policy rules that match the filter in the active store.
+func (f *FailoverAdapter) UpdateFilteredPolicies(sec string, ptype string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	var oldPolicies [][]string
+	err := f.serve(func() missedWrite {
+		newPolicies, fieldValues := cloneRules(newPolicies), slices.Clone(fieldValues)
+		return func(a *adapter) error {
+			_, err := a.UpdateFilteredPolicies(sec, ptype, newPolicies, fieldIndex, fieldValues...)
+			return err
+		}
+	}, func(a *adapter, _ bool) error {
+		var err error
+		oldPolicies, err = a.UpdateFilteredPolicies(sec, ptype, newPolicies, fieldIndex, fieldValues...)
+		return err
+	})
+	return oldPolicies, err
+}
+
+// cloneRules returns a deep copy of the rules.
+func cloneRules(rules [][]string) [][]string {
+	cloned := make([][]string, len(rules))
+	for i, rule := range rules {
+		cloned[i] = slices.Clone(rule)
+	}
+	return cloned
+}
@@ -0,0 +1,150 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"gocloud.dev/docstore"
+)
+
+func TestFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := NewFailover(ctx,
+		&Config{URL: "mem://casbin_rule_failover_primary/id"},
+		&Config{URL: "mem://casbin_rule_failover_replica/id"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range f.adapters {
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+	}
+
+	// Take the primary down by swapping in a closed collection.
+	primary := f.adapters[0]
+	healthy := primary.collection
+	down, err := docstore.OpenCollection(ctx, "mem://casbin_rule_failover_down/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.Close()
+	primary.collection = down
+
+	if err := f.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to fail over; got %v", err)
+	}
+	if f.Active() != 1 {
+		t.Fatalf("Expected the replica to be active; got store %d", f.Active())
+	}
+	if err := f.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := f.CheckHealth(ctx); err != nil {
+		t.Fatalf("Expected CheckHealth() to be successful; got %v", err)
+	}
+	if f.Active() != 1 {
+		t.Fatalf("Expected the replica to stay active while the primary is down; got store %d", f.Active())
+	}
+
+	want := [][]string{
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, want)
+
+	// Bring the primary back.
+	primary.collection = healthy
+	if err := f.CheckHealth(ctx); err != nil {
+		t.Fatalf("Expected CheckHealth() to be successful; got %v", err)
+	}
+	if f.Active() != 0 {
+		t.Fatalf("Expected the primary to be active after recovery; got store %d", f.Active())
+	}
+	if len(f.missed) != 0 {
+		t.Errorf("Expected the missed writes to be replayed; %d left", len(f.missed))
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, want)
+}
+
+func TestFailoverReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := NewFailover(ctx,
+		&Config{URL: "mem://casbin_rule_failover_replay_primary/id"},
+		&Config{URL: "mem://casbin_rule_failover_replay_replica/id"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.close()
+
+	primary := f.adapters[0]
+	healthy := primary.collection
+	down, err := docstore.OpenCollection(ctx, "mem://casbin_rule_failover_replay_down/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.Close()
+	primary.collection = down
+
+	// The missed SavePolicy replays the policy as it was saved, not as the model is later.
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to fail over; got %v", err)
+	}
+	e.ClearPolicy()
+
+	rejected := errors.New("rejected")
+	f.mu.Lock()
+	f.missed = append(f.missed, func(*adapter) error { return rejected })
+	f.mu.Unlock()
+
+	primary.collection = healthy
+	if err := f.CheckHealth(ctx); !errors.Is(err, rejected) {
+		t.Errorf("Expected CheckHealth() to return the rejected write; got %v", err)
+	}
+	if f.Active() != 0 {
+		t.Fatalf("Expected the primary to be active after recovery; got store %d", f.Active())
+	}
+	if len(f.missed) != 0 {
+		t.Errorf("Expected the rejected write to be dropped; %d left", len(f.missed))
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+)
+
+// FilterGroup selects the rules removed by RemoveFilteredPolicies, like the arguments of
+// RemoveFilteredPolicy: the rules of the policy type whose values from FieldIndex match
+// FieldValues, where empty field values match any value.
+type FilterGroup struct {
+	Sec         string
+	PType       string
+	FieldIndex  int
+	FieldValues []string
+}
+
+// RemoveFilteredPolicies removes the rules matching any of the filter groups, e.g. the
+// rules of many deprovisioned resources, in one round of chunked action lists instead of a
+// RemoveFilteredPolicy call per group. A rule matching several groups is removed once.
+//
+// Like RemoveFilteredPolicy, the removal is not atomic: if it fails part-way, the returned
+// [*BatchError] reports the rules that were not removed. A change event is reported per
+// group that removed rules.
+func (a *adapter) RemoveFilteredPolicies(ctx context.Context, groups []FilterGroup) error {
+	return a.intercept(ctx, OpInfo{Name: "RemoveFilteredPolicies", Write: true}, func(ctx context.Context) error {
+		return a.removeFilteredPolicies(ctx, groups)
+	})
+}
+
+// removeFilteredPolicies runs RemoveFilteredPolicies within the interceptors.
+func (a *adapter) removeFilteredPolicies(ctx context.Context, groups []FilterGroup) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	op := a.trackSlow("RemoveFilteredPolicies", 0)
+	shapes := make(map[string]struct{})
+	for _, g := range groups {
+		shapes[summarizeFieldValues(g.FieldIndex, g.FieldValues)] = struct{}{}
+	}
+	op.setFilter(strings.Join(sortedKeys(shapes), "; "))
+	defer op.done()
+
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
+
+	var actions []action
+	var events []ChangeEvent
+	removed := make(map[string]bool)
+	for _, g := range groups {
+		lines, err := a.filteredRules(ctx, g.PType, g.FieldIndex, g.FieldValues...)
+		if err != nil {
+			return err
+		}
+		var rules [][]string
+		for i := range lines {
+			if removed[lines[i].ID] {
+				continue
+			}
+			removed[lines[i].ID] = true
+			actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+			rules = append(rules, lines[i].values())
+		}
+		if len(rules) > 0 {
+			events = append(events, ChangeEvent{Operation: OpRemoveFilteredPolicy, Sec: g.Sec, PType: g.PType, Rules: rules})
+		}
+	}
+	op.addRules(len(actions))
+
+	return a.commit(ctx, a.do, actions, events...)
+}
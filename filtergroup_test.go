@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestRemoveFilteredPolicies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_filter_groups/id", Notifiers: []Notifier{n}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "write"},
+		{"bob", "data2", "read"},
+		{"carol", "data3", "read"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"bob", "data2_admin"}); err != nil {
+		t.Fatal(err)
+	}
+	n.events = nil
+
+	err = a.RemoveFilteredPolicies(ctx, []FilterGroup{
+		{Sec: "p", PType: "p", FieldIndex: 1, FieldValues: []string{"data2"}},
+		{Sec: "p", PType: "p", FieldIndex: 0, FieldValues: []string{"alice"}}, // overlaps the first group
+		{Sec: "g", PType: "g", FieldIndex: 0, FieldValues: []string{"bob"}},
+		{Sec: "p", PType: "p", FieldIndex: 0, FieldValues: []string{"dave"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicies() to be successful; got %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"carol", "data3", "read"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 0 {
+		t.Errorf("Expected the grouping rule to be removed; got %v", grouping)
+	}
+	if len(n.events) != 3 || len(n.events[0].Rules) != 2 || len(n.events[1].Rules) != 1 {
+		t.Errorf("Expected an event per group that removed rules; got %v", n.events)
+	}
+}
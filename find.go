@@ -0,0 +1,98 @@
+package adapter
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"gocloud.dev/docstore"
+)
+
+// FindOption configures FindRules.
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	limit int
+}
+
+// FindLimit limits FindRules to n matching documents (0 disables the limit). Which
+// documents are returned is up to the provider.
+func FindLimit(n int) FindOption {
+	return func(o *findOptions) {
+		o.limit = n
+	}
+}
+
+// FindRules returns the raw documents of the rules matching a partial example, with their
+// IDs and metadata, for admin tooling. The non-empty fields of the example among PType,
+// V0 to V5, ID and Sec, and each entry of Meta, must be equal in the matching documents;
+// other fields are ignored, and a zero example matches every rule. The documents are
+// returned as stored, sorted by ID: subjects stored as hashes are not resolved, and rules
+// outside their validity window are included.
+//
+// Only documents of the configured namespace are returned, and internal documents such as
+// outbox events are skipped.
+func (a *adapter) FindRules(ctx context.Context, example CasbinRule, opts ...FindOption) ([]CasbinRule, error) {
+	var rules []CasbinRule
+	err := a.intercept(ctx, OpInfo{Name: "FindRules"}, func(ctx context.Context) error {
+		var err error
+		rules, err = a.findRules(ctx, example, opts...)
+		return err
+	})
+	return rules, err
+}
+
+// findRules runs FindRules within the interceptors.
+func (a *adapter) findRules(ctx context.Context, example CasbinRule, opts ...FindOption) ([]CasbinRule, error) {
+	var o findOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	columns := example.columns()
+	values := a.hashRule(columns[:])
+	colls := a.ruleCollections()
+	if example.PType != "" {
+		colls = a.ptypeCollections(example.PType)
+	}
+	lines, err := a.collectFrom(ctx, colls, func(query *docstore.Query) *docstore.Query {
+		for _, f := range [][2]string{{"ptype", example.PType}, {"id", example.ID}, {"sec", example.Sec}} {
+			if f[1] != "" {
+				query = query.Where(a.field(f[0]), EqualOp, f[1])
+			}
+		}
+		for _, key := range sortedKeys(example.Meta) {
+			query = query.Where(a.field("meta."+key), EqualOp, example.Meta[key])
+		}
+		if a.config.Schema == SchemaArray {
+			return query // the values are matched below
+		}
+		for i := 0; i <= 5; i++ {
+			query = a.addFiltersToQuery(query, i, 0, values...)
+		}
+		if o.limit > 0 {
+			query = query.Limit(o.limit)
+		}
+		return query
+	})
+	if err != nil {
+		return nil, err
+	}
+	if a.config.Schema == SchemaArray {
+		lines = slices.DeleteFunc(lines, func(line CasbinRule) bool {
+			return !line.matchesFieldValues(0, values...)
+		})
+	}
+	slices.SortFunc(lines, func(x, y CasbinRule) int { return cmp.Compare(x.ID, y.ID) })
+	if o.limit > 0 && len(lines) > o.limit {
+		lines = lines[:o.limit]
+	}
+	return lines, nil
+}
@@ -0,0 +1,68 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindRules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for name, schema := range map[string]Schema{"Columns": SchemaColumns, "Array": SchemaArray} {
+		t.Run(name, func(t *testing.T) {
+			a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_find_" + name + "/id", Schema: schema})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.close()
+			if err := a.AddPolicyWithMeta(ctx, "p", "p", []string{"alice", "data1", "read"}, map[string]string{"owner": "team-a"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.AddPolicies("p", "p", [][]string{{"alice", "data2", "write"}, {"bob", "data1", "read"}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				name    string
+				example CasbinRule
+				opts    []FindOption
+				want    int
+			}{
+				{name: "All", want: 4},
+				{name: "PType", example: CasbinRule{PType: "p"}, want: 3},
+				{name: "Values", example: CasbinRule{PType: "p", V0: "alice", V2: "read"}, want: 1},
+				{name: "Meta", example: CasbinRule{Meta: map[string]string{"owner": "team-a"}}, want: 1},
+				{name: "Limit", example: CasbinRule{V0: "alice"}, opts: []FindOption{FindLimit(2)}, want: 2},
+				{name: "NoMatch", example: CasbinRule{V0: "carol"}, want: 0},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					lines, err := a.FindRules(ctx, tt.example, tt.opts...)
+					if err != nil {
+						t.Fatalf("Expected FindRules() to be successful; got %v", err)
+					}
+					if len(lines) != tt.want {
+						t.Fatalf("Expected %d rules; got %v", tt.want, lines)
+					}
+					for i, line := range lines {
+						if line.ID == "" || (i > 0 && lines[i-1].ID > line.ID) {
+							t.Errorf("Expected the documents with their IDs, sorted by ID; got %v", lines)
+						}
+					}
+				})
+			}
+
+			lines, err := a.FindRules(ctx, CasbinRule{V1: "data1", V2: "read", Meta: map[string]string{"owner": "team-a"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != 1 || lines[0].Meta["owner"] != "team-a" || !lines[0].Annotated {
+				t.Errorf("Expected the raw document with its metadata; got %+v", lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gocloud.dev/docstore"
+)
+
+// IDStrategy determines how document IDs are assigned to rules.
+type IDStrategy int
+
+const (
+	// IDStrategyHash derives the ID from the rule content. This is the default.
+	IDStrategyHash IDStrategy = iota
+	// IDStrategyRandom assigns a random ID to each new rule. Rules are then located
+	// by their content, which requires a query per policy type.
+	IDStrategyRandom
+	// IDStrategyCanonical derives the ID from a canonical, versioned encoding of the rule
+	// content, which does not depend on how rules are represented in Go. Rules stored with
+	// IDStrategyHash IDs are still found by removals and updates.
+	IDStrategyCanonical
+)
+
+// canonicalIDVersion is the version of the canonical rule encoding, which is part of every
+// canonical ID. It must be incremented whenever the encoding changes.
+const canonicalIDVersion = 1
+
+// canonicalEncoding returns the canonical encoding of the rule content: the encoding
+// version and the namespace, if any, followed by the policy type and the values, each
+// prefixed with its length.
+func canonicalEncoding(line CasbinRule) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "casbin-rule/v%d", canonicalIDVersion)
+	if line.Namespace != "" {
+		fmt.Fprintf(&b, "|ns=%d:%s", len(line.Namespace), line.Namespace)
+	}
+	for _, v := range append([]string{line.PType}, line.values()...) {
+		fmt.Fprintf(&b, "|%d:%s", len(v), v)
+	}
+	return b.Bytes()
+}
+
+// canonicalID returns the ID of the rule with IDStrategyCanonical.
+func canonicalID(line CasbinRule) string {
+	sum := sha256.Sum256(canonicalEncoding(line))
+	return fmt.Sprintf("v%d_%s", canonicalIDVersion, hex.EncodeToString(sum[:]))
+}
+
+// contentIDs reports whether document IDs are derived from the rule content.
+func (a *adapter) contentIDs() bool {
+	return a.config.IDStrategy == IDStrategyHash || a.config.IDStrategy == IDStrategyCanonical
+}
+
+// contentID returns the content-derived ID of the rule for the ID strategy.
+func (a *adapter) contentID(line CasbinRule) string {
+	if a.config.IDStrategy == IDStrategyCanonical {
+		return canonicalID(line)
+	}
+	return generateID(line)
+}
+
+// legacyLines returns the lines with the IDs they have with IDStrategyHash.
+func legacyLines(lines []CasbinRule) []CasbinRule {
+	legacy := make([]CasbinRule, len(lines))
+	for i, line := range lines {
+		legacy[i] = line
+		legacy[i].ID = generateID(line)
+	}
+	return legacy
+}
+
+// randomID returns a random hex-encoded identifier.
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ruleKey returns a key identifying the content of a rule, independent of its ID.
+func ruleKey(line CasbinRule) string {
+	return strings.Join(append([]string{line.PType}, line.values()...), "\x00")
+}
+
+// newLine returns the document for a rule of the given section, with an ID assigned according to the ID strategy.
+func (a *adapter) newLine(sec, ptype string, rule []string) CasbinRule {
+	line := a.ruleLine(ptype, rule)
+	line.Sec = sec
+	if a.config.IDStrategy == IDStrategyRandom {
+		line.ID = randomID()
+	}
+	if a.config.Priorities {
+		line.Priority = a.nextPriority()
+	}
+	return line
+}
+
+// storedRules returns the stored rules of the given policy types, indexed by [ruleKey].
+func (a *adapter) storedRules(ctx context.Context, ptypes map[string]struct{}) (map[string][]CasbinRule, error) {
+	stored := make(map[string][]CasbinRule)
+	for ptype := range ptypes {
+		lines, err := a.collectPType(ctx, ptype, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			key := ruleKey(line)
+			stored[key] = append(stored[key], line)
+		}
+	}
+	return stored, nil
+}
+
+// resolve returns the stored documents with the same content as the lines.
+//
+// With content-derived IDs the lines already carry the IDs of their documents and
+// are returned unchanged, along with stored documents that have legacy IDs under
+// IDStrategyCanonical; otherwise the stored documents are looked up by content.
+func (a *adapter) resolve(ctx context.Context, lines []CasbinRule) ([]CasbinRule, error) {
+	switch a.config.IDStrategy {
+	case IDStrategyHash:
+		return lines, nil
+	case IDStrategyCanonical:
+		legacy, err := a.getLines(ctx, legacyLines(lines))
+		if err != nil {
+			return nil, err
+		}
+		return append(slices.Clip(lines), legacy...), nil
+	}
+
+	ptypes := make(map[string]struct{})
+	for _, line := range lines {
+		ptypes[line.PType] = struct{}{}
+	}
+	stored, err := a.storedRules(ctx, ptypes)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]CasbinRule, 0, len(lines))
+	for _, line := range lines {
+		resolved = append(resolved, stored[ruleKey(line)]...)
+	}
+	return resolved, nil
+}
+
+// reuseIDs assigns the IDs of already stored documents to lines with the same content,
+// so that saving a policy with random IDs does not duplicate existing rules.
+func (a *adapter) reuseIDs(ctx context.Context, lines []CasbinRule) error {
+	if a.contentIDs() {
+		return nil
+	}
+
+	ptypes := make(map[string]struct{})
+	for _, line := range lines {
+		ptypes[line.PType] = struct{}{}
+	}
+	stored, err := a.storedRules(ctx, ptypes)
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		if existing := stored[ruleKey(lines[i])]; len(existing) > 0 {
+			lines[i].ID = existing[0].ID
+		}
+	}
+	return nil
+}
+
+// updateMods returns the modifications that turn the stored rule into the new rule.
+// Empty values are removed, matching the omitempty encoding of [CasbinRule].
+func updateMods(stored, line CasbinRule) docstore.Mods {
+	mods := docstore.Mods{}
+	if line.Values != nil {
+		if !slices.Equal(stored.Values, line.Values) {
+			mods["values"] = line.Values
+		}
+		return mods
+	}
+	oldValues := stored.columns()
+	newValues := line.columns()
+	for i := range newValues {
+		if oldValues[i] == newValues[i] {
+			continue
+		}
+		fp := docstore.FieldPath(fmt.Sprintf("v%d", i))
+		if newValues[i] == "" && i > 0 {
+			mods[fp] = nil
+		} else {
+			mods[fp] = newValues[i]
+		}
+	}
+	return mods
+}
+
+// migrateChunkSize is the number of documents read and rewritten per batch by MigrateIDs.
+const migrateChunkSize = 500
+
+// hasStrategyID reports whether the ID of the stored document was assigned by the ID strategy.
+// IDs that are not derived from the content of the rule are considered random.
+func hasStrategyID(line CasbinRule, strategy IDStrategy) bool {
+	switch strategy {
+	case IDStrategyHash:
+		return line.ID == generateID(line)
+	case IDStrategyCanonical:
+		return line.ID == canonicalID(line)
+	default:
+		return line.ID != generateID(line) && line.ID != canonicalID(line)
+	}
+}
+
+// strategyID returns the ID the ID strategy assigns to the rule.
+func strategyID(line CasbinRule, strategy IDStrategy) string {
+	switch strategy {
+	case IDStrategyHash:
+		return generateID(line)
+	case IDStrategyCanonical:
+		return canonicalID(line)
+	default:
+		return randomID()
+	}
+}
+
+// MigrateIDs rewrites the stored documents whose IDs were assigned with the from strategy
+// under the IDs of the to strategy, and returns the number of migrated documents. The
+// collection is read in chunks ordered by ID; for each chunk the documents are written
+// under their new IDs before the old documents are removed. Rules stored more than once
+// are merged when the new IDs are derived from the content.
+//
+// Documents that do not have a from ID, such as those already migrated, are skipped, so a
+// failed or interrupted migration is resumed by calling MigrateIDs again. Loading is not
+// affected while the migration runs. Afterwards Config.IDStrategy should be set to the
+// to strategy, and MigrateIDs called again to migrate rules written in the meantime.
+func (a *adapter) MigrateIDs(ctx context.Context, from, to IDStrategy) (int, error) {
+	var migrated int
+	err := a.intercept(ctx, OpInfo{Name: "MigrateIDs", Write: true}, func(ctx context.Context) error {
+		var err error
+		migrated, err = a.migrateIDs(ctx, from, to)
+		return err
+	})
+	return migrated, err
+}
+
+// migrateIDs runs MigrateIDs within the interceptors.
+func (a *adapter) migrateIDs(ctx context.Context, from, to IDStrategy) (int, error) {
+	if err := a.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	if from == to {
+		return 0, errors.New("the ID strategies must differ")
+	}
+
+	migrated := 0
+	err := a.forEachPage(ctx, migrateChunkSize, func(ctx context.Context, lines []CasbinRule) error {
+		n, err := a.migrateChunk(ctx, from, to, lines)
+		migrated += n
+		return err
+	})
+	return migrated, err
+}
+
+// migrateChunk migrates the documents of a chunk that have from IDs, and returns their number.
+func (a *adapter) migrateChunk(ctx context.Context, from, to IDStrategy, lines []CasbinRule) (int, error) {
+	var puts, deletes []action
+	putIDs := make(map[string]struct{})
+	for i := range lines {
+		line := &lines[i]
+		if !a.isRule(line) || !hasStrategyID(*line, from) {
+			continue
+		}
+		deletes = append(deletes, action{kind: actionDelete, line: line, moved: true})
+		migratedLine := *line
+		migratedLine.ID = strategyID(*line, to)
+		if _, ok := putIDs[migratedLine.ID]; ok {
+			continue // the rule is stored more than once
+		}
+		putIDs[migratedLine.ID] = struct{}{}
+		puts = append(puts, action{kind: actionPut, line: &migratedLine})
+	}
+
+	// The new documents are written first, so an interrupted migration never loses rules.
+	if len(deletes) == 0 {
+		return 0, nil
+	}
+	event := ChangeEvent{Operation: OpMigrateIDs}
+	if err := a.commit(ctx, a.do, puts, event); err != nil {
+		return 0, err
+	}
+	if err := a.commit(ctx, a.do, deletes, event); err != nil {
+		return 0, err
+	}
+	return len(deletes), nil
+}
@@ -0,0 +1,197 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestRandomIDStrategy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:            "mem://casbin_rule_random_ids/id",
+		IDStrategy:     IDStrategyRandom,
+		InPlaceUpdates: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saving twice must not duplicate rules.
+	for i := 0; i < 2; i++ {
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+	}
+	lines, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 stored rules; got %d", len(lines))
+	}
+
+	stored, err := a.resolve(ctx, []CasbinRule{savePolicyLine("p", []string{"alice", "data1", "read"})})
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected to resolve the stored rule; got %v, %v", stored, err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+	updated := CasbinRule{ID: stored[0].ID}
+	if err := a.collection.Get(ctx, &updated); err != nil {
+		t.Fatalf("Expected the document to keep its ID; got %v", err)
+	}
+	if updated.V2 != "write" {
+		t.Errorf("Expected v2 to be updated in place; got %+v", updated)
+	}
+
+	if err := a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicies("p", "p", [][]string{{"data2_admin", "data2", "read"}}); err != nil {
+		t.Fatalf("Expected RemovePolicies() to be successful; got %v", err)
+	}
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "write"},
+		{"data2_admin", "data2", "write"},
+	})
+}
+
+func TestCanonicalIDStrategy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	line := CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}
+	if got, want := canonicalID(line), "v1_"+canonicalIDForTest(t, "casbin-rule/v1|1:p|5:alice|5:data1|4:read"); got != want {
+		t.Errorf("Expected canonical ID %q; got %q", want, got)
+	}
+	line.Sec = "p"
+	line.Meta = map[string]string{"owner": "ops"}
+	if canonicalID(line) != canonicalID(CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read"}) {
+		t.Error("Expected fields other than the rule content not to change the canonical ID")
+	}
+
+	legacy, err := New(ctx, "mem://casbin_rule_canonical/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_canonical/id", IDStrategy: IDStrategyCanonical})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(legacy, a)
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+	findings, err := a.Lint(ctx, nil)
+	if err != nil {
+		t.Fatalf("Expected Lint() to be successful; got %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected legacy IDs to be recognized; got %v", findings)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	lines, err := a.collectRules(ctx, a.collection.Query().Where("v0", "=", "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].ID != canonicalID(lines[0]) {
+		t.Errorf("Expected the updated rule to be stored with its canonical ID; got %v", lines)
+	}
+}
+
+func canonicalIDForTest(t *testing.T, encoding string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(encoding))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMigrateIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_migrate_ids/id", IDStrategy: IDStrategyRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	// A rule stored twice is merged by the migration.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	for _, step := range []struct {
+		from, to IDStrategy
+		migrated int
+	}{
+		{IDStrategyRandom, IDStrategyCanonical, 6},
+		{IDStrategyCanonical, IDStrategyHash, 5},
+	} {
+		n, err := a.MigrateIDs(ctx, step.from, step.to)
+		if err != nil {
+			t.Fatalf("Expected MigrateIDs() to be successful; got %v", err)
+		}
+		if n != step.migrated {
+			t.Errorf("Expected %d migrated documents; got %d", step.migrated, n)
+		}
+		if n, err := a.MigrateIDs(ctx, step.from, step.to); err != nil || n != 0 {
+			t.Errorf("Expected a repeated migration to find nothing to migrate; got %d, %v", n, err)
+		}
+
+		lines, err := a.collectRules(ctx, a.collection.Query())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 5 {
+			t.Errorf("Expected 5 stored rules; got %d", len(lines))
+		}
+		for _, line := range lines {
+			if !hasStrategyID(line, step.to) {
+				t.Errorf("Expected %v to have an ID of strategy %d", line, step.to)
+			}
+		}
+	}
+
+	if _, err := a.MigrateIDs(ctx, IDStrategyHash, IDStrategyHash); err == nil {
+		t.Error("Expected MigrateIDs() to fail for equal strategies")
+	}
+}
@@ -0,0 +1,67 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// IndexError is returned by queries that the provider rejects because they need an index
+// that does not exist, e.g. a Firestore composite index for a filter on several fields. It
+// holds the definition of the missing index, rather than the raw provider error.
+type IndexError struct {
+	Collection string       // the collection (the collection group on Firestore) of the index
+	Fields     []IndexField // the fields of the index, in order
+	CreateURL  string       // the link creating the index in the console of the provider, if it gave one
+	Err        error        // the error of the provider
+}
+
+// IndexField is a field of the index of an [IndexError].
+type IndexField struct {
+	Path  string // the dot-separated path of the field
+	Order string // "asc" or "desc", or the array mode (e.g. "array-contains") of array fields
+}
+
+// Error implements the error interface.
+func (e *IndexError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, f.Path+" "+f.Order)
+	}
+	msg := fmt.Sprintf("query requires a missing index on %s (%s)", e.Collection, strings.Join(fields, ", "))
+	if e.CreateURL != "" {
+		msg += "; create it at " + e.CreateURL
+	}
+	return msg
+}
+
+// Unwrap returns the error of the provider.
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
+
+// IndexErrorFunc returns the [IndexError] of a provider error rejecting a query for a missing
+// index, or nil if err is another error. err may wrap the provider error.
+type IndexErrorFunc func(err error) *IndexError
+
+// newIndexErrorFunc returns the [IndexErrorFunc] of the provider of the configured URL, or
+// nil if its [Driver] has none.
+func newIndexErrorFunc(config *Config) IndexErrorFunc {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil
+	}
+	return driverFor(u.Scheme).IndexError
+}
+
+// queryError returns the error of a query with the credentials of the configured URLs
+// removed, as an [IndexError] if the provider rejected the query for a missing index.
+func (a *adapter) queryError(err error) error {
+	err = a.redact(err)
+	if a.indexError != nil {
+		if indexErr := a.indexError(err); indexErr != nil {
+			return indexErr
+		}
+	}
+	return err
+}
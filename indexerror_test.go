@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIndexError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Fail reads as a provider would for a query needing a missing index.
+	errMissingIndex := errors.New("the query requires an index")
+	registerDriver(t, "mem", Driver{
+		ReadOptions: func(_ func(interface{}) bool, _ Consistency) error {
+			return errMissingIndex
+		},
+		IndexError: func(err error) *IndexError {
+			if !errors.Is(err, errMissingIndex) {
+				return nil
+			}
+			return &IndexError{
+				Collection: "casbin_rule",
+				Fields:     []IndexField{{Path: "ptype", Order: "asc"}, {Path: "v1", Order: "desc"}},
+				CreateURL:  "https://console.example.com/indexes",
+				Err:        err,
+			}
+		},
+	})
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_index_error/id", ReadConsistency: ConsistencyStrong})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	_, err = a.GetPoliciesForSubject(ctx, "alice")
+	if err == nil {
+		t.Fatal("Expected the query to fail")
+	}
+
+	var indexErr *IndexError
+	if !errors.As(err, &indexErr) {
+		t.Fatalf("Expected an IndexError; got %v", err)
+	}
+	if !errors.Is(err, errMissingIndex) {
+		t.Errorf("Expected the IndexError to wrap the provider error; got %v", err)
+	}
+	want := "query requires a missing index on casbin_rule (ptype asc, v1 desc); create it at https://console.example.com/indexes"
+	if got := indexErr.Error(); got != want {
+		t.Errorf("Expected %q; got %q", want, got)
+	}
+}
@@ -0,0 +1,92 @@
+// Package inject provides the adapter, the watcher and a synced enforcer to dependency
+// injection frameworks, so that applications can integrate them through their existing
+// graphs. The constructors take a [Config], which the application provides.
+//
+// With google/wire, use ProviderSet in an injector:
+//
+//	func initEnforcer(ctx context.Context, config inject.Config) (*casbin.SyncedEnforcer, func(), error) {
+//		wire.Build(inject.ProviderSet)
+//		return nil, nil, nil
+//	}
+//
+// With uber/fx, provide NewSyncedEnforcer and the accessors, and shut the enforcer down
+// with the application:
+//
+//	fx.Provide(
+//		func() context.Context { return context.Background() },
+//		func() inject.Config { return config },
+//		inject.NewSyncedEnforcer,
+//		inject.Adapter,
+//		inject.Watcher,
+//		inject.Enforcer,
+//	),
+//	fx.Invoke(func(lc fx.Lifecycle, e *watcher.SyncedEnforcer) {
+//		lc.Append(fx.Hook{OnStop: e.Shutdown})
+//	})
+//
+// As for NewSyncedEnforcer of the watcher package, the docstore and pubsub drivers must be
+// registered by blank imports.
+package inject
+
+import (
+	"context"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"github.com/bartventer/casbin-go-cloud-adapter/watcher"
+	"github.com/casbin/casbin/v2"
+	"github.com/google/wire"
+)
+
+// Config is the configuration of the enforcer provided by the package.
+type Config struct {
+	ModelPath       string           // the path of the Casbin model
+	DocstoreURL     string           // the docstore url of the adapter (see adapter.New)
+	PubSubURL       string           // the pubsub url of the topic of the watcher
+	SubscriptionURL string           // the pubsub url of the subscription of the watcher (defaults to PubSubURL)
+	AdapterOptions  []adapter.Option // the options of the adapter
+	WatcherOptions  []watcher.Option // the options of the watcher
+}
+
+// ProviderSet is the google/wire provider set of the synced enforcer, its adapter, its
+// watcher and its [casbin.SyncedEnforcer], from a [Config].
+var ProviderSet = wire.NewSet(
+	ProvideSyncedEnforcer,
+	Adapter,
+	Watcher,
+	Enforcer,
+)
+
+// NewSyncedEnforcer creates the synced enforcer of the configuration, with
+// watcher.NewSyncedEnforcer.
+func NewSyncedEnforcer(ctx context.Context, config Config) (*watcher.SyncedEnforcer, error) {
+	return watcher.NewSyncedEnforcer(ctx, config.ModelPath, config.DocstoreURL, config.PubSubURL,
+		watcher.WithSubscriptionURL(config.SubscriptionURL),
+		watcher.WithAdapterOptions(config.AdapterOptions...),
+		watcher.WithWatcherOptions(config.WatcherOptions...),
+	)
+}
+
+// ProvideSyncedEnforcer is NewSyncedEnforcer with the cleanup function of google/wire, which
+// closes the enforcer.
+func ProvideSyncedEnforcer(ctx context.Context, config Config) (*watcher.SyncedEnforcer, func(), error) {
+	e, err := NewSyncedEnforcer(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, e.Close, nil
+}
+
+// Adapter returns the adapter of the synced enforcer.
+func Adapter(e *watcher.SyncedEnforcer) adapter.Adapter {
+	return e.Adapter
+}
+
+// Watcher returns the watcher of the synced enforcer.
+func Watcher(e *watcher.SyncedEnforcer) *watcher.Watcher {
+	return e.Watcher
+}
+
+// Enforcer returns the [casbin.SyncedEnforcer] of the synced enforcer.
+func Enforcer(e *watcher.SyncedEnforcer) *casbin.SyncedEnforcer {
+	return e.SyncedEnforcer
+}
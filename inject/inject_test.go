@@ -0,0 +1,38 @@
+package inject
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/bartventer/casbin-go-cloud-adapter/drivers/sqlitedocstore"
+	_ "github.com/bartventer/casbin-go-cloud-adapter/watcher/drivers/mempubsub"
+)
+
+func TestProvideSyncedEnforcer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := Config{
+		ModelPath:   "../testdata/rbac_model.conf",
+		DocstoreURL: "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db") + "?table=casbin_rule&key_field=id",
+		PubSubURL:   "mem://casbin-inject",
+	}
+	e, cleanup, err := ProvideSyncedEnforcer(ctx, config)
+	if err != nil {
+		t.Fatalf("Expected ProvideSyncedEnforcer() to be successful; got %v", err)
+	}
+	defer cleanup()
+
+	if Adapter(e) == nil || Watcher(e) == nil || Enforcer(e) == nil {
+		t.Fatal("Expected the adapter, watcher and enforcer to be provided")
+	}
+	if _, err := Enforcer(e).AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	config.ModelPath = "missing.conf"
+	if _, _, err := ProvideSyncedEnforcer(ctx, config); err == nil {
+		t.Error("Expected ProvideSyncedEnforcer() to fail with a missing model")
+	}
+}
@@ -0,0 +1,49 @@
+package adapter
+
+import "context"
+
+// OpInfo describes an adapter operation to the interceptors.
+type OpInfo struct {
+	Name  string // the name of the method, e.g. "AddPolicy"
+	Sec   string // the model section of the rules, if the operation has one
+	PType string // the policy type of the rules, if the operation has one
+	Rules int    // the number of rules passed to the operation
+	Write bool   // whether the operation changes stored data
+}
+
+// Interceptor wraps the operations of the Casbin adapter interfaces, e.g. AddPolicy or
+// LoadPolicy, and the extended operations that change or query the rules: the metadata,
+// window and result variants of AddPolicy, PurgeFiltered, PurgeSubject, RenameSubject,
+// UpdatePriority, ImportStream, Restore, RestoreArchived, Rollback, Sync, MigrateIDs, the
+// staging operations, the GetPoliciesFor methods, DistinctValues, SubjectsForObjectAction,
+// PermissionsForSubject, FindRules and ListRules. Maintenance operations, e.g. Backup,
+// HealthCheck or EnsureSchema, are not intercepted.
+//
+// It calls next to run the operation, possibly with a derived context, or
+// returns an error without calling it to fail the operation, e.g. for authorization checks,
+// tagging, chaos injection or metrics. Interceptors run before the operation is started, so
+// they also see operations rejected by the adapter, e.g. with ErrReadOnly.
+type Interceptor func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error
+
+// WithInterceptor returns the option that appends the interceptor to Config.Interceptors.
+// The first interceptor is the outermost one.
+func WithInterceptor(interceptor Interceptor) Option {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors, interceptor)
+	}
+}
+
+// intercept runs the operation through the interceptors.
+func (a *adapter) intercept(ctx context.Context, op OpInfo, fn func(ctx context.Context) error) error {
+	interceptors := a.config.Interceptors
+	var next func(i int) func(ctx context.Context) error
+	next = func(i int) func(ctx context.Context) error {
+		if i == len(interceptors) {
+			return fn
+		}
+		return func(ctx context.Context) error {
+			return interceptors[i](ctx, op, next(i+1))
+		}
+	}
+	return next(0)(ctx)
+}
@@ -0,0 +1,143 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestInterceptors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []string
+	errDenied := errors.New("denied")
+	record := func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+		calls = append(calls, op.Name)
+		return next(ctx)
+	}
+	deny := func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+		if op.Write && op.PType == "g" {
+			return errDenied
+		}
+		if op.Name == "RemovePolicy" {
+			// The operation runs with the context passed to next.
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			return next(canceled)
+		}
+		return next(ctx)
+	}
+	a, err := New(ctx, "mem://casbin_rule_interceptors/id", WithInterceptor(record), WithInterceptor(deny))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny AddPolicy(); got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected RemovePolicy() to run with the context of the interceptor; got %v", err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ := e.GetPolicy(); len(policy) != 1 {
+		t.Errorf("Expected only the allowed rule to be stored; got %v", policy)
+	}
+
+	want := []string{"AddPolicy", "AddPolicy", "RemovePolicy", "LoadPolicy"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("Expected the operations %v to be intercepted; got %v", want, calls)
+	}
+}
+
+func TestExtendedInterceptors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []string
+	errDenied := errors.New("denied")
+	denyWrites := false
+	a, err := New(ctx, "mem://casbin_rule_extended_interceptors/id", WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+		calls = append(calls, op.Name)
+		if denyWrites && op.Write {
+			return errDenied
+		}
+		return next(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries are intercepted, and run once each.
+	calls = nil
+	if _, err := a.GetPoliciesForSubject(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.DistinctValues(ctx, "v0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.SubjectsForObjectAction(ctx, "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PermissionsForSubject(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.FindRules(ctx, CasbinRule{PType: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.ListRules(ctx, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PurgeSubject(ctx, "alice", true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetPoliciesForSubject", "DistinctValues", "SubjectsForObjectAction", "PermissionsForSubject", "FindRules", "ListRules", "PurgeSubject"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("Expected the queries %v to be intercepted; got %v", want, calls)
+	}
+
+	// Changes are intercepted, so an interceptor can deny them.
+	denyWrites = true
+	calls = nil
+	if err := a.RenameSubject(ctx, "alice", "carol"); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny RenameSubject(); got %v", err)
+	}
+	if _, err := a.PurgeSubject(ctx, "bob", false); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny PurgeSubject(); got %v", err)
+	}
+	if _, err := a.PurgeFiltered(ctx, nil, Filter{FieldPath: []string{"v0"}, Value: "bob"}); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny PurgeFiltered(); got %v", err)
+	}
+	if _, err := a.ImportStream(ctx, strings.NewReader("p, dave, data3, read\n"), FormatCSV); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny ImportStream(); got %v", err)
+	}
+	if _, err := a.MigrateIDs(ctx, IDStrategyHash, IDStrategyRandom); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny MigrateIDs(); got %v", err)
+	}
+	if _, err := a.StageAddPolicies(ctx, "p", "p", [][]string{{"dave", "data3", "read"}}); !errors.Is(err, errDenied) {
+		t.Errorf("Expected the interceptor to deny StageAddPolicies(); got %v", err)
+	}
+	want = []string{"RenameSubject", "PurgeSubject", "PurgeFiltered", "ImportStream", "MigrateIDs", "StageAddPolicies"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("Expected the changes %v to be intercepted; got %v", want, calls)
+	}
+	denyWrites = false
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 2 || lines[0].V0 == "carol" || lines[1].V0 == "carol" {
+		t.Errorf("Expected the denied changes not to be stored; got %v, %v", lines, err)
+	}
+}
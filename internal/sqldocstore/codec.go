@@ -0,0 +1,305 @@
+package sqldocstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"gocloud.dev/docstore/driver"
+)
+
+// timeFormat is the format times are stored in. Times are stored in UTC with a fixed number
+// of fractional digits, so that the stored strings sort in time order.
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+var typeOfGoTime = reflect.TypeOf(time.Time{})
+
+// encodeDoc encodes a document as a map of JSON values.
+func encodeDoc(doc driver.Document) (map[string]interface{}, error) {
+	var e encoder
+	if err := doc.Encode(&e); err != nil {
+		return nil, err
+	}
+	return e.val.(map[string]interface{}), nil
+}
+
+// encodeValue encodes a value as a JSON value.
+func encodeValue(v interface{}) (interface{}, error) {
+	var e encoder
+	if err := driver.Encode(reflect.ValueOf(v), &e); err != nil {
+		return nil, err
+	}
+	return e.val, nil
+}
+
+// encoder encodes values as the values of encoding/json: times are stored as strings in
+// timeFormat and byte slices as base64 strings.
+type encoder struct {
+	val interface{}
+}
+
+func (e *encoder) EncodeNil()            { e.val = nil }
+func (e *encoder) EncodeBool(x bool)     { e.val = x }
+func (e *encoder) EncodeInt(x int64)     { e.val = x }
+func (e *encoder) EncodeUint(x uint64)   { e.val = int64(x) }
+func (e *encoder) EncodeBytes(x []byte)  { e.val = base64.StdEncoding.EncodeToString(x) }
+func (e *encoder) EncodeFloat(x float64) { e.val = x }
+func (e *encoder) EncodeString(x string) { e.val = x }
+func (e *encoder) ListIndex(int)         { panic("impossible") }
+func (e *encoder) MapKey(string)         { panic("impossible") }
+
+func (e *encoder) EncodeSpecial(v reflect.Value) (bool, error) {
+	if v.Type() == typeOfGoTime {
+		e.val = formatTime(v.Interface().(time.Time))
+		return true, nil
+	}
+	return false, nil
+}
+
+func (e *encoder) EncodeList(n int) driver.Encoder {
+	s := make([]interface{}, n)
+	e.val = s
+	return &listEncoder{s: s}
+}
+
+func (e *encoder) EncodeMap(n int) driver.Encoder {
+	m := make(map[string]interface{}, n)
+	e.val = m
+	return &mapEncoder{m: m}
+}
+
+type listEncoder struct {
+	s []interface{}
+	encoder
+}
+
+func (e *listEncoder) ListIndex(i int) { e.s[i] = e.val }
+
+type mapEncoder struct {
+	m map[string]interface{}
+	encoder
+}
+
+func (e *mapEncoder) MapKey(k string) { e.m[k] = e.val }
+
+// formatTime formats t in timeFormat.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// unmarshalDoc decodes a stored JSON document, keeping numbers as [json.Number].
+func unmarshalDoc(data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("could not decode stored document: %w", err)
+	}
+	return m, nil
+}
+
+// decodeDoc decodes m into doc. If fps is not empty, only the fields at the field paths are
+// decoded.
+func decodeDoc(m map[string]interface{}, doc driver.Document, fps [][]string) error {
+	if len(fps) > 0 {
+		m2 := make(map[string]interface{})
+		for _, fp := range fps {
+			val, ok := getAtFieldPath(m, fp)
+			if !ok {
+				continue
+			}
+			setAtFieldPath(m2, fp, val)
+		}
+		m = m2
+	}
+	return doc.Decode(decoder{m})
+}
+
+// decoder decodes the values of encoding/json and of [encoder].
+type decoder struct {
+	val interface{}
+}
+
+func (d decoder) String() string {
+	return fmt.Sprint(d.val)
+}
+
+func (d decoder) AsNull() bool {
+	return d.val == nil
+}
+
+func (d decoder) AsBool() (bool, bool) {
+	b, ok := d.val.(bool)
+	return b, ok
+}
+
+func (d decoder) AsString() (string, bool) {
+	s, ok := d.val.(string)
+	return s, ok
+}
+
+func (d decoder) AsInt() (int64, bool) {
+	switch v := d.val.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		}
+		f, err := v.Float64()
+		return int64(f), err == nil && f == float64(int64(f))
+	}
+	return 0, false
+}
+
+func (d decoder) AsUint() (uint64, bool) {
+	if n, ok := d.val.(json.Number); ok {
+		u, err := strconv.ParseUint(n.String(), 10, 64)
+		return u, err == nil
+	}
+	i, ok := d.AsInt()
+	return uint64(i), ok && i >= 0
+}
+
+func (d decoder) AsFloat() (float64, bool) {
+	switch v := d.val.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (d decoder) AsBytes() ([]byte, bool) {
+	s, ok := d.val.(string)
+	if !ok {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	return b, err == nil
+}
+
+func (d decoder) AsInterface() (interface{}, error) {
+	return plain(d.val), nil
+}
+
+func (d decoder) ListLen() (int, bool) {
+	if s, ok := d.val.([]interface{}); ok {
+		return len(s), true
+	}
+	return 0, false
+}
+
+func (d decoder) DecodeList(f func(i int, d2 driver.Decoder) bool) {
+	for i, e := range d.val.([]interface{}) {
+		if !f(i, decoder{e}) {
+			return
+		}
+	}
+}
+
+func (d decoder) MapLen() (int, bool) {
+	if m, ok := d.val.(map[string]interface{}); ok {
+		return len(m), true
+	}
+	return 0, false
+}
+
+func (d decoder) DecodeMap(f func(key string, d2 driver.Decoder, _ bool) bool) {
+	for k, v := range d.val.(map[string]interface{}) {
+		if !f(k, decoder{v}, true) {
+			return
+		}
+	}
+}
+
+func (d decoder) AsSpecial(v reflect.Value) (bool, interface{}, error) {
+	if v.Type() != typeOfGoTime {
+		return false, nil, nil
+	}
+	switch t := d.val.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return true, parsed, err
+	case time.Time:
+		return true, t, nil
+	}
+	return true, nil, fmt.Errorf("cannot decode %v as a time", d.val)
+}
+
+// plain converts JSON numbers in v to int64 or float64, for values decoded into interfaces.
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = plain(e)
+		}
+		return s
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = plain(e)
+		}
+		return m
+	}
+	return v
+}
+
+// getAtFieldPath returns the value of m at the field path.
+func getAtFieldPath(m map[string]interface{}, fp []string) (interface{}, bool) {
+	for _, k := range fp[:len(fp)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	v, ok := m[fp[len(fp)-1]]
+	return v, ok
+}
+
+// setAtFieldPath sets the value of m at the field path, creating intermediate maps. It
+// reports false if an intermediate value is not a map.
+func setAtFieldPath(m map[string]interface{}, fp []string, val interface{}) bool {
+	for _, k := range fp[:len(fp)-1] {
+		next, ok := m[k]
+		if !ok {
+			next = make(map[string]interface{})
+			m[k] = next
+		}
+		if m, ok = next.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	m[fp[len(fp)-1]] = val
+	return true
+}
+
+// deleteAtFieldPath deletes the value of m at the field path, if any.
+func deleteAtFieldPath(m map[string]interface{}, fp []string) {
+	for _, k := range fp[:len(fp)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, fp[len(fp)-1])
+}
@@ -0,0 +1,130 @@
+package sqldocstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"gocloud.dev/docstore/driver"
+)
+
+type codecDoc struct {
+	ID      string
+	Int     int
+	Uint    uint16
+	Float   float64
+	Bool    bool
+	Bytes   []byte
+	Time    time.Time
+	Strings []string
+	Meta    map[string]string
+	Nested  nestedDoc
+	Pointer *nestedDoc
+	Any     interface{}
+	Missing string
+}
+
+type nestedDoc struct {
+	Values []int
+	Big    int64
+}
+
+// roundTrip encodes src as it is stored, and decodes it into dst at the field paths.
+func roundTrip(t *testing.T, src, dst interface{}, fps [][]string) {
+	t.Helper()
+	doc, err := driver.NewDocument(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := encodeDoc(doc)
+	if err != nil {
+		t.Fatalf("Expected encodeDoc() to be successful; got %v", err)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := unmarshalDoc(data)
+	if err != nil {
+		t.Fatalf("Expected unmarshalDoc() to be successful; got %v", err)
+	}
+	out, err := driver.NewDocument(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeDoc(stored, out, fps); err != nil {
+		t.Fatalf("Expected decodeDoc() to be successful; got %v", err)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	src := &codecDoc{
+		ID:      "p_alice_data1_read",
+		Int:     -42,
+		Uint:    65535,
+		Float:   2.5,
+		Bool:    true,
+		Bytes:   []byte{0, 1, 254, 255},
+		Time:    time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+		Strings: []string{"alice", "", "read"},
+		Meta:    map[string]string{"owner": "security"},
+		Nested:  nestedDoc{Values: []int{1, 2, 3}, Big: 1 << 62},
+		Pointer: &nestedDoc{Values: []int{4}},
+		Any:     map[string]interface{}{"n": int64(7), "f": 0.25, "list": []interface{}{"a", int64(1)}},
+	}
+	var got codecDoc
+	roundTrip(t, src, &got, nil)
+	if !reflect.DeepEqual(*src, got) {
+		t.Errorf("Expected the decoded document\n\t%+v\ngot\n\t%+v", *src, got)
+	}
+
+	// Only the fields at the field paths are decoded.
+	var partial codecDoc
+	roundTrip(t, src, &partial, [][]string{{"ID"}, {"Nested", "Big"}, {"Missing"}})
+	want := codecDoc{ID: src.ID, Nested: nestedDoc{Big: src.Nested.Big}}
+	if !reflect.DeepEqual(want, partial) {
+		t.Errorf("Expected the decoded fields\n\t%+v\ngot\n\t%+v", want, partial)
+	}
+}
+
+func TestCodecMap(t *testing.T) {
+	src := map[string]interface{}{
+		"id":    "g_alice_admin",
+		"n":     int64(3),
+		"ratio": 0.75,
+		"at":    time.Date(2024, 5, 1, 0, 0, 0, 0, time.FixedZone("", -5*60*60)),
+		"tags":  []interface{}{"a", "b"},
+	}
+	got := map[string]interface{}{}
+	roundTrip(t, src, got, nil)
+	want := map[string]interface{}{
+		"id":    "g_alice_admin",
+		"n":     int64(3),
+		"ratio": 0.75,
+		"at":    "2024-05-01T05:00:00.000000000Z", // times decode into interfaces as stored
+		"tags":  []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected the decoded document %v; got %v", want, got)
+	}
+}
+
+func TestFormatTimeOrder(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(time.Nanosecond),
+		base.Add(time.Millisecond).In(time.FixedZone("", 9*60*60)),
+		base.Add(time.Second),
+		base.Add(time.Hour).In(time.FixedZone("", -8*60*60)),
+	}
+	formatted := make([]string, len(times))
+	for i, tm := range times {
+		formatted[i] = formatTime(tm)
+	}
+	if !slices.IsSorted(formatted) {
+		t.Errorf("Expected the formatted times to sort in time order; got %v", formatted)
+	}
+}
@@ -0,0 +1,197 @@
+package sqldocstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"gocloud.dev/docstore/driver"
+	"gocloud.dev/gcerrors"
+)
+
+// RunGetQuery implements [driver.Collection].
+func (c *collection) RunGetQuery(ctx context.Context, q *driver.Query) (driver.DocumentIterator, error) {
+	query, args, err := c.selectQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if q.BeforeQuery != nil {
+		if err := q.BeforeQuery(driver.AsFunc(c.db)); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the key field in the field paths if there are any.
+	var fps [][]string
+	if len(q.FieldPaths) > 0 {
+		fps = append([][]string{{c.keyField}}, q.FieldPaths...)
+	}
+	return &docIterator{rows: rows, fieldPaths: fps}, nil
+}
+
+// QueryPlan implements [driver.Collection]. It returns the SQL of the query.
+func (c *collection) QueryPlan(q *driver.Query) (string, error) {
+	query, _, err := c.selectQuery(q)
+	return query, err
+}
+
+// selectQuery returns the SQL of the query and its parameters.
+func (c *collection) selectQuery(q *driver.Query) (string, []interface{}, error) {
+	var b strings.Builder
+	var args []interface{}
+	fmt.Fprintf(&b, "SELECT doc FROM %s", c.table)
+	for i, f := range q.Filters {
+		cond, err := c.condition(f, &args)
+		if err != nil {
+			return "", nil, err
+		}
+		if i == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(cond)
+	}
+	if q.OrderByField != "" {
+		dir := "ASC"
+		if !q.OrderAscending {
+			dir = "DESC"
+		}
+		fp := []string{q.OrderByField}
+		fmt.Fprintf(&b, " ORDER BY %s %s, %s %s", c.dialect.Value(fp, KindNumber), dir, c.dialect.Value(fp, KindString), dir)
+	}
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := int64(math.MaxInt64)
+		if q.Limit > 0 {
+			limit = int64(q.Limit)
+		}
+		fmt.Fprintf(&b, " LIMIT %d", limit)
+		if q.Offset > 0 {
+			fmt.Fprintf(&b, " OFFSET %d", q.Offset)
+		}
+	}
+	return b.String(), args, nil
+}
+
+// condition returns the SQL condition of the filter, appending its parameters to args.
+// Values of other JSON types than the filter value do not match, as with memdocstore.
+func (c *collection) condition(f driver.Filter, args *[]interface{}) (string, error) {
+	param := func(v interface{}) string {
+		*args = append(*args, v)
+		return c.dialect.Placeholder(len(*args))
+	}
+
+	switch f.Op {
+	case "in", "not-in":
+		values := reflect.ValueOf(f.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return "", errorf(gcerrors.InvalidArgument, "value of %q filter must be a slice, got %T", f.Op, f.Value)
+		}
+		if values.Len() == 0 {
+			if f.Op == "in" {
+				return "1 = 0", nil
+			}
+			return "1 = 1", nil
+		}
+		var kind Kind
+		placeholders := make([]string, values.Len())
+		for i := range placeholders {
+			k, v, err := filterValue(values.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			if i > 0 && k != kind {
+				return "", errorf(gcerrors.InvalidArgument, "values of %q filter must have the same type", f.Op)
+			}
+			kind = k
+			placeholders[i] = param(v)
+		}
+		op := "IN"
+		if f.Op == "not-in" {
+			op = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", c.dialect.Value(f.FieldPath, kind), op, strings.Join(placeholders, ", ")), nil
+	case driver.EqualOp, ">", ">=", "<", "<=":
+		kind, v, err := filterValue(f.Value)
+		if err != nil {
+			return "", err
+		}
+		if kind == KindBool && f.Op != driver.EqualOp {
+			return "", errorf(gcerrors.InvalidArgument, "booleans only support equality filters")
+		}
+		return fmt.Sprintf("%s %s %s", c.dialect.Value(f.FieldPath, kind), f.Op, param(v)), nil
+	default:
+		return "", errorf(gcerrors.InvalidArgument, "unsupported filter operator %q", f.Op)
+	}
+}
+
+// filterValue returns the kind of a filter value and the statement parameter it compares as.
+func filterValue(v interface{}) (Kind, interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return KindString, v, nil
+	case time.Time:
+		return KindString, formatTime(v), nil
+	case bool:
+		return KindBool, v, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindNumber, rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindNumber, int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return KindNumber, rv.Float(), nil
+	}
+	return 0, nil, errorf(gcerrors.InvalidArgument, "unsupported filter value %v of type %[1]T", v)
+}
+
+// docIterator iterates over the rows of a query.
+type docIterator struct {
+	rows       *sql.Rows
+	fieldPaths [][]string
+}
+
+// Next implements [driver.DocumentIterator].
+func (it *docIterator) Next(ctx context.Context, doc driver.Document) error {
+	if !it.rows.Next() {
+		if err := it.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	var data []byte
+	if err := it.rows.Scan(&data); err != nil {
+		return err
+	}
+	m, err := unmarshalDoc(data)
+	if err != nil {
+		return err
+	}
+	return decodeDoc(m, doc, it.fieldPaths)
+}
+
+// Stop implements [driver.DocumentIterator].
+func (it *docIterator) Stop() {
+	_ = it.rows.Close()
+}
+
+// As implements [driver.DocumentIterator]. It exposes the *sql.Rows of the query.
+func (it *docIterator) As(i interface{}) bool {
+	p, ok := i.(**sql.Rows)
+	if !ok {
+		return false
+	}
+	*p = it.rows
+	return true
+}
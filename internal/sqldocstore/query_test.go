@@ -0,0 +1,154 @@
+package sqldocstore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gocloud.dev/docstore/driver"
+	"gocloud.dev/gcerrors"
+)
+
+// testDialect is a dialect whose expressions show the field path and kind they select.
+type testDialect struct{}
+
+func (testDialect) CreateTable(table string) string { return "CREATE TABLE " + table }
+func (testDialect) Placeholder(n int) string        { return fmt.Sprintf("$%d", n) }
+func (testDialect) ForUpdate() string               { return "" }
+
+func (testDialect) Value(fp []string, kind Kind) string {
+	return fmt.Sprintf("%s(%s)", []string{"str", "num", "bool"}[kind], strings.Join(fp, "."))
+}
+
+func (testDialect) ErrorCode(error) gcerrors.ErrorCode { return gcerrors.Unknown }
+
+func TestSelectQuery(t *testing.T) {
+	c := &collection{dialect: testDialect{}, table: quoteTable("casbin.rule"), keyField: "id"}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", 2*60*60))
+	tests := []struct {
+		name  string
+		query driver.Query
+		want  string
+		args  []interface{}
+	}{
+		{
+			name: "all",
+			want: `SELECT doc FROM "casbin"."rule"`,
+		},
+		{
+			name: "filters",
+			query: driver.Query{Filters: []driver.Filter{
+				{FieldPath: []string{"ptype"}, Op: driver.EqualOp, Value: "p"},
+				{FieldPath: []string{"meta", "rank"}, Op: ">=", Value: uint8(2)},
+				{FieldPath: []string{"created_at"}, Op: "<", Value: at},
+				{FieldPath: []string{"annotated"}, Op: driver.EqualOp, Value: true},
+			}},
+			want: `SELECT doc FROM "casbin"."rule" WHERE str(ptype) = $1 AND num(meta.rank) >= $2 AND str(created_at) < $3 AND bool(annotated) = $4`,
+			args: []interface{}{"p", int64(2), "2024-05-01T10:00:00.000000000Z", true},
+		},
+		{
+			name: "in",
+			query: driver.Query{Filters: []driver.Filter{
+				{FieldPath: []string{"v0"}, Op: "in", Value: []string{"alice", "bob"}},
+				{FieldPath: []string{"n"}, Op: "not-in", Value: []int{3}},
+			}},
+			want: `SELECT doc FROM "casbin"."rule" WHERE str(v0) IN ($1, $2) AND num(n) NOT IN ($3)`,
+			args: []interface{}{"alice", "bob", int64(3)},
+		},
+		{
+			name: "empty in",
+			query: driver.Query{Filters: []driver.Filter{
+				{FieldPath: []string{"v0"}, Op: "in", Value: []string{}},
+				{FieldPath: []string{"v1"}, Op: "not-in", Value: []string{}},
+			}},
+			want: `SELECT doc FROM "casbin"."rule" WHERE 1 = 0 AND 1 = 1`,
+		},
+		{
+			name:  "order and limit",
+			query: driver.Query{OrderByField: "priority", OrderAscending: true, Limit: 10, Offset: 20},
+			want:  `SELECT doc FROM "casbin"."rule" ORDER BY num(priority) ASC, str(priority) ASC LIMIT 10 OFFSET 20`,
+		},
+		{
+			name:  "offset",
+			query: driver.Query{OrderByField: "id", Offset: 5},
+			want:  `SELECT doc FROM "casbin"."rule" ORDER BY num(id) DESC, str(id) DESC LIMIT 9223372036854775807 OFFSET 5`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := c.selectQuery(&tt.query)
+			if err != nil {
+				t.Fatalf("Expected selectQuery() to be successful; got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected query\n\t%s\ngot\n\t%s", tt.want, got)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("Expected args %#v; got %#v", tt.args, args)
+			}
+			if plan, err := c.QueryPlan(&tt.query); err != nil || plan != got {
+				t.Errorf("Expected QueryPlan() to return the query; got %q, %v", plan, err)
+			}
+		})
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	c := &collection{dialect: testDialect{}, table: `"t"`, keyField: "id"}
+	for _, f := range []driver.Filter{
+		{FieldPath: []string{"v0"}, Op: "in", Value: "alice"},
+		{FieldPath: []string{"v0"}, Op: "in", Value: []interface{}{"alice", 1}},
+		{FieldPath: []string{"annotated"}, Op: ">", Value: true},
+		{FieldPath: []string{"v0"}, Op: "like", Value: "a%"},
+		{FieldPath: []string{"meta"}, Op: driver.EqualOp, Value: map[string]string{}},
+	} {
+		var args []interface{}
+		_, err := c.condition(f, &args)
+		if c.ErrorCode(err) != gcerrors.InvalidArgument {
+			t.Errorf("Expected condition() to fail with InvalidArgument for %v %s %v; got %v", f.FieldPath, f.Op, f.Value, err)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	doc := map[string]interface{}{
+		"v0":    "alice",
+		"count": int64(1),
+		"ratio": 0.5,
+		"meta":  map[string]interface{}{"owner": "security"},
+	}
+	mods := []driver.Mod{
+		{FieldPath: []string{"v0"}, Value: "bob"},
+		{FieldPath: []string{"count"}, Value: driver.IncOp{Amount: 2}},
+		{FieldPath: []string{"ratio"}, Value: driver.IncOp{Amount: 1}},
+		{FieldPath: []string{"hits"}, Value: driver.IncOp{Amount: 3}},
+		{FieldPath: []string{"meta", "owner"}, Value: nil},
+		{FieldPath: []string{"meta", "ticket"}, Value: "SEC-42"},
+		{FieldPath: []string{"window", "from"}, Value: int32(7)},
+	}
+	if err := update(doc, mods); err != nil {
+		t.Fatalf("Expected update() to be successful; got %v", err)
+	}
+	want := map[string]interface{}{
+		"v0":     "bob",
+		"count":  int64(3),
+		"ratio":  1.5,
+		"hits":   int64(3),
+		"meta":   map[string]interface{}{"ticket": "SEC-42"},
+		"window": map[string]interface{}{"from": int64(7)},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Expected the updated document %v; got %v", want, doc)
+	}
+
+	for _, mod := range []driver.Mod{
+		{FieldPath: []string{"v0"}, Value: driver.IncOp{Amount: 1}},
+		{FieldPath: []string{"v0", "x"}, Value: "y"},
+	} {
+		if err := update(doc, []driver.Mod{mod}); err == nil {
+			t.Errorf("Expected update() to fail for %v", mod)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Package sqldocstore implements a docstore driver that stores documents as JSON in a table
+// of a SQL database, for the SQL drivers of the adapter. The table has a text primary key
+// column "key", holding the key field of the documents, and a JSON column "doc" holding the
+// documents. The SQL specific to a database is provided by a [Dialect].
+package sqldocstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/docstore/driver"
+	"gocloud.dev/gcerrors"
+)
+
+// Kind is the JSON type of a value compared in SQL.
+type Kind int
+
+const (
+	KindString Kind = iota // JSON strings, including times
+	KindNumber             // JSON numbers
+	KindBool               // JSON booleans
+)
+
+// Dialect is the SQL of a database.
+type Dialect interface {
+	// CreateTable returns the statement that creates the table if it does not exist, with a
+	// text primary key column "key" and a JSON column "doc".
+	CreateTable(table string) string
+	// Placeholder returns the placeholder of the n-th statement parameter, counting from 1.
+	Placeholder(n int) string
+	// Value returns the expression of the value at the field path of the "doc" column if it
+	// has the JSON type of kind, and NULL otherwise. Strings must compare in byte order.
+	Value(fp []string, kind Kind) string
+	// ForUpdate returns the clause that locks the rows of a select for the transaction, or
+	// "" if the database serializes write transactions.
+	ForUpdate() string
+	// ErrorCode returns the code of an error of the database.
+	ErrorCode(err error) gcerrors.ErrorCode
+}
+
+// Options configure a collection.
+type Options struct {
+	// RevisionField is the name of the field holding the document revision (defaults to
+	// docstore.DefaultRevisionField).
+	RevisionField string
+	// OnClose is called when the collection is closed, e.g. to close the database.
+	OnClose func() error
+}
+
+type collection struct {
+	db       *sql.DB
+	dialect  Dialect
+	table    string // the quoted table name
+	keyField string
+	opts     Options
+}
+
+// OpenCollection opens the collection stored in the table of the database, creating the
+// table if it does not exist. Documents are keyed by the string field keyField.
+func OpenCollection(ctx context.Context, db *sql.DB, dialect Dialect, table, keyField string, opts *Options) (*docstore.Collection, error) {
+	if table == "" || keyField == "" {
+		return nil, errors.New("the table and key field are required")
+	}
+	c := &collection{db: db, dialect: dialect, table: quoteTable(table), keyField: keyField}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.RevisionField == "" {
+		c.opts.RevisionField = docstore.DefaultRevisionField
+	}
+	if _, err := db.ExecContext(ctx, dialect.CreateTable(c.table)); err != nil {
+		return nil, fmt.Errorf("could not create table %s: %w", table, err)
+	}
+	return docstore.NewCollection(c), nil
+}
+
+// quoteTable quotes the parts of a table name that may be qualified by a schema.
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// codeError is an error with a docstore error code.
+type codeError struct {
+	code gcerrors.ErrorCode
+	msg  string
+}
+
+func (e *codeError) Error() string { return e.msg }
+
+func errorf(code gcerrors.ErrorCode, format string, args ...interface{}) error {
+	return &codeError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Key implements [driver.Collection].
+func (c *collection) Key(doc driver.Document) (interface{}, error) {
+	key, _ := doc.GetField(c.keyField) // no error on missing key, and it will be nil
+	if key == nil || driver.IsEmptyValue(reflect.ValueOf(key)) {
+		return nil, nil
+	}
+	s, ok := key.(string)
+	if !ok {
+		return nil, errorf(gcerrors.InvalidArgument, "key field %q must be a string, got %T", c.keyField, key)
+	}
+	return s, nil
+}
+
+// RevisionField implements [driver.Collection].
+func (c *collection) RevisionField() string {
+	return c.opts.RevisionField
+}
+
+// RunActions implements [driver.Collection]. The actions are run one at a time, the writes
+// each in a transaction.
+func (c *collection) RunActions(ctx context.Context, actions []*driver.Action, opts *driver.RunActionsOptions) driver.ActionListError {
+	errs := make([]error, len(actions))
+	if opts.BeforeDo != nil {
+		if err := opts.BeforeDo(driver.AsFunc(c.db)); err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+			return driver.NewActionListError(errs)
+		}
+	}
+
+	beforeGets, gets, writes, afterGets := driver.GroupActions(actions)
+	for _, group := range [][]*driver.Action{beforeGets, gets, writes, afterGets} {
+		for _, a := range group {
+			if a.Kind == driver.Get {
+				errs[a.Index] = c.get(ctx, a)
+			} else {
+				errs[a.Index] = c.write(ctx, a)
+			}
+		}
+	}
+	return driver.NewActionListError(errs)
+}
+
+// get reads the document of the action.
+func (c *collection) get(ctx context.Context, a *driver.Action) error {
+	var data []byte
+	query := fmt.Sprintf("SELECT doc FROM %s WHERE key = %s", c.table, c.dialect.Placeholder(1))
+	err := c.db.QueryRowContext(ctx, query, a.Key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return errorf(gcerrors.NotFound, "document with key %v does not exist", a.Key)
+	} else if err != nil {
+		return err
+	}
+	m, err := unmarshalDoc(data)
+	if err != nil {
+		return err
+	}
+	return decodeDoc(m, a.Doc, a.FieldPaths)
+}
+
+// write runs a write action in a transaction.
+func (c *collection) write(ctx context.Context, a *driver.Action) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // the transaction is committed on success
+
+	if a.Kind == driver.Create {
+		if err := c.create(ctx, tx, a); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	current, err := c.current(ctx, tx, a.Key)
+	if err != nil {
+		return err
+	}
+	if current == nil && (a.Kind == driver.Replace || a.Kind == driver.Update) {
+		return errorf(gcerrors.NotFound, "document with key %v does not exist", a.Key)
+	}
+	if err := c.checkRevision(a.Doc, current); err != nil {
+		return err
+	}
+
+	switch a.Kind {
+	case driver.Replace, driver.Put:
+		doc, err := encodeDoc(a.Doc)
+		if err != nil {
+			return err
+		}
+		if err := c.setRevision(a.Doc, doc); err != nil {
+			return err
+		}
+		err = c.put(ctx, tx, a.Key, doc)
+		if err != nil {
+			return err
+		}
+	case driver.Delete:
+		query := fmt.Sprintf("DELETE FROM %s WHERE key = %s", c.table, c.dialect.Placeholder(1))
+		if _, err := tx.ExecContext(ctx, query, a.Key); err != nil {
+			return err
+		}
+	case driver.Update:
+		if err := update(current, a.Mods); err != nil {
+			return err
+		}
+		if err := c.setRevision(a.Doc, current); err != nil {
+			return err
+		}
+		if err := c.put(ctx, tx, a.Key, current); err != nil {
+			return err
+		}
+	default:
+		return errorf(gcerrors.Internal, "unknown kind %v", a.Kind)
+	}
+	return tx.Commit()
+}
+
+// create inserts the document of a create action, assigning it a key if it has none.
+func (c *collection) create(ctx context.Context, tx *sql.Tx, a *driver.Action) error {
+	if a.Key == nil {
+		a.Key = driver.UniqueString()
+		if err := a.Doc.SetField(c.keyField, a.Key); err != nil {
+			return errorf(gcerrors.InvalidArgument, "cannot set key field %q", c.keyField)
+		}
+	}
+	doc, err := encodeDoc(a.Doc)
+	if err != nil {
+		return err
+	}
+	if err := c.setRevision(a.Doc, doc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (key, doc) VALUES (%s, %s)", c.table, c.dialect.Placeholder(1), c.dialect.Placeholder(2))
+	_, err = tx.ExecContext(ctx, query, a.Key, string(data))
+	if err != nil && c.dialect.ErrorCode(err) == gcerrors.AlreadyExists {
+		return errorf(gcerrors.AlreadyExists, "document with key %v exists", a.Key)
+	}
+	return err
+}
+
+// current reads and locks the stored document with the key, or returns nil if there is none.
+func (c *collection) current(ctx context.Context, tx *sql.Tx, key interface{}) (map[string]interface{}, error) {
+	var data []byte
+	query := fmt.Sprintf("SELECT doc FROM %s WHERE key = %s %s", c.table, c.dialect.Placeholder(1), c.dialect.ForUpdate())
+	err := tx.QueryRowContext(ctx, query, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return unmarshalDoc(data)
+}
+
+// put stores the document with the key.
+func (c *collection) put(ctx context.Context, tx *sql.Tx, key interface{}, doc map[string]interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (key, doc) VALUES (%s, %s) ON CONFLICT (key) DO UPDATE SET doc = excluded.doc",
+		c.table, c.dialect.Placeholder(1), c.dialect.Placeholder(2))
+	_, err = tx.ExecContext(ctx, query, key, string(data))
+	return err
+}
+
+// setRevision assigns a new revision to the stored document if the document has a revision
+// field, and sets it in the document.
+func (c *collection) setRevision(doc driver.Document, stored map[string]interface{}) error {
+	if !doc.HasField(c.opts.RevisionField) {
+		return nil
+	}
+	rev := driver.UniqueString()
+	stored[c.opts.RevisionField] = rev
+	return doc.SetField(c.opts.RevisionField, rev)
+}
+
+// checkRevision fails if the document has a revision that differs from that of the stored
+// document.
+func (c *collection) checkRevision(doc driver.Document, current map[string]interface{}) error {
+	if current == nil {
+		return nil
+	}
+	curRev, ok := current[c.opts.RevisionField]
+	if !ok {
+		return nil
+	}
+	rev, err := doc.GetField(c.opts.RevisionField)
+	if err != nil || rev == nil {
+		return nil
+	}
+	if fmt.Sprint(rev) != fmt.Sprint(curRev) {
+		return errorf(gcerrors.FailedPrecondition, "mismatched revisions: want %v, current %v", rev, curRev)
+	}
+	return nil
+}
+
+// update applies the modifications to the stored document.
+func update(doc map[string]interface{}, mods []driver.Mod) error {
+	for _, mod := range mods {
+		if mod.Value == nil {
+			deleteAtFieldPath(doc, mod.FieldPath)
+			continue
+		}
+		var val interface{}
+		if inc, ok := mod.Value.(driver.IncOp); ok {
+			cur, _ := getAtFieldPath(doc, mod.FieldPath)
+			sum, err := add(cur, inc.Amount)
+			if err != nil {
+				return err
+			}
+			val = sum
+		} else {
+			var err error
+			if val, err = encodeValue(mod.Value); err != nil {
+				return err
+			}
+		}
+		if !setAtFieldPath(doc, mod.FieldPath, val) {
+			return errorf(gcerrors.InvalidArgument, "field path %q is not in a map", strings.Join(mod.FieldPath, "."))
+		}
+	}
+	return nil
+}
+
+// add returns the sum of the stored number x, which may be missing, and the amount y.
+func add(x, y interface{}) (interface{}, error) {
+	amount, err := encodeValue(y)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil {
+		return amount, nil
+	}
+	xi, xIsInt := decoder{x}.AsInt()
+	yi, yIsInt := decoder{amount}.AsInt()
+	if xIsInt && yIsInt {
+		return xi + yi, nil
+	}
+	xf, ok := decoder{x}.AsFloat()
+	if !ok {
+		return nil, errorf(gcerrors.InvalidArgument, "cannot increment non-numeric value %v", x)
+	}
+	yf, ok := decoder{amount}.AsFloat()
+	if !ok {
+		return nil, errorf(gcerrors.InvalidArgument, "cannot increment by non-numeric value %v", y)
+	}
+	return xf + yf, nil
+}
+
+// RevisionToBytes implements [driver.Collection].
+func (c *collection) RevisionToBytes(rev interface{}) ([]byte, error) {
+	s, ok := rev.(string)
+	if !ok {
+		return nil, errorf(gcerrors.InvalidArgument, "revision %v of type %[1]T is not a string", rev)
+	}
+	return []byte(s), nil
+}
+
+// BytesToRevision implements [driver.Collection].
+func (c *collection) BytesToRevision(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+// As implements [driver.Collection]. It exposes the *sql.DB of the collection.
+func (c *collection) As(i interface{}) bool {
+	p, ok := i.(**sql.DB)
+	if !ok {
+		return false
+	}
+	*p = c.db
+	return true
+}
+
+// ErrorAs implements [driver.Collection].
+func (c *collection) ErrorAs(err error, i interface{}) bool {
+	return errors.As(err, i)
+}
+
+// ErrorCode implements [driver.Collection].
+func (c *collection) ErrorCode(err error) gcerrors.ErrorCode {
+	var cerr *codeError
+	switch {
+	case errors.As(err, &cerr):
+		return cerr.code
+	case errors.Is(err, context.Canceled):
+		return gcerrors.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return gcerrors.DeadlineExceeded
+	case errors.Is(err, sql.ErrConnDone), errors.Is(err, sql.ErrTxDone):
+		return gcerrors.FailedPrecondition
+	}
+	return c.dialect.ErrorCode(err)
+}
+
+// Close implements [driver.Collection].
+func (c *collection) Close() error {
+	if c.opts.OnClose != nil {
+		return c.opts.OnClose()
+	}
+	return nil
+}
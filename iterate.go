@@ -0,0 +1,18 @@
+package adapter
+
+import (
+	"context"
+
+	"gocloud.dev/docstore"
+)
+
+// nextDoc reads the next document of the iterator into dst, like iter.Next, but returns the
+// error of ctx as soon as it is done. Some providers serve documents from pages or results
+// they already fetched without checking ctx, so a cancelled read would otherwise run to the
+// end of the query; callers stop the iterator on the error, releasing the provider cursor.
+func nextDoc(ctx context.Context, iter *docstore.DocumentIterator, dst docstore.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return iter.Next(ctx, dst)
+}
@@ -0,0 +1,47 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestLoadCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the load once it has loaded its first rule.
+	loadCtx, cancelLoad := context.WithCancel(ctx)
+	defer cancelLoad()
+	var last LoadProgress
+	a, err := New(ctx, "mem://casbin_rule_load_cancellation/id", WithLoadProgress(time.Nanosecond, func(p LoadProgress) {
+		last = p
+		cancelLoad()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	rules := make([][]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadPolicyCtx(loadCtx, m); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the load to stop with context.Canceled; got %v", err)
+	}
+	if !last.Done || last.Rules != 1 {
+		t.Errorf("Expected the load to stop after its first rule; got %+v", last)
+	}
+}
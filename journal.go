@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JournalEntry is a change buffered with Config.WriteBehind, journaled in the collection of
+// Config.JournalURL until it is written.
+type JournalEntry struct {
+	ID        string    `docstore:"id"`
+	Event     string    `docstore:"event"`           // the JSON-encoded ChangeEvent of the change
+	Attrs     string    `docstore:"attrs,omitempty"` // the JSON-encoded attributes of an added rule, e.g. its metadata
+	CreatedAt time.Time `docstore:"created_at"`
+	Namespace string    `docstore:"ns,omitempty"`
+}
+
+// journalChange journals a change before it is buffered, and returns the ID of the entry.
+func (a *adapter) journalChange(ctx context.Context, event ChangeEvent, attrs ruleAttrs, at time.Time) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	var attrsData []byte
+	if len(attrs.Meta) > 0 || attrs.EffectiveFrom != 0 || attrs.ExpiresAt != 0 {
+		if attrsData, err = json.Marshal(attrs); err != nil {
+			return "", err
+		}
+	}
+	// IDs sort by creation time, so changes are replayed in order.
+	entry := JournalEntry{
+		ID:        a.namespacedID(fmt.Sprintf("%020d_%s", at.UnixNano(), randomID())),
+		Event:     string(data),
+		Attrs:     string(attrsData),
+		CreatedAt: at.UTC(),
+		Namespace: a.config.Namespace,
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	if err := a.journal.Create(ctx, &entry); err != nil {
+		return "", fmt.Errorf("could not journal change: %w", a.redact(err))
+	}
+	return entry.ID, nil
+}
+
+// deleteJournaled deletes the journal entries of written changes. The changes are already
+// written, so failures are logged; entries left behind are replayed again, which is harmless.
+func (a *adapter) deleteJournaled(ctx context.Context, ids []string) {
+	if a.journal == nil || len(ids) == 0 {
+		return
+	}
+	actions := a.journal.Actions()
+	for _, id := range ids {
+		actions.Delete(&JournalEntry{ID: id})
+	}
+	if err := actions.Do(ctx); err != nil {
+		a.config.logger().Printf("delete journal entries error: %v", a.redact(err))
+	}
+}
+
+// replayJournal buffers the journaled changes left by a previous process, oldest first,
+// and writes them.
+func (a *adapter) replayJournal(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, a.timeout())
+	iter := a.scopeOther(a.journal.Query()).Get(queryCtx)
+	var entries []JournalEntry
+	for {
+		var entry JournalEntry
+		err := nextDoc(queryCtx, iter, &entry)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			iter.Stop()
+			cancel()
+			return err
+		}
+		if entry.Namespace == a.config.Namespace {
+			entries = append(entries, entry)
+		}
+	}
+	iter.Stop()
+	cancel()
+	slices.SortFunc(entries, func(x, y JournalEntry) int { return strings.Compare(x.ID, y.ID) })
+
+	for _, entry := range entries {
+		var event ChangeEvent
+		if err := json.Unmarshal([]byte(entry.Event), &event); err != nil {
+			return fmt.Errorf("could not decode journal entry %s: %w", entry.ID, err)
+		}
+		var attrs ruleAttrs
+		if entry.Attrs != "" {
+			if err := json.Unmarshal([]byte(entry.Attrs), &attrs); err != nil {
+				return fmt.Errorf("could not decode journal entry %s: %w", entry.ID, err)
+			}
+		}
+		// The change may have been written before the journal entry was deleted.
+		a.bufferEvent(event, attrs, entry.CreatedAt, entry.ID, false)
+	}
+
+	return a.flush(ctx)
+}
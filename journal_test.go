@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	config := func() *Config {
+		return &Config{
+			URL:         "sqlitedoc://" + filepath.Join(dir, "policy.db"),
+			WriteBehind: time.Hour,
+			JournalURL:  "sqlitedoc://" + filepath.Join(dir, "journal.db") + "?table=journal",
+		}
+	}
+
+	a, err := NewWithOption(ctx, config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	// The process crashes before the buffered changes are written.
+	a.close()
+
+	a, err = NewWithOption(ctx, config())
+	if err != nil {
+		t.Fatalf("Expected the journal to be replayed; got %v", err)
+	}
+	defer a.close()
+	lines, err := a.collectAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].V0 != "alice" {
+		t.Errorf("Expected the journaled changes to be written; got %v", lines)
+	}
+	iter := a.journal.Query().Get(ctx)
+	defer iter.Stop()
+	var entry JournalEntry
+	if err := iter.Next(ctx, &entry); err != io.EOF {
+		t.Errorf("Expected the journal to be empty after the replay; got %v, %v", entry, err)
+	}
+}
+
+func TestJournalRequiresWriteBehind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_journal/id", JournalURL: "mem://journal/id"}); err == nil {
+		t.Error("Expected NewWithOption() to fail without write-behind")
+	}
+}
@@ -0,0 +1,103 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// LintKind is the kind of problem reported by Lint.
+type LintKind int
+
+const (
+	// LintDuplicate reports a rule that is stored in more than one document.
+	LintDuplicate LintKind = iota
+	// LintIDMismatch reports a rule whose ID does not match its content hash.
+	LintIDMismatch
+	// LintInvalidPType reports a rule whose policy type is not defined in the model.
+	LintInvalidPType
+)
+
+// String returns the name of the kind.
+func (k LintKind) String() string {
+	switch k {
+	case LintDuplicate:
+		return "duplicate"
+	case LintIDMismatch:
+		return "id-mismatch"
+	case LintInvalidPType:
+		return "invalid-ptype"
+	default:
+		return fmt.Sprintf("LintKind(%d)", int(k))
+	}
+}
+
+// LintFinding is a problem with a stored rule reported by Lint.
+type LintFinding struct {
+	Kind    LintKind // the kind of problem
+	ID      string   // the ID of the document
+	PType   string   // the policy type of the rule
+	Rule    []string // the rule values
+	Message string   // a human readable description of the problem
+}
+
+// Lint scans the stored rules for problems: rules stored in more than one document, rules
+// whose ID does not match their content hash (with content-derived IDs), and, if model is not
+// nil, rules whose policy type is not defined in the model. The findings are sorted by rule.
+func (a *adapter) Lint(ctx context.Context, model model.Model) ([]LintFinding, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	lines, err := a.collectAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(lines, compareRules)
+
+	findings := make([]LintFinding, 0)
+	add := func(kind LintKind, line CasbinRule, format string, args ...any) {
+		findings = append(findings, LintFinding{
+			Kind:    kind,
+			ID:      line.ID,
+			PType:   line.PType,
+			Rule:    line.values(),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	first := make(map[string]string, len(lines)) // the ID of the first document of each rule
+	for _, line := range lines {
+		key := ruleKey(line)
+		if id, ok := first[key]; ok {
+			add(LintDuplicate, line, "rule is also stored in document %q", id)
+		} else {
+			first[key] = line.ID
+		}
+
+		if a.contentIDs() {
+			// Legacy IDs are still recognized with IDStrategyCanonical.
+			if want := a.contentID(line); line.ID != want && line.ID != generateID(line) {
+				add(LintIDMismatch, line, "ID does not match the content hash %q", want)
+			}
+		}
+
+		if model != nil {
+			sec := line.Sec
+			if sec == "" {
+				sec = line.PType[:min(1, len(line.PType))]
+			}
+			if _, ok := model[sec][line.PType]; !ok {
+				add(LintInvalidPType, line, "policy type %q is not defined in section %q of the model", line.PType, sec)
+			}
+		}
+	}
+
+	return findings, nil
+}
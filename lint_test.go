@@ -0,0 +1,65 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestLint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_lint/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	findings, err := a.Lint(ctx, m)
+	if err != nil {
+		t.Fatalf("Expected Lint() to be successful; got %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings for a clean policy; got %v", findings)
+	}
+
+	// Out-of-band writes: a copy under a foreign ID, and a rule with an unknown policy type.
+	dup := CasbinRule{PType: "p", V0: "alice", V1: "data1", V2: "read", ID: "copy"}
+	if err := a.collection.Put(ctx, &dup); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p2", []string{"bob", "write"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	findings, err = a.Lint(ctx, m)
+	if err != nil {
+		t.Fatalf("Expected Lint() to be successful; got %v", err)
+	}
+	kinds := make(map[LintKind]int)
+	for _, f := range findings {
+		kinds[f.Kind]++
+	}
+	if kinds[LintDuplicate] != 1 || kinds[LintIDMismatch] != 1 || kinds[LintInvalidPType] != 1 {
+		t.Errorf("Expected one finding of each kind; got %v", findings)
+	}
+
+	findings, err = a.Lint(ctx, nil)
+	if err != nil {
+		t.Fatalf("Expected Lint() to be successful; got %v", err)
+	}
+	for _, f := range findings {
+		if f.Kind == LintInvalidPType {
+			t.Errorf("Expected no policy type findings without a model; got %v", f)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package adapter
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned by ListRules for cursors it did not return.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListRules returns a page of up to pageSize stored rules following the cursor, and the
+// cursor of the next page, for admin UIs over large collections. An empty cursor starts at
+// the first rule; the next cursor is empty after the last page.
+//
+// Rules are ordered by collection (the rule collection, its shards, then the grouping
+// collection), then by ID, so pages are deterministic and stable under concurrent changes:
+// a rule added or removed after its position was listed does not shift the following pages.
+// Like FindRules, the documents are returned as stored. Each page gets the adapter timeout.
+//
+// Paging by ID may require an index on some providers, e.g. a DynamoDB table whose sort key
+// is the ID.
+func (a *adapter) ListRules(ctx context.Context, pageSize int, cursor string) ([]CasbinRule, string, error) {
+	var rules []CasbinRule
+	var next string
+	err := a.intercept(ctx, OpInfo{Name: "ListRules"}, func(ctx context.Context) error {
+		var err error
+		rules, next, err = a.listRules(ctx, pageSize, cursor)
+		return err
+	})
+	return rules, next, err
+}
+
+// listRules runs ListRules within the interceptors.
+func (a *adapter) listRules(ctx context.Context, pageSize int, cursor string) ([]CasbinRule, string, error) {
+	if pageSize <= 0 {
+		return nil, "", errors.New("the page size must be positive")
+	}
+	coll, last, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := a.begin(); err != nil {
+		return nil, "", err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	colls := a.ruleCollections()
+	if coll >= len(colls) {
+		return nil, "", ErrInvalidCursor
+	}
+	rules := make([]CasbinRule, 0, pageSize)
+	for coll < len(colls) {
+		// Pages include internal documents, which are skipped, so a page may take several reads.
+		limit := pageSize - len(rules)
+		lines, err := a.pageRules(ctx, colls[coll], last, limit)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, line := range lines {
+			if a.isRule(&line) {
+				rules = append(rules, line)
+			}
+		}
+		if len(lines) < limit {
+			coll, last = coll+1, ""
+			continue
+		}
+		last = lines[len(lines)-1].ID
+		if len(rules) == pageSize {
+			return rules, encodeCursor(coll, last), nil
+		}
+	}
+	return rules, "", nil
+}
+
+// encodeCursor returns the cursor of the page following the document with the ID last of
+// the rule collection with the index coll.
+func encodeCursor(coll int, last string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(coll) + ":" + last))
+}
+
+// decodeCursor returns the collection index and the last ID of the cursor.
+func decodeCursor(cursor string) (coll int, last string, err error) {
+	if cursor == "" {
+		return 0, "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	index, last, ok := strings.Cut(string(b), ":")
+	if coll, err = strconv.Atoi(index); !ok || err != nil || coll < 0 || last == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	return coll, last, nil
+}
@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestListRules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Outbox events are internal documents stored with the rules, which pages skip.
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_list/id",
+		GroupingURL: "mem://casbin_rule_list_grouping/id",
+		Outbox:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	for i := 0; i < 5; i++ {
+		if err := a.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data1", "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.AddPolicies("g", "g", [][]string{{"user0", "admin"}, {"user1", "admin"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var listed []CasbinRule
+	var pages int
+	cursor := ""
+	for {
+		rules, next, err := a.ListRules(ctx, 3, cursor)
+		if err != nil {
+			t.Fatalf("Expected ListRules() to be successful; got %v", err)
+		}
+		if len(rules) > 3 {
+			t.Fatalf("Expected at most 3 rules per page; got %d", len(rules))
+		}
+		listed = append(listed, rules...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(listed) != 7 || pages != 3 {
+		t.Fatalf("Expected 7 rules in 3 pages; got %d rules in %d pages", len(listed), pages)
+	}
+	seen := make(map[string]bool)
+	for i, line := range listed {
+		if line.isInternal() || seen[line.ID] {
+			t.Errorf("Expected each rule once, and no internal documents; got %+v", line)
+		}
+		seen[line.ID] = true
+		if i > 0 && listed[i-1].PType == line.PType && listed[i-1].ID > line.ID {
+			t.Errorf("Expected the rules of each collection to be ordered by ID; got %v", listed)
+		}
+	}
+	if listed[0].PType != "p" || listed[6].PType != "g" {
+		t.Errorf("Expected the grouping rules last; got %v", listed)
+	}
+
+	if _, _, err := a.ListRules(ctx, 3, "not a cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor; got %v", err)
+	}
+}
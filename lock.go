@@ -0,0 +1,225 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gocloud.dev/gcerrors"
+)
+
+const (
+	defaultLockTTL    time.Duration = 30 * time.Second
+	lockRetryInterval time.Duration = 100 * time.Millisecond
+	savePolicyLockID  string        = "save_policy"
+	// lockPType is the policy type of lease documents, which are stored alongside the rules
+	// and are never loaded as rules.
+	lockPType = "__lock__"
+)
+
+// errLockLost is the cause of the cancellation of an operation whose lock could not be
+// renewed before it expired.
+var errLockLost = errors.New("lock was lost")
+
+// locker is a distributed lock built on lease documents stored alongside the rules: V0
+// holds the lock ID and V1 the owner. A lease is taken by creating its document, or by
+// replacing it once it has expired, and renewed by replacing it; every replacement is
+// conditional on the revision that was read or last written, so that it fails with
+// FailedPrecondition for all but one of the instances racing for the lease.
+type locker struct {
+	adapter *adapter
+	owner   string
+	ttl     time.Duration
+	held    chan struct{} // holds a value while a save of this instance holds the lock, which shares its owner
+}
+
+// newLocker returns a locker of the adapter, whose leases expire after ttl unless renewed.
+func newLocker(a *adapter, ttl time.Duration) *locker {
+	return &locker{adapter: a, owner: randomID(), ttl: ttl, held: make(chan struct{}, 1)}
+}
+
+// isLease reports whether the document is the lease of a lock.
+func (c *CasbinRule) isLease() bool {
+	return c.PType == lockPType
+}
+
+// lease returns the lease document of the lock with the given id, or nil if there is none.
+func (l *locker) lease(ctx context.Context, id string) (*CasbinRule, error) {
+	a := l.adapter
+	lease := &CasbinRule{PType: lockPType, ID: a.namespacedID(lockPType + "_" + id)}
+	switch err := a.get(ctx, a.collection, lease); gcerrors.Code(err) {
+	case gcerrors.OK:
+		return lease, nil
+	case gcerrors.NotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// write creates the lease document if create is set, and otherwise replaces it if its
+// revision is that of lease. It returns errLockLost if another instance wrote or removed
+// the lease first.
+func (l *locker) write(ctx context.Context, lease *CasbinRule, create bool) error {
+	a := l.adapter
+	lease.V1 = l.owner
+	lease.ExpiresAt = time.Now().Add(l.ttl).UnixMilli()
+	doc, err := a.document(lease)
+	if err != nil {
+		return err
+	}
+	if create {
+		err = a.collection.Create(ctx, doc)
+	} else {
+		err = a.collection.Replace(ctx, doc)
+	}
+	switch gcerrors.Code(err) {
+	case gcerrors.OK:
+		return a.decode(doc, lease)
+	case gcerrors.AlreadyExists, gcerrors.FailedPrecondition, gcerrors.NotFound:
+		return errLockLost
+	default:
+		return err
+	}
+}
+
+// acquire blocks until the lock with the given id is held by this instance, or ctx is
+// done. It returns the lease document, which is passed to renew and release.
+func (l *locker) acquire(ctx context.Context, id string) (*CasbinRule, error) {
+	for {
+		held, err := l.tryAcquire(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("could not acquire lock %q: %w", id, err)
+		}
+		if held != nil {
+			return held, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not acquire lock %q: %w", id, ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// tryAcquire makes a single attempt to take the lock, returning nil if it is held, or was
+// taken first, by another instance.
+func (l *locker) tryAcquire(ctx context.Context, id string) (*CasbinRule, error) {
+	a := l.adapter
+	lease, err := l.lease(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	create := lease == nil
+	if create {
+		lease = &CasbinRule{PType: lockPType, ID: a.namespacedID(lockPType + "_" + id), V0: id, Namespace: a.config.Namespace}
+	} else if time.Now().UnixMilli() < lease.ExpiresAt {
+		return nil, nil
+	}
+	if err := l.write(ctx, lease, create); err != nil {
+		if errors.Is(err, errLockLost) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return lease, nil
+}
+
+// renew extends the lease, failing with errLockLost if it was taken over by another
+// instance after it expired.
+func (l *locker) renew(ctx context.Context, held *CasbinRule) error {
+	return l.write(ctx, held, false)
+}
+
+// release gives up the lease. A lease that was already taken over or removed by another
+// instance is left as is.
+func (l *locker) release(ctx context.Context, held *CasbinRule) error {
+	doc, err := l.adapter.document(held)
+	if err != nil {
+		return err
+	}
+	switch err := l.adapter.collection.Delete(ctx, doc); gcerrors.Code(err) {
+	case gcerrors.OK, gcerrors.NotFound, gcerrors.FailedPrecondition:
+		return nil
+	default:
+		return err
+	}
+}
+
+// acquireSaveLock takes the lock that allows only one instance to perform a full save at
+// a time, if locking is configured, waiting at most wait for it if wait is positive. The
+// lease is renewed every third of Config.LockTTL until the returned function releases it;
+// if it cannot be renewed, the returned context, which the save must run with, is
+// cancelled with errLockLost as its cause.
+func (a *adapter) acquireSaveLock(ctx context.Context, wait time.Duration) (context.Context, func(), error) {
+	if a.lock == nil {
+		return ctx, func() {}, nil
+	}
+	waitCtx, waitCancel := ctx, context.CancelFunc(func() {})
+	if wait > 0 {
+		waitCtx, waitCancel = context.WithTimeout(ctx, wait)
+	}
+	defer waitCancel()
+	// The saves of this instance share its owner, so they are serialized before the lease is
+	// taken.
+	select {
+	case a.lock.held <- struct{}{}:
+	case <-waitCtx.Done():
+		return nil, nil, fmt.Errorf("could not acquire lock %q: %w", savePolicyLockID, waitCtx.Err())
+	}
+	held, err := a.lock.acquire(waitCtx, savePolicyLockID)
+	if err != nil {
+		<-a.lock.held
+		return nil, nil, err
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(a.lock.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-lockCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := a.lock.renew(lockCtx, held); err != nil {
+				if !errors.Is(err, errLockLost) {
+					err = fmt.Errorf("%w: %w", errLockLost, err)
+				}
+				cancel(fmt.Errorf("could not renew lock %q: %w", savePolicyLockID, err))
+				return
+			}
+		}
+	}()
+
+	return lockCtx, func() {
+		close(stop)
+		wg.Wait()
+		cancel(nil)
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), a.timeout())
+		defer releaseCancel()
+		if err := a.lock.release(releaseCtx, held); err != nil {
+			a.config.logger().Printf("release lock error: %v", a.redact(err))
+		}
+		<-a.lock.held
+	}, nil
+}
+
+// lockError returns the cause of the cancellation of ctx, a context returned by
+// acquireSaveLock, instead of err if the operation failed because the lock was lost.
+func lockError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, errLockLost) {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLocker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_locker/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	first := newLocker(a, time.Minute)
+	second := newLocker(a, 50*time.Millisecond)
+
+	held, err := first.acquire(ctx, savePolicyLockID)
+	if err != nil {
+		t.Fatalf("Expected acquire() to be successful; got %v", err)
+	}
+	if lease, err := first.tryAcquire(ctx, savePolicyLockID); err != nil || lease != nil {
+		t.Fatalf("Expected tryAcquire() of the same owner to fail while the lock is held; got %v, %v", lease, err)
+	}
+	if lease, err := second.tryAcquire(ctx, savePolicyLockID); err != nil || lease != nil {
+		t.Fatalf("Expected tryAcquire() to fail while the lock is held; got %v, %v", lease, err)
+	}
+	if err := first.release(ctx, held); err != nil {
+		t.Fatalf("Expected release() to be successful; got %v", err)
+	}
+
+	// An expired lease can be taken over by another instance, after which the previous
+	// holder can no longer renew or release it.
+	stale, err := second.acquire(ctx, savePolicyLockID)
+	if err != nil {
+		t.Fatalf("Expected acquire() to be successful; got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if held, err = first.tryAcquire(ctx, savePolicyLockID); err != nil || held == nil {
+		t.Fatalf("Expected tryAcquire() to take over an expired lock; got %v, %v", held, err)
+	}
+	if err := second.renew(ctx, stale); !errors.Is(err, errLockLost) {
+		t.Errorf("Expected renew() of a lease that was taken over to fail with errLockLost; got %v", err)
+	}
+	if err := second.release(ctx, stale); err != nil {
+		t.Errorf("Expected release() of a lease that was taken over to be successful; got %v", err)
+	}
+	if err := first.renew(ctx, held); err != nil {
+		t.Errorf("Expected renew() to be successful; got %v", err)
+	}
+	if lease, err := first.lease(ctx, savePolicyLockID); err != nil || lease == nil || lease.V1 != first.owner {
+		t.Errorf("Expected the lease to be held by the instance that took it over; got %v, %v", lease, err)
+	}
+
+	// Of the instances racing for an expired lease, only one takes it.
+	if err := first.release(ctx, held); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.acquire(ctx, savePolicyLockID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	var (
+		wg    sync.WaitGroup
+		taken atomic.Int32
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lease, err := newLocker(a, time.Minute).tryAcquire(ctx, savePolicyLockID); err != nil {
+				t.Errorf("Expected tryAcquire() to be successful; got %v", err)
+			} else if lease != nil {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := taken.Load(); n != 1 {
+		t.Errorf("Expected a single instance to take over the expired lock; got %d", n)
+	}
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer timeoutCancel()
+	if _, err := second.acquire(timeoutCtx, savePolicyLockID); err == nil {
+		t.Error("Expected acquire() to fail when the context is done")
+	}
+}
+
+func TestSavePolicyLock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:     "mem://casbin_rule_lock/id",
+		Lock:    true,
+		LockTTL: 30 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if held, err := a.lock.tryAcquire(ctx, savePolicyLockID); err != nil || held == nil {
+		t.Errorf("Expected the lock to be released after SavePolicy(); got %v, %v", held, err)
+	} else if err := a.lock.release(ctx, held); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lease is stored alongside the rules, and renewed for as long as it is held.
+	lockCtx, release, err := a.acquireSaveLock(ctx, 0)
+	if err != nil {
+		t.Fatalf("Expected acquireSaveLock() to be successful; got %v", err)
+	}
+	other := newLocker(a, time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	if held, err := other.tryAcquire(ctx, savePolicyLockID); err != nil || held != nil {
+		t.Errorf("Expected the renewed lock to stay held; got %v, %v", held, err)
+	}
+	if lockCtx.Err() != nil {
+		t.Errorf("Expected the context of the held lock to be live; got %v", context.Cause(lockCtx))
+	}
+
+	// Saves of the same instance are serialized, rather than sharing the lease.
+	waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer waitCancel()
+	if _, _, err := a.acquireSaveLock(waitCtx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected acquireSaveLock() to wait while another save holds the lock; got %v", err)
+	}
+
+	iter := a.collection.Query().Where("ptype", "=", lockPType).Get(ctx)
+	var lease CasbinRule
+	if err := iter.Next(ctx, &lease); err != nil {
+		t.Errorf("Expected the lease to be stored in the rule collection; got %v", err)
+	}
+	iter.Stop()
+
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	if policies, _ := e.GetPolicy(); len(policies) != 4 {
+		t.Errorf("Expected the lease not to be loaded as a rule; got %v", policies)
+	}
+	ptypes, err := a.DistinctValues(ctx, "ptype")
+	if err != nil {
+		t.Fatalf("Expected DistinctValues() to be successful; got %v", err)
+	}
+	for _, ptype := range ptypes {
+		if ptype == lockPType {
+			t.Errorf("Expected the lease to be excluded from DistinctValues(); got %v", ptypes)
+		}
+	}
+
+	release()
+	iter = a.collection.Query().Where("ptype", "=", lockPType).Get(ctx)
+	defer iter.Stop()
+	if err := iter.Next(ctx, &lease); err != io.EOF {
+		t.Errorf("Expected release() to remove the lease; got %v", err)
+	}
+}
+
+func TestSavePolicyLockLost(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:     "mem://casbin_rule_lock_lost/id",
+		Lock:    true,
+		LockTTL: 30 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	lockCtx, release, err := a.acquireSaveLock(ctx, 0)
+	if err != nil {
+		t.Fatalf("Expected acquireSaveLock() to be successful; got %v", err)
+	}
+	defer release()
+
+	// A lease that was removed, e.g. by another instance that took it over, cannot be renewed.
+	lease, err := a.lock.lease(ctx, savePolicyLockID)
+	if err != nil || lease == nil {
+		t.Fatalf("Expected the lease to be stored; got %v, %v", lease, err)
+	}
+	if err := a.collection.Delete(ctx, lease); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-lockCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the context of the lost lock to be cancelled")
+	}
+	if err := lockError(lockCtx, lockCtx.Err()); !errors.Is(err, errLockLost) {
+		t.Errorf("Expected lockError() to return errLockLost; got %v", err)
+	}
+}
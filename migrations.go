@@ -0,0 +1,135 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+const (
+	// schemaPType is the policy type of the schema version marker, which is stored alongside
+	// the rules and is never loaded as a rule.
+	schemaPType = "__schema__"
+	// schemaVersionID is the ID of the schema version marker.
+	schemaVersionID = "__schema_version__"
+)
+
+// isInternal reports whether the document is an internal document, such as an outbox
+// document, a lease or the schema version marker, rather than a rule.
+func (c *CasbinRule) isInternal() bool {
+	return c.isOutbox() || c.PType == schemaPType || c.PType == lockPType
+}
+
+// migration upgrades the stored documents to a schema version.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, a *adapter) error
+}
+
+// migrations are the schema migrations in ascending version order. Schema changes are
+// rolled out by appending a migration. Migrations must be idempotent, since a migration
+// that is interrupted before the marker is updated runs again.
+var migrations = []migration{
+	{version: 1, description: "record the schema version", up: func(context.Context, *adapter) error { return nil }},
+	{version: 2, description: "record the section of rules stored without one", up: migrateSections},
+}
+
+// latestSchemaVersion returns the schema version written by this version of the adapter.
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrateSections sets the section of rules written before sections were stored, derived
+// from their policy type as when they are loaded.
+func migrateSections(ctx context.Context, a *adapter) error {
+	return a.forEachPage(ctx, migrateChunkSize, func(ctx context.Context, lines []CasbinRule) error {
+		var actions []action
+		for i := range lines {
+			if !a.isRule(&lines[i]) || lines[i].Sec != "" || lines[i].PType == "" {
+				continue
+			}
+			mods := docstore.Mods{a.field("sec"): lines[i].PType[:1]}
+			actions = append(actions, action{kind: actionUpdate, line: &lines[i], mods: mods})
+		}
+		return a.commit(ctx, a.do, actions)
+	})
+}
+
+// SchemaVersion returns the schema version of the stored documents, or 0 if the
+// collection has never been migrated. Each namespace has its own schema version.
+func (a *adapter) SchemaVersion(ctx context.Context) (int, error) {
+	if err := a.begin(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+	return a.schemaVersion(ctx)
+}
+
+// schemaVersion reads the schema version marker of the namespace.
+func (a *adapter) schemaVersion(ctx context.Context) (int, error) {
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	marker := CasbinRule{ID: a.namespacedID(schemaVersionID)}
+	if err := a.get(ctx, a.collection, &marker); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return 0, nil
+		}
+		return 0, a.redact(err)
+	}
+	return marker.SchemaVersion, nil
+}
+
+// setSchemaVersion records the schema version of the stored documents.
+func (a *adapter) setSchemaVersion(ctx context.Context, version int) error {
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	marker := CasbinRule{PType: schemaPType, ID: a.namespacedID(schemaVersionID), SchemaVersion: version, Namespace: a.config.Namespace}
+	return a.do(ctx, []action{{kind: actionPut, line: &marker}})
+}
+
+// Migrate runs the schema migrations that have not been applied to the stored documents
+// yet, in version order, recording the schema version after each one. If a migration
+// fails, the migrations before it stay applied and Migrate can be called again.
+//
+// Migrate fails if the stored documents have a newer schema version than this version of
+// the adapter supports. If locking is configured, the SavePolicy lock is held while
+// migrating. Migrate is run when the adapter is opened if Config.AutoMigrate is set.
+func (a *adapter) Migrate(ctx context.Context) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	ctx, release, err := a.acquireSaveLock(ctx, a.timeout())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	current, err := a.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := latestSchemaVersion(); current > latest {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(ctx, a); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, lockError(ctx, err))
+		}
+		if err := a.setSchemaVersion(ctx, m.version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The collection is saved to the file when it is closed, and loaded by the next adapter.
+	url := "mem://casbin_rule_migrate/id?filename=" + filepath.Join(t.TempDir(), "rules")
+	a, err := New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	// Rules written by earlier versions have no section.
+	legacy := []CasbinRule{savePolicyLine("p", []string{"alice", "data1", "read"}), savePolicyLine("g", []string{"alice", "admin"})}
+	for i := range legacy {
+		if err := a.collection.Put(ctx, &legacy[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if version, err := a.SchemaVersion(ctx); err != nil || version != 0 {
+		t.Fatalf("Expected an unmigrated collection to have version 0; got %d, %v", version, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := a.Migrate(ctx); err != nil {
+			t.Fatalf("Expected Migrate() to be successful; got %v", err)
+		}
+	}
+	if version, err := a.SchemaVersion(ctx); err != nil || version != latestSchemaVersion() {
+		t.Errorf("Expected schema version %d; got %d, %v", latestSchemaVersion(), version, err)
+	}
+	lines, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		if line.Sec != line.PType {
+			t.Errorf("Expected the section of %v to be recorded", line)
+		}
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if version, _ := a.SchemaVersion(ctx); version != latestSchemaVersion() {
+		t.Errorf("Expected SavePolicy() to keep the schema version marker; got version %d", version)
+	}
+
+	if err := a.setSchemaVersion(ctx, latestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	a.close()
+	if _, err := NewWithOption(ctx, &Config{URL: url, AutoMigrate: true}); err == nil {
+		t.Error("Expected opening a collection with a newer schema version to fail")
+	}
+}
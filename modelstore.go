@@ -0,0 +1,182 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// ErrModelStorageDisabled is returned by the model storage APIs when no model collection is configured.
+var ErrModelStorageDisabled = errors.New("model storage is disabled")
+
+// ErrNoModel is returned by LoadModel when no model is stored.
+var ErrNoModel = errors.New("no model stored")
+
+// ModelVersion is a version of the model definition (the text of a .conf file), recorded
+// on each SaveModel, so that services fetch the model from the same backend as the policy:
+//
+//	m, err := a.LoadModel(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	e, err := casbin.NewEnforcer(m, a)
+type ModelVersion struct {
+	ID        string    `docstore:"id"`
+	Version   int64     `docstore:"version"`
+	CreatedAt time.Time `docstore:"created_at"`
+	Text      string    `docstore:"text,omitempty"`
+	Namespace string    `docstore:"ns,omitempty"`
+}
+
+// modelVersionID returns the document ID for a model version of the configured namespace,
+// padded so that IDs sort by version.
+func (a *adapter) modelVersionID(version int64) string {
+	return a.namespacedID(fmt.Sprintf("model-%020d", version))
+}
+
+// latestModelVersion returns the most recent model version in the model collection, or 0 if there is none.
+func (a *adapter) latestModelVersion(ctx context.Context) (int64, error) {
+	iter := a.scopeOther(a.models.Query()).OrderBy("version", docstore.Descending).Limit(1).Get(ctx, "id", "version")
+	defer iter.Stop()
+
+	var v ModelVersion
+	err := nextDoc(ctx, iter, &v)
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, a.redact(err)
+	}
+	return v.Version, nil
+}
+
+// SaveModel records the definition of the model as a new version in the model collection,
+// and returns the version.
+func (a *adapter) SaveModel(ctx context.Context, m model.Model) (int64, error) {
+	if err := a.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	if a.models == nil {
+		return 0, ErrModelStorageDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	latest, err := a.latestModelVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not determine latest model version: %w", err)
+	}
+	v := ModelVersion{
+		ID:        a.modelVersionID(latest + 1),
+		Version:   latest + 1,
+		CreatedAt: time.Now().UTC(),
+		Text:      m.ToText(),
+		Namespace: a.config.Namespace,
+	}
+	// Create fails if a concurrent SaveModel claimed the same version.
+	if err := a.models.Create(ctx, &v); err != nil {
+		return 0, fmt.Errorf("could not record model version %d: %w", v.Version, a.redact(err))
+	}
+
+	return v.Version, nil
+}
+
+// ListModelVersions returns the recorded model versions, oldest first. The text of each
+// version is not populated; use [adapter.LoadModelVersion] to read it.
+func (a *adapter) ListModelVersions(ctx context.Context) ([]ModelVersion, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.models == nil {
+		return nil, ErrModelStorageDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	iter := a.scopeOther(a.models.Query()).OrderBy("version", docstore.Ascending).Get(ctx, "id", "version", "created_at", "ns")
+	defer iter.Stop()
+
+	versions := make([]ModelVersion, 0)
+	for {
+		var v ModelVersion
+		err := nextDoc(ctx, iter, &v)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, a.redact(err)
+		}
+		if v.Namespace == a.config.Namespace {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+// LoadModel returns the most recent version of the model, or ErrNoModel if none is stored.
+func (a *adapter) LoadModel(ctx context.Context) (model.Model, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.models == nil {
+		return nil, ErrModelStorageDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	latest, err := a.latestModelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest == 0 {
+		return nil, ErrNoModel
+	}
+	return a.loadModelVersion(ctx, latest)
+}
+
+// LoadModelVersion returns the model as it was recorded in the given version.
+func (a *adapter) LoadModelVersion(ctx context.Context, version int64) (model.Model, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.models == nil {
+		return nil, ErrModelStorageDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	return a.loadModelVersion(ctx, version)
+}
+
+// loadModelVersion reads a single model version and parses its definition.
+func (a *adapter) loadModelVersion(ctx context.Context, version int64) (model.Model, error) {
+	v := ModelVersion{ID: a.modelVersionID(version)}
+	if err := a.models.Get(ctx, &v); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, fmt.Errorf("model version %d not found", version)
+		}
+		return nil, a.redact(err)
+	}
+
+	m, err := model.NewModelFromString(v.Text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse model version %d: %w", version, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestModelStorage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:      "mem://casbin_rule_modelstore/id",
+		ModelURL: "mem://casbin_model/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if _, err := a.LoadModel(ctx); !errors.Is(err, ErrNoModel) {
+		t.Fatalf("Expected LoadModel() to fail with ErrNoModel; got %v", err)
+	}
+
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := a.SaveModel(ctx, m); err != nil || v != 1 {
+		t.Fatalf("Expected SaveModel() to record version 1; got %d, %v", v, err)
+	}
+	m.AddDef("r", "r", "sub, obj, act, env")
+	if v, err := a.SaveModel(ctx, m); err != nil || v != 2 {
+		t.Fatalf("Expected SaveModel() to record version 2; got %d, %v", v, err)
+	}
+
+	versions, err := a.ListModelVersions(ctx)
+	if err != nil {
+		t.Fatalf("Expected ListModelVersions() to be successful; got %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+		t.Fatalf("Expected versions 1 and 2; got %+v", versions)
+	}
+
+	latest, err := a.LoadModel(ctx)
+	if err != nil {
+		t.Fatalf("Expected LoadModel() to be successful; got %v", err)
+	}
+	if got := latest["r"]["r"].Value; got != "sub, obj, act, env" {
+		t.Errorf("Expected the latest model; got request definition %q", got)
+	}
+
+	first, err := a.LoadModelVersion(ctx, 1)
+	if err != nil {
+		t.Fatalf("Expected LoadModelVersion() to be successful; got %v", err)
+	}
+	e, err := casbin.NewEnforcer(first, a)
+	if err != nil {
+		t.Fatalf("Expected an enforcer with the stored model; got %v", err)
+	}
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e.Enforce("alice", "data1", "read"); err != nil || !ok {
+		t.Errorf("Expected the stored model to enforce the policy; got %v, %v", ok, err)
+	}
+
+	if _, err := a.LoadModelVersion(ctx, 42); err == nil {
+		t.Error("Expected LoadModelVersion() of an unknown version to fail")
+	}
+}
+
+func TestModelStorageDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_modelstore_disabled/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if _, err := a.LoadModel(ctx); !errors.Is(err, ErrModelStorageDisabled) {
+		t.Errorf("Expected ErrModelStorageDisabled; got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package adapter
+
+import "gocloud.dev/docstore"
+
+// WithNamespace returns the option that sets Config.Namespace, so that several models or
+// enforcers can share a single collection.
+func WithNamespace(name string) Option {
+	return func(c *Config) {
+		c.Namespace = name
+	}
+}
+
+// scope restricts the query of a rule collection to the documents of the configured
+// namespace, if any.
+func (a *adapter) scope(query *docstore.Query) *docstore.Query {
+	if a.config.Namespace == "" {
+		return query
+	}
+	return query.Where(a.field("ns"), EqualOp, a.config.Namespace)
+}
+
+// scopeOther restricts the query of a collection that does not hold rules, e.g. the
+// history, like [adapter.scope].
+func (a *adapter) scopeOther(query *docstore.Query) *docstore.Query {
+	if a.config.Namespace == "" {
+		return query
+	}
+	return query.Where("ns", EqualOp, a.config.Namespace)
+}
+
+// isRule reports whether the document is a rule of the configured namespace, rather than
+// an internal document or a rule of another namespace.
+func (a *adapter) isRule(line *CasbinRule) bool {
+	return !line.isInternal() && line.Namespace == a.config.Namespace
+}
+
+// namespacedID returns the ID of an internal document of the configured namespace.
+func (a *adapter) namespacedID(id string) string {
+	if a.config.Namespace == "" {
+		return id
+	}
+	return a.config.Namespace + "/" + id
+}
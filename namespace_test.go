@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestNamespace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const url = "mem://casbin_rule_namespace/id"
+	tenants := make(map[string]*adapter)
+	var opened []*adapter
+	defer func() { closeAll(opened...) }()
+	for _, ns := range []string{"", "tenant1", "tenant2"} {
+		a, err := New(ctx, url, WithNamespace(ns))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tenants[ns] = a
+		opened = append(opened, a)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range tenants {
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+	}
+	if err := tenants["tenant1"].AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := tenants["tenant2"].RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+
+	lines, err := tenants[""].collectRules(ctx, tenants[""].collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5 {
+		t.Errorf("Expected the rules of other namespaces to be skipped; got %d rules", len(lines))
+	}
+
+	for _, tt := range []struct {
+		ns       string
+		want     [][]string
+		subjects []string
+	}{
+		{"", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}, []string{"alice", "bob", "data2_admin"}},
+		{"tenant1", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}}, []string{"alice", "bob", "carol", "data2_admin"}},
+		{"tenant2", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}, []string{"alice", "bob"}},
+	} {
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", tenants[tt.ns])
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicyWithoutOrder(t, e, tt.want)
+
+		subjects, err := tenants[tt.ns].DistinctValues(ctx, "v0", "p")
+		if err != nil {
+			t.Fatalf("Expected DistinctValues() to be successful; got %v", err)
+		}
+		if !util.ArrayEquals(tt.subjects, subjects) {
+			t.Errorf("Expected the subjects %v of namespace %q; got %v", tt.subjects, tt.ns, subjects)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operation is the kind of policy change reported in a [ChangeEvent].
+type Operation string
+
+// The operations reported in a [ChangeEvent], named after the adapter methods.
+const (
+	OpSavePolicy             Operation = "save_policy"
+	OpAddPolicy              Operation = "add_policy"
+	OpAddPolicies            Operation = "add_policies"
+	OpRemovePolicy           Operation = "remove_policy"
+	OpRemovePolicies         Operation = "remove_policies"
+	OpRemoveFilteredPolicy   Operation = "remove_filtered_policy"
+	OpUpdatePolicy           Operation = "update_policy"
+	OpUpdatePolicies         Operation = "update_policies"
+	OpUpdateFilteredPolicies Operation = "update_filtered_policies"
+)
+
+// The operations of the bulk changes made by the other methods of the adapter. Their events
+// carry no rules, since the changes may span policy types: consumers reload the policy.
+// Changes written in several batches report an event per batch.
+const (
+	OpSync            Operation = "sync"
+	OpRestore         Operation = "restore"
+	OpRollback        Operation = "rollback"
+	OpImport          Operation = "import"
+	OpRestoreArchived Operation = "restore_archived"
+	OpRenameSubject   Operation = "rename_subject"
+	OpPurgeSubject    Operation = "purge_subject"
+	OpPurgeFiltered   Operation = "purge_filtered"
+	OpMigrateIDs      Operation = "migrate_ids"
+	OpUpdatePriority  Operation = "update_priority"
+)
+
+// ChangeEvent describes a successful policy change.
+type ChangeEvent struct {
+	Operation Operation  `json:"operation"`
+	Sec       string     `json:"sec,omitempty"`
+	PType     string     `json:"ptype,omitempty"`
+	Rules     [][]string `json:"rules,omitempty"`     // the added or removed rules, or the new rules of an update
+	OldRules  [][]string `json:"old_rules,omitempty"` // the replaced rules of an update
+	Actor     string     `json:"actor,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// Notifier is notified of successful policy changes made through the adapter.
+type Notifier interface {
+	Notify(ctx context.Context, event ChangeEvent) error
+}
+
+// stamp sets the actor of the changes made with ctx and the timestamp of the event. The
+// actor of buffered events was set when they were buffered.
+func (a *adapter) stamp(ctx context.Context, event *ChangeEvent) {
+	if event.Actor == "" {
+		event.Actor = a.actor(ctx)
+	}
+	event.Timestamp = time.Now().UTC()
+}
+
+// notify reports a successful change to the configured notifiers. The change has already
+// been applied, so notification failures are logged rather than returned.
+//
+// The event is recorded in the change log first, if enabled. With Config.Outbox the event
+// was written with the change, and is delivered by the outbox relay instead.
+func (a *adapter) notify(ctx context.Context, event ChangeEvent) {
+	if (len(a.config.Notifiers) == 0 && a.changes == nil) || a.config.Outbox {
+		return
+	}
+	a.stamp(ctx, &event)
+
+	// The notifiers get their own deadline, since the change itself may have used up most of ctx.
+	ctx, cancel := a.withTimeout(context.WithoutCancel(ctx), opWrite)
+	defer cancel()
+	if err := a.record(ctx, changeKey(event), event); err != nil {
+		a.config.logger().Printf("record %s error: %v", event.Operation, err)
+	}
+	if err := a.deliver(ctx, event); err != nil {
+		a.config.logger().Printf("notify %s error: %v", event.Operation, err)
+	}
+}
+
+// commit runs the actions of a change with exec (a.do, or a.doWithRollback or a.doAtomically
+// for changes that must not apply part-way), together with the outbox actions of the events,
+// and notifies the events once the actions are applied. Every change of the stored rules goes
+// through commit, so that none is made without a change event.
+func (a *adapter) commit(ctx context.Context, exec func(context.Context, []action) error, actions []action, events ...ChangeEvent) error {
+	for _, event := range events {
+		actions = append(actions, a.outboxAction(ctx, event)...)
+	}
+	if err := exec(ctx, actions); err != nil {
+		return err
+	}
+	for _, event := range events {
+		a.notify(ctx, event)
+	}
+	return nil
+}
+
+// deliver passes the event to every notifier, and returns the combined errors of the
+// notifiers that failed.
+func (a *adapter) deliver(ctx context.Context, event ChangeEvent) error {
+	var errs []error
+	for _, n := range a.config.Notifiers {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
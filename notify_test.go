@@ -0,0 +1,116 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestBulkChangeEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_bulk_events/id",
+		HistoryURL: "mem://casbin_history_bulk_events/id",
+		PendingURL: "mem://casbin_pending_bulk_events/id",
+		ArchiveURL: "mem://casbin_archive_bulk_events/id",
+		Outbox:     true,
+		Notifiers:  []Notifier{n},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	// Every change is written with its outbox event, and delivered by the relay.
+	expect := func(name string, op Operation) {
+		t.Helper()
+		n.events = nil
+		if _, err := a.DeliverOutbox(ctx); err != nil {
+			t.Fatalf("Expected DeliverOutbox() to be successful; got %v", err)
+		}
+		if len(n.events) == 0 || n.events[len(n.events)-1].Operation != op {
+			t.Errorf("Expected %s to report a %s event; got %v", name, op, n.events)
+		}
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddPolicies([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	expect("SavePolicy", OpSavePolicy)
+
+	if _, err := e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sync(ctx, e.GetModel()); err != nil {
+		t.Fatalf("Expected Sync() to be successful; got %v", err)
+	}
+	expect("Sync", OpSync)
+
+	if err := a.RenameSubject(ctx, "alice", "alicia"); err != nil {
+		t.Fatalf("Expected RenameSubject() to be successful; got %v", err)
+	}
+	expect("RenameSubject", OpRenameSubject)
+
+	if _, err := a.ImportStream(ctx, strings.NewReader("p, dave, data4, read\n"), FormatCSV); err != nil {
+		t.Fatalf("Expected ImportStream() to be successful; got %v", err)
+	}
+	expect("ImportStream", OpImport)
+
+	id, err := a.StageAddPolicies(ctx, "p", "p", [][]string{{"erin", "data5", "read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Approve(ctx, id); err != nil {
+		t.Fatalf("Expected Approve() to be successful; got %v", err)
+	}
+	expect("Approve", OpAddPolicies)
+
+	if _, err := a.PurgeFiltered(ctx, nil, Filter{FieldPath: []string{"v0"}, Value: "dave"}); err != nil {
+		t.Fatalf("Expected PurgeFiltered() to be successful; got %v", err)
+	}
+	expect("PurgeFiltered", OpPurgeFiltered)
+
+	if _, err := a.PurgeSubject(ctx, "bob", false); err != nil {
+		t.Fatalf("Expected PurgeSubject() to be successful; got %v", err)
+	}
+	expect("PurgeSubject", OpPurgeSubject)
+
+	if _, err := a.RestoreArchived(ctx, time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Expected RestoreArchived() to be successful; got %v", err)
+	}
+	expect("RestoreArchived", OpRestoreArchived)
+
+	if err := a.Rollback(ctx, 1); err != nil {
+		t.Fatalf("Expected Rollback() to be successful; got %v", err)
+	}
+	expect("Rollback", OpRollback)
+
+	bucketURL := "file://" + t.TempDir()
+	if err := a.Backup(ctx, bucketURL, "policy.jsonl.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"frank", "data6", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Restore(ctx, bucketURL, "policy.jsonl.gz"); err != nil {
+		t.Fatalf("Expected Restore() to be successful; got %v", err)
+	}
+	expect("Restore", OpRestore)
+
+	if _, err := a.MigrateIDs(ctx, IDStrategyHash, IDStrategyCanonical); err != nil {
+		t.Fatalf("Expected MigrateIDs() to be successful; got %v", err)
+	}
+	expect("MigrateIDs", OpMigrateIDs)
+}
@@ -0,0 +1,122 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// outboxPType is the policy type of outbox documents, which are stored alongside the rules
+// so they can be written in the same action list as a change. They are never loaded as rules.
+const outboxPType = "__outbox__"
+
+// isOutbox reports whether the document is an outbox document rather than a rule.
+func (c *CasbinRule) isOutbox() bool {
+	return c.PType == outboxPType
+}
+
+// outboxAction returns the action that writes the event to the outbox, or nil if the
+// outbox is disabled.
+func (a *adapter) outboxAction(ctx context.Context, event ChangeEvent) []action {
+	if !a.config.Outbox {
+		return nil
+	}
+	a.stamp(ctx, &event)
+	data, err := json.Marshal(event)
+	if err != nil {
+		// A ChangeEvent always encodes; fall back to direct notification just in case.
+		a.config.logger().Printf("encode outbox event error: %v", err)
+		return nil
+	}
+	// IDs sort by creation time, so events are delivered in order.
+	id := a.namespacedID("outbox_" + changeKey(event))
+	line := &CasbinRule{PType: outboxPType, ID: id, Event: string(data), Namespace: a.config.Namespace}
+	return []action{{kind: actionPut, line: line}}
+}
+
+// DeliverOutbox delivers the pending outbox events to the change log and the configured
+// notifiers, oldest first, and deletes each delivered event. It stops at the first event that cannot be
+// delivered, which is retried on the next call, and returns the number of delivered events.
+//
+// Events are delivered at least once: an event may be delivered again if it could not be
+// deleted, or if DeliverOutbox runs concurrently in several instances.
+func (a *adapter) DeliverOutbox(ctx context.Context) (int, error) {
+	if err := a.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	return a.deliverOutbox(ctx)
+}
+
+// deliverOutbox runs DeliverOutbox, without registering an operation, so that Shutdown
+// delivers the pending events once no operation is in progress.
+func (a *adapter) deliverOutbox(ctx context.Context) (int, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, a.timeout())
+	iter := a.scope(a.collection.Query()).Where(a.field("ptype"), EqualOp, outboxPType).Get(queryCtx)
+	var pending []CasbinRule
+	for {
+		var line CasbinRule
+		err := a.next(queryCtx, iter, &line)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			iter.Stop()
+			cancel()
+			return 0, err
+		}
+		if line.Namespace == a.config.Namespace {
+			pending = append(pending, line)
+		}
+	}
+	iter.Stop()
+	cancel()
+	slices.SortFunc(pending, func(x, y CasbinRule) int { return strings.Compare(x.ID, y.ID) })
+
+	for i := range pending {
+		var event ChangeEvent
+		if err := json.Unmarshal([]byte(pending[i].Event), &event); err != nil {
+			return i, fmt.Errorf("could not decode outbox event %s: %w", pending[i].ID, err)
+		}
+
+		deliverCtx, cancel := context.WithTimeout(ctx, a.timeout())
+		err := a.record(deliverCtx, outboxKey(pending[i].ID), event)
+		if err == nil {
+			err = a.deliver(deliverCtx, event)
+		}
+		if err == nil {
+			err = a.doActions(deliverCtx, []action{{kind: actionDelete, line: &pending[i]}})
+		}
+		cancel()
+		if err != nil {
+			return i, fmt.Errorf("could not deliver outbox event %s: %w", pending[i].ID, err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// RunOutbox delivers outbox events every interval until ctx is done, logging delivery
+// errors. It is meant to run in its own goroutine when Config.Outbox is set, and returns
+// ErrShutdown once the adapter is shut down.
+func (a *adapter) RunOutbox(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.DeliverOutbox(ctx); errors.Is(err, ErrShutdown) {
+			return err
+		} else if err != nil && ctx.Err() == nil {
+			a.config.logger().Printf("deliver outbox error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// recordingNotifier records the events it is notified of.
+type recordingNotifier struct {
+	events []ChangeEvent
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event ChangeEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_outbox/id",
+		Outbox:    true,
+		Notifiers: []Notifier{n},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if len(n.events) != 0 {
+		t.Fatalf("Expected events to be delivered by the outbox only; got %v", n.events)
+	}
+
+	// Outbox documents are not loaded as rules.
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("Expected the policy to load alongside outbox documents; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}})
+	if ptypes, _ := a.DistinctValues(ctx, "ptype"); len(ptypes) != 1 {
+		t.Errorf("Expected outbox documents to be hidden from DistinctValues(); got %v", ptypes)
+	}
+
+	delivered, err := a.DeliverOutbox(ctx)
+	if err != nil {
+		t.Fatalf("Expected DeliverOutbox() to be successful; got %v", err)
+	}
+	if delivered != 3 || len(n.events) != 3 {
+		t.Fatalf("Expected 3 events to be delivered; got %d, %v", delivered, n.events)
+	}
+	for i, op := range []Operation{OpAddPolicy, OpRemovePolicy, OpAddPolicies} {
+		if n.events[i].Operation != op || n.events[i].Timestamp.IsZero() {
+			t.Errorf("Expected event %d to be %s; got %+v", i, op, n.events[i])
+		}
+	}
+
+	if delivered, err := a.DeliverOutbox(ctx); err != nil || delivered != 0 {
+		t.Errorf("Expected delivered events to be removed from the outbox; got %d, %v", delivered, err)
+	}
+}
@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"gocloud.dev/docstore"
+)
+
+// ErrQueryFallback is returned by queries that the provider cannot execute natively, if
+// Config.QueryFallback is FallbackError.
+var ErrQueryFallback = errors.New("query is not executed natively by the provider")
+
+// Fallback is how the adapter handles queries that the provider cannot execute natively,
+// e.g. a DynamoDB table scan or Firestore filters evaluated client-side. docstore falls back
+// silently to such full scans, which are slow and costly on large collections.
+//
+// It is applied with the [PlanFunc] registered by the driver package of the provider: the
+// awsdynamodb package reports table scans, and the gcpfirestore package reports filters
+// evaluated client-side.
+type Fallback int
+
+const (
+	// FallbackAllow runs the queries without reporting them.
+	FallbackAllow Fallback = iota
+	// FallbackWarn logs a warning the first time each query shape falls back, so that
+	// missing indexes are found before production.
+	FallbackWarn
+	// FallbackError fails the queries with ErrQueryFallback.
+	FallbackError
+)
+
+// String returns the name of the fallback policy.
+func (f Fallback) String() string {
+	switch f {
+	case FallbackAllow:
+		return "allow"
+	case FallbackWarn:
+		return "warn"
+	case FallbackError:
+		return "error"
+	default:
+		return fmt.Sprintf("Fallback(%d)", int(f))
+	}
+}
+
+// PlanFunc reports how the provider executes a query with the given filters, which the
+// adapter added to the query. It returns a description of the fallback (e.g. "table scan")
+// if the provider cannot execute the query natively, and "" otherwise.
+type PlanFunc func(query *docstore.Query, filters []Filter) (string, error)
+
+// newPlanFunc returns the [PlanFunc] of the provider of the configured URL, or nil if
+// fallbacks are allowed or the [Driver] of the provider has no [PlanFunc].
+func newPlanFunc(config *Config) PlanFunc {
+	if config.QueryFallback == FallbackAllow {
+		return nil
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil
+	}
+	return driverFor(u.Scheme).Plan
+}
+
+// checkPlan applies Config.QueryFallback to the query, to which the adapter added the
+// filters (nil if it only added equality filters). Errors of the plan are left to the
+// query, which reports them when it runs.
+func (a *adapter) checkPlan(query *docstore.Query, filters []Filter) error {
+	if a.plan == nil {
+		return nil
+	}
+	fallback, err := a.plan(query, filters)
+	if err != nil || fallback == "" {
+		return nil
+	}
+	summary := summarizeFilters(filters)
+	if summary == "" {
+		summary = "equality filters"
+	}
+	if a.config.QueryFallback == FallbackError {
+		return fmt.Errorf("%w: %s (filter: %s); add an index or set Config.QueryFallback", ErrQueryFallback, fallback, summary)
+	}
+	if _, warned := a.fallbacks.LoadOrStore(fallback+"\x00"+summary, struct{}{}); warned {
+		return nil
+	}
+	a.config.logger().Printf("query falls back to %s (filter: %s); add an index before the collection grows", fallback, summary)
+	return nil
+}
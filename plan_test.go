@@ -0,0 +1,83 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"gocloud.dev/docstore"
+)
+
+func TestQueryFallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Report every query with a range filter as a fallback.
+	registerDriver(t, "mem", Driver{
+		Plan: func(_ *docstore.Query, filters []Filter) (string, error) {
+			for _, f := range filters {
+				if f.Op != EqualOp {
+					return "a full scan", nil
+				}
+			}
+			return "", nil
+		},
+	})
+
+	var buf bytes.Buffer
+	a, err := NewWithOption(ctx, &Config{
+		URL:           "mem://casbin_rule_plan/id",
+		QueryFallback: FallbackWarn,
+		Logger:        log.New(&buf, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native := Filter{FieldPath: []string{"v0"}, Op: EqualOp, Value: "alice"}
+	if err := e.LoadFilteredPolicy(native); err != nil {
+		t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning for a native query; got %q", buf.String())
+	}
+
+	scan := Filter{FieldPath: []string{"v1"}, Op: ">", Value: "data0"}
+	for i := 0; i < 2; i++ {
+		if err := e.LoadFilteredPolicy(scan); err != nil {
+			t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+		}
+	}
+	if got := strings.Count(buf.String(), "query falls back to a full scan (filter: v1 >)"); got != 1 {
+		t.Errorf("Expected a single warning for the fallback; got %q", buf.String())
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_plan/id", QueryFallback: FallbackError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadFilteredPolicy(scan); !errors.Is(err, ErrQueryFallback) {
+		t.Errorf("Expected ErrQueryFallback; got %v", err)
+	}
+	if err := e.LoadFilteredPolicy(native); err != nil {
+		t.Errorf("Expected the native query to be successful; got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package adapter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// preloadState is the state of the load started by Preload.
+type preloadState struct {
+	mu      sync.Mutex
+	started bool
+	ready   chan struct{} // closed when the load completes
+	err     error         // the error of the load, set before ready is closed
+}
+
+// readyChan returns the channel closed when the preload completes, creating it if needed.
+func (p *preloadState) readyChan() chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ready == nil {
+		p.ready = make(chan struct{})
+	}
+	return p.ready
+}
+
+// Preload loads the policy into the model in the background, so that a service can start
+// serving the traffic that needs no authorization while the policy store loads. Ready is
+// closed when the load completes, after which ReadyErr returns its error. Calls after the
+// first are ignored.
+//
+// The model is written by the load until Ready is closed. Once it is, create the enforcer
+// from the model without an adapter (casbin.NewEnforcer(model)), so that casbin does not
+// load the policy again, call its BuildRoleLinks, and set the adapter with SetAdapter.
+func (a *adapter) Preload(ctx context.Context, model model.Model) {
+	ready := a.preload.readyChan()
+	a.preload.mu.Lock()
+	started := a.preload.started
+	a.preload.started = true
+	a.preload.mu.Unlock()
+	if started {
+		return
+	}
+	go func() {
+		a.preload.err = a.LoadPolicyCtx(ctx, model)
+		close(ready)
+	}()
+}
+
+// Ready returns a channel that is closed when the load started by Preload completes,
+// whether or not it succeeded. It is never closed if Preload is not called.
+func (a *adapter) Ready() <-chan struct{} {
+	return a.preload.readyChan()
+}
+
+// ReadyErr returns the error of the load started by Preload once Ready is closed, and nil
+// until then.
+func (a *adapter) ReadyErr() error {
+	select {
+	case <-a.preload.readyChan():
+		return a.preload.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestPreload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_preload/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicy("p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-a.Ready():
+		t.Fatal("Expected Ready() to be open before Preload()")
+	default:
+	}
+
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Preload(ctx, m)
+	a.Preload(ctx, m)
+	select {
+	case <-a.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Ready() to be closed after the preload")
+	}
+	if err := a.ReadyErr(); err != nil {
+		t.Fatalf("Expected Preload() to be successful; got %v", err)
+	}
+
+	e, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.BuildRoleLinks(); err != nil {
+		t.Fatal(err)
+	}
+	e.SetAdapter(a)
+	testGetPolicy(t, e, [][]string{{"admin", "data1", "read"}})
+	if ok, err := e.Enforce("alice", "data1", "read"); err != nil || !ok {
+		t.Errorf("Expected alice to be allowed through the preloaded role; got %v, %v", ok, err)
+	}
+
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_preload/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	b.Preload(ctx, m)
+	<-b.Ready()
+	if err := b.ReadyErr(); err == nil {
+		t.Error("Expected the preload of a shut down adapter to fail")
+	}
+}
@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gocloud.dev/docstore"
+)
+
+// nextPriority returns the priority of a rule added now, which orders it after the rules
+// added before it. Priorities are Unix times in nanoseconds, increasing within the process.
+func (a *adapter) nextPriority() int64 {
+	for {
+		last := a.priorityClock.Load()
+		priority := max(time.Now().UnixNano(), last+1)
+		if a.priorityClock.CompareAndSwap(last, priority) {
+			return priority
+		}
+	}
+}
+
+// sortByPriority sorts the rules by priority, lowest first, keeping the order of rules
+// with the same priority.
+func sortByPriority(lines []CasbinRule) {
+	slices.SortStableFunc(lines, func(x, y CasbinRule) int { return cmp.Compare(x.Priority, y.Priority) })
+}
+
+// UpdatePriority sets the priority of a stored rule, which orders the loaded rules when
+// Config.Priorities is set: rules with lower priorities are loaded first, so they come first
+// in models with priority(p.eff). Added rules are given priorities after the stored ones.
+//
+// The enforcer must reload the policy for the new order to take effect.
+func (a *adapter) UpdatePriority(ctx context.Context, sec, ptype string, rule []string, priority int64) error {
+	return a.intercept(ctx, OpInfo{Name: "UpdatePriority", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.updatePriority(ctx, sec, ptype, rule, priority)
+	})
+}
+
+// updatePriority runs UpdatePriority within the interceptors.
+func (a *adapter) updatePriority(ctx context.Context, sec, ptype string, rule []string, priority int64) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if !a.config.Priorities {
+		return errors.New("priorities are disabled")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	if err := a.flush(ctx); err != nil {
+		return err
+	}
+
+	lines, err := a.resolve(ctx, []CasbinRule{a.ruleLine(ptype, rule)})
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return errors.New("rule not found")
+	}
+	actions := make([]action, 0, len(lines))
+	for i := range lines {
+		actions = append(actions, action{kind: actionUpdate, line: &lines[i], mods: docstore.Mods{"priority": priority}})
+	}
+	return a.commit(ctx, a.do, actions, ChangeEvent{Operation: OpUpdatePriority, Sec: sec, PType: ptype, Rules: [][]string{rule}})
+}
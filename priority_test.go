@@ -0,0 +1,61 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestPriorities(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_priorities/id", Priorities: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	deny := []string{"t1", "alice", "/data", "read", "svc", "deny"}
+	allow := []string{"t1", "alice", "/data", "read", "svc", "allow"}
+	for _, rule := range [][]string{deny, allow} {
+		if err := a.AddPolicy("p", "p", rule); err != nil {
+			t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+		}
+	}
+
+	// Rules are loaded in the order they were added, so the first matching rule denies.
+	e, err := casbin.NewEnforcer("testdata/rbac_tenant_service.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.Enforce("t1", "alice", "/data", "read", "svc"); ok {
+		t.Error("Expected the earlier deny rule to take precedence")
+	}
+
+	if err := a.UpdatePriority(ctx, "p", "p", allow, 1); err != nil {
+		t.Fatalf("Expected UpdatePriority() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := e.Enforce("t1", "alice", "/data", "read", "svc"); !ok {
+		t.Error("Expected the allow rule to take precedence after raising its priority")
+	}
+
+	// Updates keep the place of a rule in the load order.
+	if _, err := e.UpdatePolicy(allow, []string{"t1", "alice", "/data", "write", "svc", "allow"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	policy, _ := e.GetPolicy()
+	if len(policy) != 2 || policy[0][3] != "write" || policy[1][5] != "deny" {
+		t.Errorf("Expected the updated rule to stay before the deny rule; got %v", policy)
+	}
+
+	if err := a.UpdatePriority(ctx, "p", "p", []string{"t1", "bob", "/data", "read", "svc", "allow"}, 1); err == nil {
+		t.Error("Expected UpdatePriority() to fail for a rule that is not stored")
+	}
+}
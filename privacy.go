@@ -0,0 +1,122 @@
+package adapter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// subjectHashPrefix marks a stored subject as a keyed hash, so it is not hashed again.
+const subjectHashPrefix = "hmac:"
+
+// HashSubject returns the keyed hash stored in place of the subject when Config.SubjectKey
+// is set. Without a SubjectResolver the hashes are loaded as stored, so the subjects passed
+// to the enforcer must be hashed the same way.
+func HashSubject(key []byte, subject string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(subject))
+	return subjectHashPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SubjectResolver maps the stored subject hashes back to subjects when the policy is loaded.
+type SubjectResolver interface {
+	// ResolveSubject returns the subject for a hash produced by HashSubject. An error
+	// fails the load.
+	ResolveSubject(ctx context.Context, hash string) (string, error)
+}
+
+// SubjectMap is a SubjectResolver for a known set of subjects, indexed by their hash.
+// Unknown hashes resolve to themselves.
+type SubjectMap map[string]string
+
+// NewSubjectMap returns the SubjectMap of the subjects hashed with the key.
+func NewSubjectMap(key []byte, subjects ...string) SubjectMap {
+	m := make(SubjectMap, len(subjects))
+	for _, subject := range subjects {
+		m[HashSubject(key, subject)] = subject
+	}
+	return m
+}
+
+// ResolveSubject implements SubjectResolver.
+func (m SubjectMap) ResolveSubject(_ context.Context, hash string) (string, error) {
+	if subject, ok := m[hash]; ok {
+		return subject, nil
+	}
+	return hash, nil
+}
+
+// hashSubject returns the value stored for the subject: its keyed hash if subject hashing
+// is enabled, or the subject itself.
+func (a *adapter) hashSubject(subject string) string {
+	if len(a.config.SubjectKey) == 0 || subject == "" || strings.HasPrefix(subject, subjectHashPrefix) {
+		return subject
+	}
+	return HashSubject(a.config.SubjectKey, subject)
+}
+
+// hashRule returns the rule with its subject (v0) replaced by the stored value.
+func (a *adapter) hashRule(rule []string) []string {
+	if len(rule) == 0 || len(a.config.SubjectKey) == 0 {
+		return rule
+	}
+	rule = slices.Clone(rule)
+	rule[0] = a.hashSubject(rule[0])
+	return rule
+}
+
+// hashFieldValues returns the field values of a filtered operation with the subject
+// replaced by the stored value, if the values include the subject.
+func (a *adapter) hashFieldValues(fieldIndex int, fieldValues []string) []string {
+	if fieldIndex != 0 {
+		return fieldValues
+	}
+	return a.hashRule(fieldValues)
+}
+
+// hashFilter returns the filter with its value replaced by the stored value, if it matches
+// the subject for equality.
+func (a *adapter) hashFilter(f Filter) Filter {
+	if subject, ok := f.Value.(string); ok && valueIndex(f.FieldPath) == 0 && (f.Op == "" || f.Op == EqualOp) {
+		f.Value = a.hashSubject(subject)
+	}
+	return f
+}
+
+// resolveLine replaces the subject hash of the line by the subject, if a SubjectResolver
+// is configured.
+func (a *adapter) resolveLine(ctx context.Context, line *CasbinRule) error {
+	if a.config.SubjectResolver == nil {
+		return nil
+	}
+	subject := &line.V0
+	if line.Values != nil {
+		if len(line.Values) == 0 {
+			return nil
+		}
+		subject = &line.Values[0]
+	}
+	if !strings.HasPrefix(*subject, subjectHashPrefix) {
+		return nil
+	}
+	resolved, err := a.config.SubjectResolver.ResolveSubject(ctx, *subject)
+	if err != nil {
+		return fmt.Errorf("could not resolve subject %q: %w", *subject, err)
+	}
+	*subject = resolved
+	return nil
+}
+
+// loadLine loads a stored line into the model, resolving its subject.
+func (a *adapter) loadLine(ctx context.Context, line CasbinRule, model model.Model) error {
+	if err := a.resolveLine(ctx, &line); err != nil {
+		return err
+	}
+	return loadPolicyLine(line, model)
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestSubjectKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := []byte("secret")
+	a, err := NewWithOption(ctx, &Config{
+		URL:             "mem://casbin_rule_subject_key/id",
+		SubjectKey:      key,
+		SubjectResolver: NewSubjectMap(key, "alice", "bob", "data2_admin"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	subjects, err := a.DistinctValues(ctx, "v0")
+	if err != nil {
+		t.Fatalf("Expected DistinctValues() to be successful; got %v", err)
+	}
+	for _, subject := range subjects {
+		if !strings.HasPrefix(subject, subjectHashPrefix) {
+			t.Errorf("Expected subjects to be stored hashed; got %q", subject)
+		}
+	}
+	if !slices.Contains(subjects, HashSubject(key, "alice")) {
+		t.Errorf("Expected the hash of alice to be stored; got %v", subjects)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+	if ok, _ := e.Enforce("alice", "data2", "write"); !ok {
+		t.Error("Expected the resolved subjects to be enforced")
+	}
+
+	policies, err := a.GetPoliciesForSubject(ctx, "bob")
+	if err != nil {
+		t.Fatalf("Expected GetPoliciesForSubject() to be successful; got %v", err)
+	}
+	if want := [][]string{{"bob", "data2", "write"}}; !arrayEqualsWithoutOrder(want, policies) {
+		t.Errorf("Expected policies %v; got %v", want, policies)
+	}
+
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "bob"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+
+	a.config.SubjectResolver = nil
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	admin := HashSubject(key, "data2_admin")
+	testGetPolicy(t, e, [][]string{{admin, "data2", "read"}, {admin, "data2", "write"}})
+
+	// Saving the loaded hashes does not hash them again.
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{admin, "data2", "read"}, {admin, "data2", "write"}})
+}
@@ -0,0 +1,71 @@
+package adapter
+
+import "time"
+
+// defaultProgressInterval is the default of Config.ProgressInterval.
+const defaultProgressInterval = 5 * time.Second
+
+// LoadProgress is the progress of a load reported to Config.OnLoadProgress.
+type LoadProgress struct {
+	Rules   int           // the number of rules loaded so far
+	Elapsed time.Duration // the time since the load started
+	Done    bool          // whether the load completed, in which case Err is its error
+	Err     error         // the error of the completed load
+}
+
+// ProgressFunc receives the progress of loads, e.g. to log it or report it from a health
+// endpoint. It is called from the loading goroutine, which it must not block.
+type ProgressFunc func(LoadProgress)
+
+// WithLoadProgress sets the function receiving the progress of loads, called at most every
+// interval while a load runs (defaults to 5s if not positive) and once when it completes.
+func WithLoadProgress(interval time.Duration, fn ProgressFunc) Option {
+	return func(c *Config) {
+		c.OnLoadProgress = fn
+		c.ProgressInterval = interval
+	}
+}
+
+// loadProgress tracks a load for Config.OnLoadProgress.
+type loadProgress struct {
+	fn       ProgressFunc
+	interval time.Duration
+	start    time.Time
+	last     time.Time // when the progress was last reported
+	rules    int
+}
+
+// trackProgress starts tracking a load, or returns nil if Config.OnLoadProgress is not set.
+// Call done when the load completes.
+func (a *adapter) trackProgress() *loadProgress {
+	if a.config.OnLoadProgress == nil {
+		return nil
+	}
+	interval := a.config.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	now := time.Now()
+	return &loadProgress{fn: a.config.OnLoadProgress, interval: interval, start: now, last: now}
+}
+
+// add counts n loaded rules, reporting the progress if the interval has passed since it was
+// last reported.
+func (p *loadProgress) add(n int) {
+	if p == nil {
+		return
+	}
+	p.rules += n
+	if now := time.Now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.fn(LoadProgress{Rules: p.rules, Elapsed: now.Sub(p.start)})
+	}
+}
+
+// done reports the completion of the load with its error.
+func (p *loadProgress) done(err error) {
+	if p == nil {
+		return
+	}
+	p.fn(LoadProgress{Rules: p.rules, Elapsed: time.Since(p.start), Done: true, Err: err})
+}
@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLoadProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reports []LoadProgress
+	a, err := New(ctx, "mem://casbin_rule_load_progress/id", WithLoadProgress(time.Nanosecond, func(p LoadProgress) {
+		reports = append(reports, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("Expected NewEnforcer() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+
+	if len(reports) == 0 {
+		t.Fatal("Expected the progress of the load to be reported")
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Err != nil || last.Rules != 2 {
+		t.Errorf("Expected the completion of the load with 2 rules; got %+v", last)
+	}
+	for i, p := range reports[:len(reports)-1] {
+		if p.Done {
+			t.Errorf("Expected report %d to be in progress; got %+v", i, p)
+		}
+		if i > 0 && p.Rules < reports[i-1].Rules {
+			t.Errorf("Expected the rules loaded to grow; got %+v", reports)
+		}
+	}
+}
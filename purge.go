@@ -0,0 +1,77 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+)
+
+// purgeChunkSize is the number of rules deleted per batch by PurgeFiltered.
+const purgeChunkSize = 500
+
+// PurgeProgress is called by PurgeFiltered after each deleted chunk, with the number of
+// rules deleted so far and the total number of rules matched by the filters.
+type PurgeProgress func(deleted, total int)
+
+// PurgeFiltered deletes every stored rule matched by all the filters, such as the rules
+// of a tenant domain, and returns the number of deleted rules. The filters have the same
+// form as those accepted by LoadFilteredPolicy, and at least one is required.
+//
+// The rules are deleted in chunks, which are subject to rate limiting and each get the
+// adapter timeout, so large purges are not bounded by a single timeout. If progress is not
+// nil it is called after each chunk. On failure, the rules of earlier chunks stay deleted.
+func (a *adapter) PurgeFiltered(ctx context.Context, progress PurgeProgress, filters ...Filter) (int, error) {
+	var purged int
+	err := a.intercept(ctx, OpInfo{Name: "PurgeFiltered", Write: true}, func(ctx context.Context) error {
+		var err error
+		purged, err = a.purgeFiltered(ctx, progress, filters...)
+		return err
+	})
+	return purged, err
+}
+
+// purgeFiltered runs PurgeFiltered within the interceptors.
+func (a *adapter) purgeFiltered(ctx context.Context, progress PurgeProgress, filters ...Filter) (int, error) {
+	if err := a.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer a.end()
+
+	if len(filters) == 0 {
+		return 0, errors.New("at least one filter is required")
+	}
+
+	build, valueFilters, err := a.filterQuery(filters)
+	if err != nil {
+		return 0, err
+	}
+	queryCtx, cancel := a.withTimeout(ctx, opBulk)
+	lines, err := a.collectAll(queryCtx, build)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	var matched []action
+	for i := range lines {
+		if matchesFilters(lines[i], valueFilters) {
+			matched = append(matched, action{kind: actionDelete, line: &lines[i]})
+		}
+	}
+
+	deleted := 0
+	for start := 0; start < len(matched); start += purgeChunkSize {
+		chunk := matched[start:min(start+purgeChunkSize, len(matched))]
+		chunkCtx, cancel := a.withTimeout(ctx, opBulk)
+		err := a.commit(chunkCtx, a.do, chunk, ChangeEvent{Operation: OpPurgeFiltered})
+		cancel()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += len(chunk)
+		if progress != nil {
+			progress(deleted, len(matched))
+		}
+	}
+
+	return deleted, nil
+}
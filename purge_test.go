@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPurgeFiltered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_purge_filtered/id", RateLimit: 100000, RateBurst: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	var rules [][]string
+	for i := 0; i < 1200; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "tenant-42", "data", "read"})
+	}
+	rules = append(rules, []string{"alice", "tenant-1", "data", "read"})
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+
+	var calls, last int
+	deleted, err := a.PurgeFiltered(ctx, func(deleted, total int) {
+		calls++
+		last = deleted
+		if total != 1200 {
+			t.Errorf("Expected a total of 1200 rules; got %d", total)
+		}
+	}, Filter{FieldPath: []string{"v1"}, Op: EqualOp, Value: "tenant-42"})
+	if err != nil {
+		t.Fatalf("Expected PurgeFiltered() to be successful; got %v", err)
+	}
+	if deleted != 1200 || last != 1200 || calls != 3 {
+		t.Errorf("Expected 1200 rules deleted in 3 chunks; got %d rules, %d progress calls, last %d", deleted, calls, last)
+	}
+
+	lines, err := a.collectRules(ctx, a.collection.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].V1 != "tenant-1" {
+		t.Errorf("Expected only the rule of the other tenant to remain; got %v", lines)
+	}
+
+	if _, err := a.PurgeFiltered(ctx, nil); err == nil {
+		t.Error("Expected PurgeFiltered() without filters to fail")
+	}
+}
@@ -0,0 +1,250 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"gocloud.dev/docstore"
+)
+
+// GetPoliciesForSubject returns the stored "p" rules whose subject (v0) is sub.
+// The rules are read directly from storage, without loading the whole policy.
+func (a *adapter) GetPoliciesForSubject(ctx context.Context, sub string) ([][]string, error) {
+	return a.getPolicies(ctx, "GetPoliciesForSubject", 0, sub)
+}
+
+// GetPoliciesForObject returns the stored "p" rules whose object (v1) is obj.
+// The rules are read directly from storage, without loading the whole policy.
+func (a *adapter) GetPoliciesForObject(ctx context.Context, obj string) ([][]string, error) {
+	return a.getPolicies(ctx, "GetPoliciesForObject", 1, obj)
+}
+
+// GetPoliciesForAction returns the stored "p" rules whose action (v2) is act.
+// The rules are read directly from storage, without loading the whole policy.
+func (a *adapter) GetPoliciesForAction(ctx context.Context, act string) ([][]string, error) {
+	return a.getPolicies(ctx, "GetPoliciesForAction", 2, act)
+}
+
+// getPolicies runs the query of the named method within the interceptors.
+func (a *adapter) getPolicies(ctx context.Context, name string, fieldIndex int, value string) ([][]string, error) {
+	var policies [][]string
+	err := a.intercept(ctx, OpInfo{Name: name, Sec: "p", PType: "p"}, func(ctx context.Context) error {
+		var err error
+		policies, err = a.policiesAt(ctx, fieldIndex, value)
+		return err
+	})
+	return policies, err
+}
+
+// policiesAt returns the values of the stored "p" rules with the given value at fieldIndex,
+// sorted for a stable result. As with RemoveFilteredPolicy, an empty value matches any value.
+func (a *adapter) policiesAt(ctx context.Context, fieldIndex int, value string) ([][]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	lines, err := a.filteredRules(ctx, "p", fieldIndex, value)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(lines, compareRules)
+
+	policies := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		if err := a.resolveLine(ctx, &line); err != nil {
+			return nil, err
+		}
+		policies = append(policies, line.values())
+	}
+	return policies, nil
+}
+
+// DistinctValues returns the unique non-empty values of a rule field, such as "v0" for the
+// subjects of "p" rules or "v1" for the roles of "g" rules, sorted in ascending order.
+// The field may also be "ptype". If ptypes are given, only rules of those policy types are
+// considered.
+//
+// Only the requested field is read from storage, so providers that support projections
+// transfer a fraction of each document.
+func (a *adapter) DistinctValues(ctx context.Context, field string, ptypes ...string) ([]string, error) {
+	var values []string
+	err := a.intercept(ctx, OpInfo{Name: "DistinctValues"}, func(ctx context.Context) error {
+		if err := a.begin(); err != nil {
+			return err
+		}
+		defer a.end()
+
+		var err error
+		values, err = a.distinctValues(ctx, field, ptypes...)
+		return err
+	})
+	return values, err
+}
+
+// distinctValues reads the unique non-empty values of a rule field.
+func (a *adapter) distinctValues(ctx context.Context, field string, ptypes ...string) ([]string, error) {
+	index := valueIndex([]string{field})
+	if field != "ptype" && (index < 0 || (a.config.Schema != SchemaArray && index > 5)) {
+		return nil, fmt.Errorf("invalid rule field %q", field)
+	}
+	fieldPath := a.field(field)
+	if index >= 0 && a.config.Schema == SchemaArray {
+		fieldPath = a.field("values")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	var queries []*docstore.Query
+	if len(ptypes) == 0 {
+		for _, coll := range a.ruleCollections() {
+			queries = append(queries, a.scope(coll.Query()))
+		}
+	}
+	for _, ptype := range ptypes {
+		for _, coll := range a.ptypeCollections(ptype) {
+			queries = append(queries, a.scope(coll.Query()).Where(a.field("ptype"), EqualOp, ptype))
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, query := range queries {
+		iter := query.Get(ctx, fieldPath, a.field("ns"))
+		for {
+			var line CasbinRule
+			err := a.next(ctx, iter, &line)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				iter.Stop()
+				return nil, err
+			}
+			value := line.PType
+			if index >= 0 {
+				value = line.value(index)
+			}
+			if value != "" && value != outboxPType && value != schemaPType && value != lockPType && line.Namespace == a.config.Namespace {
+				seen[value] = struct{}{}
+			}
+		}
+		iter.Stop()
+	}
+
+	return sortedKeys(seen), nil
+}
+
+// SubjectsForObjectAction returns the subjects granted act on obj by the stored "p" rules
+// (with the subject, object and action in v0, v1 and v2), for access reviews that should
+// not load the whole policy. One level of grouping is resolved: the members of a granted
+// role (the v0 of the "g" rules with the role in v1) are returned with the role. The
+// subjects are sorted, and as with GetPoliciesForObject an empty value matches any value.
+func (a *adapter) SubjectsForObjectAction(ctx context.Context, obj, act string) ([]string, error) {
+	var subjects []string
+	err := a.intercept(ctx, OpInfo{Name: "SubjectsForObjectAction"}, func(ctx context.Context) error {
+		var err error
+		subjects, err = a.subjectsForObjectAction(ctx, obj, act)
+		return err
+	})
+	return subjects, err
+}
+
+// subjectsForObjectAction runs SubjectsForObjectAction within the interceptors.
+func (a *adapter) subjectsForObjectAction(ctx context.Context, obj, act string) ([]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	granted, err := a.filteredRules(ctx, "p", 1, obj, act)
+	if err != nil {
+		return nil, err
+	}
+	subjects := make(map[string]struct{}, len(granted))
+	for _, line := range granted {
+		if err := a.resolveLine(ctx, &line); err != nil {
+			return nil, err
+		}
+		subjects[line.value(0)] = struct{}{}
+	}
+	for _, role := range sortedKeys(subjects) {
+		members, err := a.filteredRules(ctx, "g", 1, role)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range members {
+			if err := a.resolveLine(ctx, &line); err != nil {
+				return nil, err
+			}
+			subjects[line.value(0)] = struct{}{}
+		}
+	}
+	return sortedKeys(subjects), nil
+}
+
+// PermissionsForSubject returns the stored "p" rules granted to sub, directly or through
+// one level of grouping: the rules of the roles of sub (the v1 of the "g" rules with sub in
+// v0) are returned with its own rules. The rules are sorted for a stable result.
+func (a *adapter) PermissionsForSubject(ctx context.Context, sub string) ([][]string, error) {
+	var permissions [][]string
+	err := a.intercept(ctx, OpInfo{Name: "PermissionsForSubject"}, func(ctx context.Context) error {
+		var err error
+		permissions, err = a.permissionsForSubject(ctx, sub)
+		return err
+	})
+	return permissions, err
+}
+
+// permissionsForSubject runs PermissionsForSubject within the interceptors.
+func (a *adapter) permissionsForSubject(ctx context.Context, sub string) ([][]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	roles, err := a.filteredRules(ctx, "g", 0, sub)
+	if err != nil {
+		return nil, err
+	}
+	var lines []CasbinRule
+	for _, subject := range append([]string{sub}, rolesOf(roles)...) {
+		granted, err := a.filteredRules(ctx, "p", 0, subject)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, granted...)
+	}
+	slices.SortFunc(lines, compareRules)
+	lines = slices.CompactFunc(lines, func(x, y CasbinRule) bool { return slices.Equal(x.values(), y.values()) })
+
+	policies := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		if err := a.resolveLine(ctx, &line); err != nil {
+			return nil, err
+		}
+		policies = append(policies, line.values())
+	}
+	return policies, nil
+}
+
+// rolesOf returns the distinct roles (v1) of the grouping rules.
+func rolesOf(lines []CasbinRule) []string {
+	roles := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		if role := line.value(1); role != "" {
+			roles[role] = struct{}{}
+		}
+	}
+	return sortedKeys(roles)
+}
@@ -0,0 +1,138 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestGetPoliciesFor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_queries/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		query func(context.Context, string) ([][]string, error)
+		value string
+		want  [][]string
+	}{
+		{"Subject", a.GetPoliciesForSubject, "data2_admin", [][]string{{"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}},
+		{"Object", a.GetPoliciesForObject, "data1", [][]string{{"alice", "data1", "read"}}},
+		{"Action", a.GetPoliciesForAction, "write", [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "write"}}},
+		{"NoMatch", a.GetPoliciesForSubject, "carol", [][]string{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query(ctx, tt.value)
+			if err != nil {
+				t.Fatalf("Expected GetPoliciesFor%s() to be successful; got %v", tt.name, err)
+			}
+			if !util.Array2DEquals(tt.want, got) {
+				t.Errorf("Expected %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDistinctValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, schema := range []Schema{SchemaColumns, SchemaArray} {
+		a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_distinct/id", Schema: schema})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+
+		for _, tt := range []struct {
+			field  string
+			ptypes []string
+			want   []string
+		}{
+			{"v0", []string{"p"}, []string{"alice", "bob", "data2_admin"}},
+			{"v1", []string{"g"}, []string{"data2_admin"}},
+			{"v0", nil, []string{"alice", "bob", "data2_admin"}},
+			{"v2", nil, []string{"read", "write"}},
+			{"ptype", nil, []string{"g", "p"}},
+		} {
+			got, err := a.DistinctValues(ctx, tt.field, tt.ptypes...)
+			if err != nil {
+				t.Fatalf("Expected DistinctValues() to be successful; got %v", err)
+			}
+			if !util.ArrayEquals(tt.want, got) {
+				t.Errorf("Expected distinct values of %s %v to be %v; got %v", tt.field, tt.ptypes, tt.want, got)
+			}
+		}
+
+		if _, err := a.DistinctValues(ctx, "id"); err == nil {
+			t.Error("Expected DistinctValues() to fail for an invalid field")
+		}
+		a.close()
+	}
+}
+
+func TestReverseQueries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_reverse_queries/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	for _, tt := range []struct {
+		obj, act string
+		want     []string
+	}{
+		{"data2", "read", []string{"alice", "data2_admin"}},
+		{"data2", "write", []string{"alice", "bob", "data2_admin"}},
+		{"data1", "read", []string{"alice"}},
+		{"data3", "read", []string{}},
+	} {
+		got, err := a.SubjectsForObjectAction(ctx, tt.obj, tt.act)
+		if err != nil {
+			t.Fatalf("Expected SubjectsForObjectAction() to be successful; got %v", err)
+		}
+		if !util.ArrayEquals(tt.want, got) {
+			t.Errorf("Expected the subjects with %s on %s to be %v; got %v", tt.act, tt.obj, tt.want, got)
+		}
+	}
+
+	got, err := a.PermissionsForSubject(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Expected PermissionsForSubject() to be successful; got %v", err)
+	}
+	want := [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}
+	if !util.Array2DEquals(want, got) {
+		t.Errorf("Expected %v; got %v", want, got)
+	}
+}
@@ -0,0 +1,114 @@
+package adapter
+
+import (
+	"context"
+	"io"
+
+	"gocloud.dev/docstore"
+)
+
+// checkQuota returns a [*QuotaError] if adding the rules would exceed Config.MaxRules or
+// Config.MaxTotalRules. With content-derived IDs, rules that are already stored are not
+// counted, so adding them again is never rejected.
+//
+// The stored rules are counted on each check, and rules buffered with Config.WriteBehind
+// are not counted until they are written.
+func (a *adapter) checkQuota(ctx context.Context, ptype string, rules [][]string) error {
+	if a.config.MaxRules <= 0 && a.config.MaxTotalRules <= 0 {
+		return nil
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	adding := len(rules)
+	if a.contentIDs() {
+		lines := make([]*CasbinRule, 0, len(rules))
+		seen := make(map[string]bool, len(rules))
+		for _, rule := range rules {
+			line := a.ruleLine(ptype, rule)
+			if !seen[line.ID] {
+				seen[line.ID] = true
+				lines = append(lines, &line)
+			}
+		}
+		found, err := a.exists(ctx, lines)
+		if err != nil {
+			return err
+		}
+		adding = 0
+		for _, line := range lines {
+			if !found[line.ID] {
+				adding++
+			}
+		}
+	}
+	if adding == 0 {
+		return nil
+	}
+
+	if a.config.MaxRules > 0 {
+		count, err := a.countRules(ctx, false)
+		if err != nil {
+			return err
+		}
+		if count+adding > a.config.MaxRules {
+			return &QuotaError{Scope: QuotaNamespace, Limit: a.config.MaxRules, Count: count, Adding: adding}
+		}
+	}
+	if a.config.MaxTotalRules > 0 {
+		count, err := a.countRules(ctx, true)
+		if err != nil {
+			return err
+		}
+		if count+adding > a.config.MaxTotalRules {
+			return &QuotaError{Scope: QuotaTotal, Limit: a.config.MaxTotalRules, Count: count, Adding: adding}
+		}
+	}
+	return nil
+}
+
+// countRules returns the number of stored rules of the configured namespace, or of every
+// namespace if all is true.
+func (a *adapter) countRules(ctx context.Context, all bool) (int, error) {
+	count := 0
+	for _, coll := range a.ruleCollections() {
+		query := coll.Query()
+		if !all {
+			query = a.scope(query)
+		}
+		var n int
+		err := a.retry(ctx, func() error {
+			var err error
+			n, err = a.countQuery(ctx, query, all)
+			return err
+		})
+		if err != nil {
+			return 0, a.redact(err)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// countQuery returns the number of rules matched by the query, reading only the fields
+// needed to tell rules apart from internal documents.
+func (a *adapter) countQuery(ctx context.Context, query *docstore.Query, all bool) (int, error) {
+	iter := a.readQuery(query).Get(ctx, a.field("ptype"), a.field("ns"))
+	defer iter.Stop()
+
+	n := 0
+	for {
+		var line CasbinRule
+		err := a.next(ctx, iter, &line)
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if line.isInternal() || (!all && line.Namespace != a.config.Namespace) {
+			continue
+		}
+		n++
+	}
+}
@@ -0,0 +1,48 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dbURL := "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db")
+
+	a, err := NewWithOption(ctx, &Config{URL: dbURL, Namespace: "tenant1", MaxRules: 2, MaxTotalRules: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	b, err := NewWithOption(ctx, &Config{URL: dbURL, Namespace: "tenant2", MaxRules: 2, MaxTotalRules: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	var qerr *QuotaError
+	if err := a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); !errors.As(err, &qerr) || qerr.Scope != QuotaNamespace {
+		t.Errorf("Expected AddPolicy() to exceed the namespace quota; got %v", err)
+	}
+	// Adding a stored rule again does not count against the quota.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Errorf("Expected adding a stored rule to be successful; got %v", err)
+	}
+
+	if err := b.AddPolicy("p", "p", []string{"dave", "data4", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	err = b.AddPolicy("p", "p", []string{"erin", "data5", "read"})
+	if !errors.As(err, &qerr) || qerr.Scope != QuotaTotal || qerr.Count != 3 || qerr.Adding != 1 {
+		t.Errorf("Expected AddPolicy() to exceed the total quota; got %v", err)
+	}
+	if lines, err := b.collectAll(ctx, nil); err != nil || len(lines) != 1 {
+		t.Errorf("Expected no rules to be added over the quota; got %v, %v", lines, err)
+	}
+}
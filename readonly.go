@@ -0,0 +1,25 @@
+package adapter
+
+import "errors"
+
+// ErrReadOnly is returned by the operations that change stored data when Config.ReadOnly
+// is set.
+var ErrReadOnly = errors.New("adapter is read-only")
+
+// WithReadOnly returns the option that sets Config.ReadOnly, so that loads work normally
+// while changes fail with ErrReadOnly, e.g. for replica consumers or for staging
+// environments pointed at production policy data.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.ReadOnly = true
+	}
+}
+
+// beginWrite registers an operation that changes stored data like begin, or returns
+// ErrReadOnly if the adapter is read-only.
+func (a *adapter) beginWrite() error {
+	if a.readOnly() {
+		return ErrReadOnly
+	}
+	return a.begin()
+}
@@ -0,0 +1,55 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestReadOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dbURL := "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db")
+
+	w, err := New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	w.close()
+
+	a, err := New(ctx, dbURL, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("Expected loads to work; got %v", err)
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("Expected the policy to be loaded")
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected AddPolicy() to fail with ErrReadOnly; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected RemovePolicy() to fail with ErrReadOnly; got %v", err)
+	}
+	if err := a.SavePolicy(e.GetModel()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected SavePolicy() to fail with ErrReadOnly; got %v", err)
+	}
+	if _, err := a.PurgeSubject(ctx, "alice", false); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected PurgeSubject() to fail with ErrReadOnly; got %v", err)
+	}
+	if rules, err := a.PurgeSubject(ctx, "alice", true); err != nil || len(rules) != 1 {
+		t.Errorf("Expected a dry run of PurgeSubject() to be successful; got %v, %v", rules, err)
+	}
+}
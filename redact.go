@@ -0,0 +1,147 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces credentials in errors and printed configurations, as in [url.URL.Redacted].
+const redacted = "xxxxx"
+
+// sensitiveParams are the substrings of URL query parameter names whose values are credentials.
+var sensitiveParams = []string{"password", "secret", "token", "key", "sig", "credential"}
+
+// isSensitiveParam reports whether the URL query parameter holds a credential.
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns the URL with its password and credential query parameters replaced.
+// URLs that cannot be parsed are replaced entirely.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	q := u.Query()
+	changed := false
+	for name := range q {
+		if isSensitiveParam(name) {
+			q.Set(name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
+
+// urlSecrets returns the credentials in the URL, both as written and unescaped.
+func urlSecrets(raw string) []string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	var secrets []string
+	if password, ok := u.User.Password(); ok && password != "" {
+		secrets = append(secrets, password, url.UserPassword("", password).String()[1:])
+	}
+	for name, values := range u.Query() {
+		if !isSensitiveParam(name) {
+			continue
+		}
+		for _, v := range values {
+			if v != "" {
+				secrets = append(secrets, v, url.QueryEscape(v))
+			}
+		}
+	}
+	return secrets
+}
+
+// redactedError is an error whose message has credentials removed. The original error
+// is kept for errors.Is, errors.As and gcerrors.Code.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError removes the credentials of the URLs from the message of err.
+func redactError(err error, urls ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	clean := msg
+	for _, raw := range urls {
+		for _, secret := range urlSecrets(raw) {
+			clean = strings.ReplaceAll(clean, secret, redacted)
+		}
+	}
+	if clean == msg {
+		return err
+	}
+	return &redactedError{msg: clean, err: err}
+}
+
+// redact removes the credentials of the configured URLs from the message of err.
+func (a *adapter) redact(err error) error {
+	urls := []string{a.config.URL, a.config.GroupingURL, a.config.HistoryURL, a.config.PendingURL, a.config.ArchiveURL, a.config.ChangeLogURL, a.config.ModelURL, a.config.SnapshotURL}
+	return redactError(err, append(urls, a.config.ShardURLs...)...)
+}
+
+// String returns the configuration with credentials redacted: the passwords and credential
+// query parameters of URLs, and the SubjectKey. Fields with zero values are omitted.
+func (c Config) String() string {
+	v := reflect.ValueOf(c)
+	var b strings.Builder
+	b.WriteString("Config{")
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.IsZero() {
+			continue
+		}
+		if n > 0 {
+			b.WriteString(", ")
+		}
+		n++
+		b.WriteString(field.Name + ": ")
+		switch {
+		case field.Name == "SubjectKey":
+			b.WriteString(redacted)
+		case strings.HasSuffix(field.Name, "URL"):
+			b.WriteString(redactURL(value.String()))
+		case strings.HasSuffix(field.Name, "URLs"):
+			urls := make([]string, value.Len())
+			for i := range urls {
+				urls[i] = redactURL(value.Index(i).String())
+			}
+			fmt.Fprintf(&b, "%v", urls)
+		case value.Kind() == reflect.Func || value.Kind() == reflect.Interface || value.Kind() == reflect.Slice || value.Kind() == reflect.Map:
+			fmt.Fprintf(&b, "%T", value.Interface())
+		default:
+			fmt.Fprintf(&b, "%v", value.Interface())
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// String returns a description of the adapter with its configuration, with credentials redacted.
+func (a *adapter) String() string {
+	return fmt.Sprintf("adapter{filtered: %t, config: %s}", a.filtered, a.config)
+}
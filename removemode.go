@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRuleNotFound is returned by RemovePolicy and RemovePolicies with RemoveRequireExisting
+// when a rule is not stored.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// RemoveMode determines how RemovePolicy and RemovePolicies handle rules that are not
+// stored. Providers differ in how they report the deletion of a missing document, so the
+// adapter applies the mode itself, the same way on every provider.
+type RemoveMode int
+
+const (
+	// RemoveIgnoreMissing removes the stored rules and ignores the missing ones, so that
+	// removals are idempotent. This is the default.
+	RemoveIgnoreMissing RemoveMode = iota
+	// RemoveRequireExisting fails the removal with ErrRuleNotFound, removing nothing, if any
+	// of the rules is not stored. The failed rules are reported by the [*BatchError], with
+	// their index in the rules argument.
+	//
+	// The rules are checked before the removal, so a rule removed concurrently by another
+	// instance after the check is not reported. Changes buffered with Config.WriteBehind are
+	// not checked.
+	RemoveRequireExisting
+)
+
+// String returns the name of the remove mode.
+func (m RemoveMode) String() string {
+	switch m {
+	case RemoveIgnoreMissing:
+		return "ignore-missing"
+	case RemoveRequireExisting:
+		return "require-existing"
+	default:
+		return fmt.Sprintf("RemoveMode(%d)", int(m))
+	}
+}
+
+// checkRemoved applies Config.RemoveMode to the rules about to be removed, returning a
+// [*BatchError] wrapping ErrRuleNotFound for the rules that are not stored.
+func (a *adapter) checkRemoved(ctx context.Context, ptype string, rules [][]string) error {
+	if a.config.RemoveMode != RemoveRequireExisting {
+		return nil
+	}
+	stored, err := a.storedKeys(ctx, ptype, rules)
+	if err != nil {
+		return err
+	}
+	var failures []RuleError
+	for i, rule := range rules {
+		if !stored[ruleKey(a.ruleLine(ptype, rule))] {
+			failures = append(failures, RuleError{Index: i, PType: ptype, Rule: rule, Err: ErrRuleNotFound})
+		}
+	}
+	if len(failures) > 0 {
+		return &BatchError{Err: ErrRuleNotFound, Failures: failures}
+	}
+	return nil
+}
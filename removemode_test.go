@@ -0,0 +1,83 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestRemoveMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each driver reports the deletion of missing documents its own way.
+	drivers := map[string]func(name string) string{
+		"mem": func(name string) string { return "mem://casbin_rule_remove_mode_" + name + "/id" },
+		"sqlitedoc": func(name string) string {
+			return "sqlitedoc://" + filepath.Join(t.TempDir(), name+".db") + "?table=casbin_rule&key_field=id"
+		},
+	}
+	strategies := map[string]IDStrategy{"hash": IDStrategyHash, "canonical": IDStrategyCanonical, "random": IDStrategyRandom}
+	for driver, url := range drivers {
+		for name, strategy := range strategies {
+			t.Run(driver+"/"+name, func(t *testing.T) {
+				testRemoveMode(ctx, t, url(name), strategy)
+			})
+		}
+	}
+}
+
+func testRemoveMode(ctx context.Context, t *testing.T, url string, strategy IDStrategy) {
+	t.Helper()
+	a, err := NewWithOption(ctx, &Config{URL: url, IDStrategy: strategy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing rules are ignored by default.
+	if err := a.RemovePolicy("p", "p", []string{"carol", "data1", "read"}); err != nil {
+		t.Errorf("Expected RemovePolicy() of a missing rule to be successful; got %v", err)
+	}
+	if err := a.RemovePolicies("p", "p", [][]string{{"carol", "data1", "read"}}); err != nil {
+		t.Errorf("Expected RemovePolicies() of a missing rule to be successful; got %v", err)
+	}
+
+	b, err := NewWithOption(ctx, &Config{URL: url, IDStrategy: strategy, RemoveMode: RemoveRequireExisting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(a, b)
+	if err := b.RemovePolicy("p", "p", []string{"carol", "data1", "read"}); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected ErrRuleNotFound; got %v", err)
+	}
+	err = b.RemovePolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"carol", "data1", "read"}})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, ErrRuleNotFound) {
+		t.Fatalf("Expected a BatchError wrapping ErrRuleNotFound; got %v", err)
+	}
+	if len(batchErr.Failures) != 1 || batchErr.Failures[0].Index != 1 {
+		t.Errorf("Expected the missing rule to be reported at index 1; got %+v", batchErr.Failures)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	if _, err := e.RemovePolicy("carol", "data1", "read"); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("Expected the enforcer to report ErrRuleNotFound; got %v", err)
+	}
+	if _, err := e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data2", "write"}})
+}
@@ -0,0 +1,125 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// defaultRetryBackoff is the initial backoff between retries of throttled requests.
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// ThrottleFunc reports whether err is a provider error that throttled the request and, if
+// the provider says when the request may be retried, how long to wait. err may wrap the
+// provider error.
+type ThrottleFunc func(err error) (throttled bool, retryAfter time.Duration)
+
+// newThrottle returns the function that recognizes throttling errors of the configured
+// provider. Errors with code ResourceExhausted are recognized for every provider.
+func newThrottle(config *Config) ThrottleFunc {
+	var fn ThrottleFunc
+	if u, err := url.Parse(config.URL); err == nil {
+		fn = driverFor(u.Scheme).Throttle
+	}
+	return func(err error) (bool, time.Duration) {
+		if fn != nil {
+			if throttled, retryAfter := fn(err); throttled {
+				return true, retryAfter
+			}
+		}
+		return gcerrors.Code(err) == gcerrors.ResourceExhausted, 0
+	}
+}
+
+// ThrottleStats are the counters of requests throttled by the provider.
+type ThrottleStats struct {
+	Throttled int64         // the number of requests that failed because they were throttled
+	Retries   int64         // the number of retries of throttled requests
+	Waited    time.Duration // the total time waited before retries
+}
+
+// throttleCounters accumulate the [ThrottleStats] of an adapter.
+type throttleCounters struct {
+	throttled atomic.Int64
+	retries   atomic.Int64
+	waited    atomic.Int64
+}
+
+// ThrottleStats returns the counters of requests throttled by the provider since the
+// adapter was opened.
+func (a *adapter) ThrottleStats() ThrottleStats {
+	return ThrottleStats{
+		Throttled: a.throttleStats.throttled.Load(),
+		Retries:   a.throttleStats.retries.Load(),
+		Waited:    time.Duration(a.throttleStats.waited.Load()),
+	}
+}
+
+// isThrottled reports whether err throttled the request, and the longest wait hinted by
+// the provider. Action list errors are throttled if any of their errors is.
+func (a *adapter) isThrottled(err error) (bool, time.Duration) {
+	var alerr docstore.ActionListError
+	if !errors.As(err, &alerr) || len(alerr) <= 1 {
+		return a.throttle(err)
+	}
+	var throttled bool
+	var retryAfter time.Duration
+	for _, e := range alerr {
+		if ok, after := a.throttle(e.Err); ok {
+			throttled, retryAfter = true, max(retryAfter, after)
+		}
+	}
+	return throttled, retryAfter
+}
+
+// retry calls fn until it succeeds, fails with an error that is not throttling, or the
+// retries of Config.MaxRetries are exhausted. A retry waits as long as the provider hints,
+// such as the Retry-After of Cosmos DB, and otherwise backs off exponentially from
+// Config.RetryBackoff. fn must be safe to repeat, which holds for the puts, deletes, updates
+// and reads of the adapter.
+//
+// If the circuit breaker of Config.BreakerThreshold is open, retry fails with ErrCircuitOpen
+// without calling fn, and otherwise records the outcome.
+func (a *adapter) retry(ctx context.Context, fn func() error) (err error) {
+	if err := a.breaker.allow(); err != nil {
+		return err
+	}
+	defer func() { a.breaker.record(err) }()
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		throttled, wait := a.isThrottled(err)
+		if !throttled {
+			return err
+		}
+		a.throttleStats.throttled.Add(1)
+		if attempt >= a.config.MaxRetries {
+			return err
+		}
+		if wait <= 0 {
+			backoff := a.config.RetryBackoff
+			if backoff <= 0 {
+				backoff = defaultRetryBackoff
+			}
+			wait = backoff << attempt
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		a.throttleStats.retries.Add(1)
+		a.throttleStats.waited.Add(int64(wait))
+	}
+}
@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryThrottled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errThrottled := errors.New("throttled")
+	registerDriver(t, "mem", Driver{
+		Throttle: func(err error) (bool, time.Duration) {
+			return errors.Is(err, errThrottled), time.Millisecond
+		},
+	})
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_retry/id", MaxRetries: 2, RetryBackoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	calls := 0
+	err = a.retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return errThrottled
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected retry() to be successful; got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls; got %d", calls)
+	}
+	// The hinted wait is used instead of the backoff.
+	want := ThrottleStats{Throttled: 2, Retries: 2, Waited: 2 * time.Millisecond}
+	if stats := a.ThrottleStats(); stats != want {
+		t.Errorf("Expected stats %+v; got %+v", want, stats)
+	}
+
+	calls = 0
+	err = a.retry(ctx, func() error {
+		calls++
+		return errThrottled
+	})
+	if !errors.Is(err, errThrottled) {
+		t.Errorf("Expected the throttling error once retries are exhausted; got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls; got %d", calls)
+	}
+
+	calls = 0
+	errOther := errors.New("other")
+	err = a.retry(ctx, func() error {
+		calls++
+		return errOther
+	})
+	if !errors.Is(err, errOther) || calls != 1 {
+		t.Errorf("Expected other errors not to be retried; got %v after %d calls", err, calls)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRoleGraph(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_role_graph/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if err := a.AddPolicies("g", "g", [][]string{{"alice", "admin"}, {"admin", "viewer"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("g", "g2", []string{"bob", "editor", "domain1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"admin", "data1", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := a.RoleGraph(ctx)
+	if err != nil {
+		t.Fatalf("Expected RoleGraph() to be successful; got %v", err)
+	}
+	want := &RoleGraph{
+		Nodes: []string{"admin", "alice", "bob", "editor", "viewer"},
+		Edges: []RoleEdge{
+			{PType: "g", From: "admin", To: "viewer"},
+			{PType: "g", From: "alice", To: "admin"},
+			{PType: "g2", From: "bob", To: "editor", Domain: "domain1"},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("Expected %+v; got %+v", want, g)
+	}
+	if g, err := a.RoleGraph(ctx, "g2"); err != nil || len(g.Edges) != 1 {
+		t.Errorf("Expected the graph of g2 only; got %+v, %v", g, err)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`{"ptype":"g2","from":"bob","to":"editor","domain":"domain1"}`)) {
+		t.Errorf("Expected the JSON edges; got %s", data)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatalf("Expected WriteDOT() to be successful; got %v", err)
+	}
+	wantDOT := `digraph roles {
+	"admin";
+	"alice";
+	"bob";
+	"editor";
+	"viewer";
+	"admin" -> "viewer" [label="g"];
+	"alice" -> "admin" [label="g"];
+	"bob" -> "editor" [label="g2 domain1"];
+}
+`
+	if got := buf.String(); got != wantDOT {
+		t.Errorf("Expected %q; got %q", wantDOT, got)
+	}
+}
@@ -0,0 +1,77 @@
+package adapter
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Schema determines how rule values are stored in documents.
+type Schema int
+
+const (
+	// SchemaColumns stores rule values in the fields v0, ..., v5. This is the default.
+	SchemaColumns Schema = iota
+	// SchemaArray stores rule values in a single values array field, which supports
+	// any number of values. Filters on rule values are evaluated client-side, so
+	// queries only need an index on ptype.
+	SchemaArray
+)
+
+// columns returns the first six rule values, as stored in the fields v0, ..., v5.
+func (c *CasbinRule) columns() [6]string {
+	if c.Values == nil {
+		return [...]string{c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+	}
+	var cols [6]string
+	copy(cols[:], c.Values)
+	return cols
+}
+
+// value returns the rule value at index i, or "" if there is none.
+func (c *CasbinRule) value(i int) string {
+	if values := c.values(); i < len(values) {
+		return values[i]
+	}
+	return ""
+}
+
+// matchesFieldValues reports whether the rule matches the filter used by RemoveFilteredPolicy,
+// where empty field values match any value.
+func (c *CasbinRule) matchesFieldValues(fieldIndex int, fieldValues ...string) bool {
+	for i, v := range fieldValues {
+		if v != "" && c.value(fieldIndex+i) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFilters reports whether the rule values match all the equality filters.
+func matchesFilters(line CasbinRule, filters []Filter) bool {
+	for _, f := range filters {
+		if line.value(valueIndex(f.FieldPath)) != f.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// valueIndex returns the index of the rule value referenced by a field path such as ["v2"], or -1.
+func valueIndex(fieldPath []string) int {
+	if len(fieldPath) != 1 || !strings.HasPrefix(fieldPath[0], "v") {
+		return -1
+	}
+	i, err := strconv.Atoi(fieldPath[0][1:])
+	if err != nil || i < 0 {
+		return -1
+	}
+	return i
+}
+
+// arrayLine returns the document for a rule stored with [SchemaArray].
+func arrayLine(ptype string, rule []string) CasbinRule {
+	line := CasbinRule{PType: ptype, Values: slices.Clone(rule)}
+	line.ID = generateID(line)
+	return line
+}
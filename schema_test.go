@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestArraySchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:    "mem://casbin_rule_array_schema/id",
+		Schema: SchemaArray,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_tenant_service.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddPolicies([][]string{
+		{"domain1", "alice", "data1", "read", "", "service1"},
+		{"domain1", "bob", "data2", "write", "accept", "service2"},
+		{"domain2", "alice", "data3", "read", "accept", "service1"},
+	}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+
+	line := a.ruleLine("p", []string{"domain1", "alice", "data1", "read", "", "service1"})
+	if err := a.collection.Get(ctx, &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.V0 != "" || len(line.Values) != 6 {
+		t.Errorf("Expected the rule values to be stored as an array; got %+v", line)
+	}
+
+	if err := e.LoadFilteredPolicy(Filter{FieldPath: []string{"v1"}, Value: "alice"}); err != nil {
+		t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"domain1", "alice", "data1", "read", "", "service1"},
+		{"domain2", "alice", "data3", "read", "accept", "service1"},
+	})
+	if err := e.LoadFilteredPolicy(Filter{FieldPath: []string{"v1"}, Op: ">", Value: "alice"}); err == nil {
+		t.Error("Expected LoadFilteredPolicy() with a range filter on rule values to fail")
+	}
+
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "domain1", "", "", "read"); err != nil {
+		t.Fatalf("Expected RemoveFilteredPolicy() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"domain1", "bob", "data2", "write", "accept", "service2"},
+		{"domain2", "alice", "data3", "read", "accept", "service1"},
+	})
+}
+
+func TestArraySchemaManyValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:    "mem://casbin_rule_array_schema_many/id",
+		Schema: SchemaArray,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	long := []string{"v0", "v1", "v2", "v3", "v4", "v5", "v6", "v7"}
+	if err := a.AddPolicies("p", "p", [][]string{long, long[:6]}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	oldRules, err := a.UpdateFilteredPolicies("p", "p", nil, 7, "v7")
+	if err != nil {
+		t.Fatalf("Expected UpdateFilteredPolicies() to be successful; got %v", err)
+	}
+	if want := [][]string{append([]string{"p"}, long...)}; !util.Array2DEquals(want, oldRules) {
+		t.Errorf("Expected rules with more than 6 values to be supported; got %v", oldRules)
+	}
+}
@@ -0,0 +1,210 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gocloud.dev/runtimevar"
+	"golang.org/x/time/rate"
+)
+
+// settings are the parts of the configuration that may change while the adapter is open,
+// set from the variable at Config.SettingsURL.
+type settings struct {
+	timeout   time.Duration
+	timeouts  *Timeouts
+	rateLimit float64
+	rateBurst int
+	limiter   *rate.Limiter // the limiter of rateLimit and rateBurst, or nil if rate limiting is disabled
+	readOnly  bool
+}
+
+// newSettings returns the settings of the configuration.
+func newSettings(config *Config) *settings {
+	return &settings{
+		timeout:   config.Timeout,
+		timeouts:  config.Timeouts,
+		rateLimit: config.RateLimit,
+		rateBurst: config.RateBurst,
+		limiter:   newLimiter(config.RateLimit, config.RateBurst),
+		readOnly:  config.ReadOnly,
+	}
+}
+
+// settingsDocument is the JSON document of the variable at Config.SettingsURL, e.g.
+//
+//	{"timeout": "10s", "timeouts": {"read": "5s", "write": "10s", "bulk": "5m"}, "rate_limit": 50, "rate_burst": 10, "read_only": true}
+//
+// Fields that are absent keep the value of the Config, including the kinds absent from
+// timeouts, which keep their timeout in Config.Timeouts, or the timeout if it is nil.
+type settingsDocument struct {
+	Timeout  *jsonDuration `json:"timeout"`
+	Timeouts *struct {
+		Read  *jsonDuration `json:"read"`
+		Write *jsonDuration `json:"write"`
+		Bulk  *jsonDuration `json:"bulk"`
+	} `json:"timeouts"`
+	RateLimit *float64 `json:"rate_limit"`
+	RateBurst *int     `json:"rate_burst"`
+	ReadOnly  *bool    `json:"read_only"`
+}
+
+// jsonDuration is a duration encoded in JSON as a string, e.g. "10s".
+type jsonDuration time.Duration
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(v)
+	return nil
+}
+
+// WithSettings returns the option that sets Config.SettingsURL, the runtimevar url of the
+// variable whose changes to the timeouts, rate limits and read-only flag apply live. The
+// runtimevar driver of the url must be imported, e.g. gocloud.dev/runtimevar/etcdvar.
+func WithSettings(url string) Option {
+	return func(c *Config) {
+		c.SettingsURL = url
+	}
+}
+
+// decodeSettings returns the settings of the configuration overridden by the value of the
+// variable. The limiter of prev is kept if the rate limit is unchanged, so that its tokens
+// are not reset.
+func decodeSettings(config *Config, value interface{}, prev *settings) (*settings, error) {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		// e.g. the map[string]interface{} of the json decoder
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	var doc settingsDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	s := newSettings(config)
+	if doc.Timeout != nil {
+		if *doc.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %v", time.Duration(*doc.Timeout))
+		}
+		s.timeout = time.Duration(*doc.Timeout)
+	}
+	if t := doc.Timeouts; t != nil {
+		timeouts := Timeouts{Read: s.timeout, Write: s.timeout, Bulk: s.timeout}
+		if config.Timeouts != nil {
+			timeouts = *config.Timeouts
+		}
+		if t.Read != nil {
+			timeouts.Read = time.Duration(*t.Read)
+		}
+		if t.Write != nil {
+			timeouts.Write = time.Duration(*t.Write)
+		}
+		if t.Bulk != nil {
+			timeouts.Bulk = time.Duration(*t.Bulk)
+		}
+		s.timeouts = &timeouts
+	}
+	if doc.RateLimit != nil {
+		s.rateLimit = *doc.RateLimit
+	}
+	if doc.RateBurst != nil {
+		s.rateBurst = *doc.RateBurst
+	}
+	if doc.ReadOnly != nil {
+		s.readOnly = *doc.ReadOnly
+	}
+	if prev != nil && s.rateLimit == prev.rateLimit && s.rateBurst == prev.rateBurst {
+		s.limiter = prev.limiter
+	} else {
+		s.limiter = newLimiter(s.rateLimit, s.rateBurst)
+	}
+	return s, nil
+}
+
+// openSettings opens the variable at Config.SettingsURL, applies its current value, and
+// watches it to apply changes until stop is called.
+func openSettings(ctx context.Context, config *Config, live *atomic.Pointer[settings]) (stop func(), err error) {
+	v, err := runtimevar.OpenVariable(ctx, config.SettingsURL)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := v.Latest(ctx)
+	if err != nil {
+		v.Close()
+		return nil, err
+	}
+	s, err := decodeSettings(config, snapshot.Value, live.Load())
+	if err != nil {
+		v.Close()
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	live.Store(s)
+
+	// The watcher does not reference the adapter, so that the finalizer of an adapter that
+	// is not closed still runs.
+	watchCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			snapshot, err := v.Watch(watchCtx)
+			if watchCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				// The previous settings stay in effect until the variable is valid again.
+				config.logger().Printf("watch settings error: %v", redactError(err, config.SettingsURL))
+				continue
+			}
+			s, err := decodeSettings(config, snapshot.Value, live.Load())
+			if err != nil {
+				config.logger().Printf("invalid settings: %v", err)
+				continue
+			}
+			live.Store(s)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		if err := v.Close(); err != nil {
+			config.logger().Printf("close settings error: %v", redactError(err, config.SettingsURL))
+		}
+	}, nil
+}
+
+// timeout returns the timeout of the operations on the adapter, Config.Timeout unless the
+// settings override it.
+func (a *adapter) timeout() time.Duration {
+	return a.live.Load().timeout
+}
+
+// limiter returns the rate limiter of write operations, or nil if rate limiting is disabled.
+func (a *adapter) limiter() *rate.Limiter {
+	return a.live.Load().limiter
+}
+
+// readOnly reports whether the adapter is read-only, per Config.ReadOnly unless the
+// settings override it.
+func (a *adapter) readOnly() bool {
+	return a.live.Load().readOnly
+}
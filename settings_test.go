@@ -0,0 +1,109 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/memblob"
+	"gocloud.dev/runtimevar"
+	"gocloud.dev/runtimevar/blobvar"
+	_ "gocloud.dev/runtimevar/constantvar"
+)
+
+var (
+	settingsBucketOnce sync.Once
+	settingsBucket     *blob.Bucket
+)
+
+// settingsVariable writes the value of a new settings variable, which is polled every 10ms,
+// and returns its runtimevar url and a function that changes its value.
+func settingsVariable(t *testing.T, value string) (string, func(value string)) {
+	t.Helper()
+	settingsBucketOnce.Do(func() {
+		settingsBucket = memblob.OpenBucket(nil)
+		runtimevar.DefaultURLMux().RegisterVariable("settingsblob", &blobvar.URLOpener{Bucket: settingsBucket})
+	})
+	key := "settings_" + randomID()
+	set := func(value string) {
+		t.Helper()
+		if err := settingsBucket.WriteAll(context.Background(), key, []byte(value), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set(value)
+	return "settingsblob://" + key + "?decoder=string&wait=10ms", set
+}
+
+// eventually waits until cond holds, failing the test with msg if it does not within a second.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	settingsURL, set := settingsVariable(t, `{"timeout": "5s", "read_only": true}`)
+	a, err := New(ctx, "mem://casbin_rule_settings/id", WithSettings(settingsURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if a.timeout() != 5*time.Second {
+		t.Errorf("Expected the settings to override the timeout; got %v", a.timeout())
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected the settings to make the adapter read-only; got %v", err)
+	}
+
+	// Changes of the variable apply without recreating the adapter.
+	set(`{"rate_limit": 10, "rate_burst": 2}`)
+	eventually(t, func() bool { return a.chunkSize(5) == 2 }, "Expected the new settings to apply")
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if a.timeout() != defaultTimeout {
+		t.Errorf("Expected the timeout of the configuration; got %v", a.timeout())
+	}
+	limiter := a.limiter()
+	set(`{"rate_limit": 10, "rate_burst": 2, "timeout": "1s"}`)
+	eventually(t, func() bool { return a.timeout() == time.Second }, "Expected the new timeout to apply")
+	if a.limiter() != limiter {
+		t.Error("Expected an unchanged rate limit to keep the limiter")
+	}
+
+	if _, err := decodeSettings(a.config, []byte(`{"timeout": "soon"}`), nil); err == nil {
+		t.Error("Expected an invalid timeout to fail")
+	}
+
+	// The kinds absent from timeouts keep their configured timeout.
+	s, err := decodeSettings(&Config{Timeout: time.Second}, []byte(`{"timeouts": {"bulk": "1m"}}`), nil)
+	if err != nil {
+		t.Fatalf("Expected decodeSettings() to be successful; got %v", err)
+	}
+	if want := (Timeouts{Read: time.Second, Write: time.Second, Bulk: time.Minute}); *s.timeouts != want {
+		t.Errorf("Expected the timeouts %+v; got %+v", want, *s.timeouts)
+	}
+	config := &Config{Timeouts: &Timeouts{Read: time.Second, Write: 2 * time.Second, Bulk: time.Minute}}
+	if s, err = decodeSettings(config, []byte(`{"timeouts": {"write": "5s"}}`), nil); err != nil {
+		t.Fatalf("Expected decodeSettings() to be successful; got %v", err)
+	}
+	if want := (Timeouts{Read: time.Second, Write: 5 * time.Second, Bulk: time.Minute}); *s.timeouts != want {
+		t.Errorf("Expected the timeouts %+v; got %+v", want, *s.timeouts)
+	}
+	if *config.Timeouts != (Timeouts{Read: time.Second, Write: 2 * time.Second, Bulk: time.Minute}) {
+		t.Errorf("Expected the configured timeouts to be unchanged; got %+v", *config.Timeouts)
+	}
+	if _, err := New(ctx, "mem://casbin_rule_settings_invalid/id", WithSettings("constant://?decoder=string&val=nope")); err == nil {
+		t.Error("Expected New() to fail with invalid settings")
+	}
+}
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown is returned by the operations of an adapter that is shut down.
+var ErrShutdown = errors.New("adapter is shut down")
+
+// operations tracks the operations in progress, so that Shutdown waits for them.
+type operations struct {
+	mu       sync.Mutex
+	shutdown bool
+	inflight sync.WaitGroup
+}
+
+// begin registers an operation, or returns ErrShutdown if the adapter is shut down. The
+// operation must call end when it completes.
+func (a *adapter) begin() error {
+	a.ops.mu.Lock()
+	defer a.ops.mu.Unlock()
+	if a.ops.shutdown {
+		return ErrShutdown
+	}
+	a.ops.inflight.Add(1)
+	return nil
+}
+
+// end marks an operation registered by begin as completed.
+func (a *adapter) end() {
+	a.ops.inflight.Done()
+}
+
+// Shutdown gracefully shuts the adapter down, e.g. on pod termination. It stops accepting
+// new operations (loads, changes, queries and the other methods reading or writing the
+// collections), which return ErrShutdown, waits for the operations in progress to
+// complete, including the delivery of their change events, flushes the changes buffered
+// with Config.WriteBehind, delivers the pending outbox events if Config.Outbox is set, and
+// closes the collections.
+//
+// If ctx is done before the operations in progress complete, Shutdown returns the error of
+// ctx and leaves the collections open for them.
+func (a *adapter) Shutdown(ctx context.Context) error {
+	a.ops.mu.Lock()
+	a.ops.shutdown = true
+	a.ops.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.ops.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown: %w", ctx.Err())
+	}
+
+	var err error
+	if flushErr := a.flush(ctx); flushErr != nil {
+		err = fmt.Errorf("shutdown: could not flush buffered changes: %w", flushErr)
+	}
+	if a.config.Outbox && a.collection != nil {
+		if _, deliverErr := a.deliverOutbox(ctx); deliverErr != nil {
+			err = errors.Join(err, fmt.Errorf("shutdown: could not deliver outbox: %w", deliverErr))
+		}
+	}
+	a.close()
+	return err
+}
@@ -0,0 +1,184 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// blockingNotifier blocks the changes it is notified of until released.
+type blockingNotifier struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingNotifier) Notify(context.Context, ChangeEvent) error {
+	b.entered <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &blockingNotifier{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_shutdown/id", Notifiers: []Notifier{n}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := make(chan error, 1)
+	go func() { added <- a.AddPolicy("p", "p", []string{"alice", "data1", "read"}) }()
+	<-n.entered
+
+	// The change in progress is awaited.
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer timeoutCancel()
+	if err := a.Shutdown(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Shutdown() to time out while a change is in progress; got %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Expected new changes to fail with ErrShutdown; got %v", err)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- a.Shutdown(ctx) }()
+	close(n.release)
+	if err := <-added; err != nil {
+		t.Errorf("Expected the change in progress to complete; got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Expected Shutdown() to be successful; got %v", err)
+	}
+	if err := a.HealthCheck(ctx); err == nil {
+		t.Error("Expected the collection to be closed")
+	}
+}
+
+func TestShutdownOutbox(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_shutdown_outbox/id", Outbox: true, Notifiers: []Notifier{n}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected Shutdown() to be successful; got %v", err)
+	}
+	if len(n.events) != 1 {
+		t.Errorf("Expected the pending outbox events to be delivered; got %v", n.events)
+	}
+}
+
+func TestShutdownEntryPoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_shutdown_entry_points/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(ctx, "mem://casbin_rule_shutdown_entry_points_other/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.close()
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	rules := [][]string{rule}
+	now := time.Now()
+	calls := map[string]func() error{
+		"HealthCheck":              func() error { return a.HealthCheck(ctx) },
+		"LoadPolicy":               func() error { return a.LoadPolicy(m) },
+		"LoadFilteredPolicy":       func() error { return a.LoadFilteredPolicy(m, Filter{FieldPath: []string{"v0"}, Value: "alice"}) },
+		"LoadPolicySection":        func() error { return a.LoadPolicySection(ctx, m, "p") },
+		"SavePolicy":               func() error { return a.SavePolicy(m) },
+		"AddPolicy":                func() error { return a.AddPolicy("p", "p", rule) },
+		"AddPolicies":              func() error { return a.AddPolicies("p", "p", rules) },
+		"RemovePolicy":             func() error { return a.RemovePolicy("p", "p", rule) },
+		"RemovePolicies":           func() error { return a.RemovePolicies("p", "p", rules) },
+		"RemoveFilteredPolicy":     func() error { return a.RemoveFilteredPolicy("p", "p", 0, "alice") },
+		"UpdatePolicy":             func() error { return a.UpdatePolicy("p", "p", rule, rule) },
+		"UpdatePolicies":           func() error { return a.UpdatePolicies("p", "p", rules, rules) },
+		"UpdateFilteredPolicies":   func() error { _, err := a.UpdateFilteredPolicies("p", "p", rules, 0, "alice"); return err },
+		"AddPolicyWithMeta":        func() error { return a.AddPolicyWithMeta(ctx, "p", "p", rule, map[string]string{"k": "v"}) },
+		"GetPolicyMeta":            func() error { _, err := a.GetPolicyMeta(ctx, "p", rule); return err },
+		"ListArchived":             func() error { _, err := a.ListArchived(ctx, now, now); return err },
+		"RestoreArchived":          func() error { _, err := a.RestoreArchived(ctx, now, now); return err },
+		"Backup":                   func() error { return a.Backup(ctx, "mem://", "backup") },
+		"Restore":                  func() error { return a.Restore(ctx, "mem://", "backup") },
+		"AddPoliciesWithResult":    func() error { _, err := a.AddPoliciesWithResult(ctx, "p", "p", rules); return err },
+		"RemovePoliciesWithResult": func() error { _, err := a.RemovePoliciesWithResult(ctx, "p", "p", rules); return err },
+		"Changes":                  func() error { _, err := a.Changes(ctx, ""); return err },
+		"Diff":                     func() error { _, _, err := a.Diff(ctx, m); return err },
+		"Sync":                     func() error { return a.Sync(ctx, m) },
+		"EnsureSchema":             func() error { return a.EnsureSchema(ctx) },
+		"RemoveFilteredPolicies":   func() error { return a.RemoveFilteredPolicies(ctx, []FilterGroup{{Sec: "p", PType: "p"}}) },
+		"FindRules":                func() error { _, err := a.FindRules(ctx, CasbinRule{PType: "p"}); return err },
+		"MigrateIDs":               func() error { _, err := a.MigrateIDs(ctx, IDStrategyHash, IDStrategyCanonical); return err },
+		"Lint":                     func() error { _, err := a.Lint(ctx, m); return err },
+		"ListRules":                func() error { _, _, err := a.ListRules(ctx, 10, ""); return err },
+		"SchemaVersion":            func() error { _, err := a.SchemaVersion(ctx); return err },
+		"Migrate":                  func() error { return a.Migrate(ctx) },
+		"SaveModel":                func() error { _, err := a.SaveModel(ctx, m); return err },
+		"ListModelVersions":        func() error { _, err := a.ListModelVersions(ctx); return err },
+		"LoadModel":                func() error { _, err := a.LoadModel(ctx); return err },
+		"LoadModelVersion":         func() error { _, err := a.LoadModelVersion(ctx, 1); return err },
+		"DeliverOutbox":            func() error { _, err := a.DeliverOutbox(ctx); return err },
+		"RunOutbox":                func() error { return a.RunOutbox(ctx, time.Hour) },
+		"Preload":                  func() error { a.Preload(ctx, m); <-a.Ready(); return a.ReadyErr() },
+		"UpdatePriority":           func() error { return a.UpdatePriority(ctx, "p", "p", rule, 1) },
+		"PurgeFiltered":            func() error { _, err := a.PurgeFiltered(ctx, nil); return err },
+		"GetPoliciesForSubject":    func() error { _, err := a.GetPoliciesForSubject(ctx, "alice"); return err },
+		"GetPoliciesForObject":     func() error { _, err := a.GetPoliciesForObject(ctx, "data1"); return err },
+		"GetPoliciesForAction":     func() error { _, err := a.GetPoliciesForAction(ctx, "read"); return err },
+		"DistinctValues":           func() error { _, err := a.DistinctValues(ctx, "v0"); return err },
+		"SubjectsForObjectAction":  func() error { _, err := a.SubjectsForObjectAction(ctx, "data1", "read"); return err },
+		"PermissionsForSubject":    func() error { _, err := a.PermissionsForSubject(ctx, "alice"); return err },
+		"RoleGraph":                func() error { _, err := a.RoleGraph(ctx); return err },
+		"StageAddPolicies":         func() error { _, err := a.StageAddPolicies(ctx, "p", "p", rules); return err },
+		"StageRemovePolicies":      func() error { _, err := a.StageRemovePolicies(ctx, "p", "p", rules); return err },
+		"ListPending":              func() error { _, err := a.ListPending(ctx); return err },
+		"Approve":                  func() error { return a.Approve(ctx, "change") },
+		"Reject":                   func() error { return a.Reject(ctx, "change") },
+		"ImportStream": func() error {
+			_, err := a.ImportStream(ctx, strings.NewReader("p,alice,data1,read\n"), FormatCSV)
+			return err
+		},
+		"ExportStream":        func() error { return a.ExportStream(ctx, io.Discard, FormatCSV) },
+		"RenameSubject":       func() error { return a.RenameSubject(ctx, "alice", "bob") },
+		"PurgeSubject":        func() error { _, err := a.PurgeSubject(ctx, "alice", true); return err },
+		"AddPolicyWithWindow": func() error { return a.AddPolicyWithWindow(ctx, "p", "p", rule, now, now.Add(time.Hour)) },
+		"Fingerprint":         func() error { _, err := a.Fingerprint(ctx); return err },
+		"VerifyAgainst":       func() error { _, err := other.VerifyAgainst(ctx, a); return err },
+		"ListVersions":        func() error { _, err := a.ListVersions(ctx); return err },
+		"LoadVersion":         func() error { return a.LoadVersion(ctx, m, 1) },
+		"Rollback":            func() error { return a.Rollback(ctx, 1) },
+		"LoadPolicyAt":        func() error { return a.LoadPolicyAt(ctx, m, now) },
+		"Flush":               func() error { return a.Flush(ctx) },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, ErrShutdown) {
+			t.Errorf("Expected %s() to fail with ErrShutdown after Shutdown(); got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// slowOp tracks an operation for the slow-operation log of Config.SlowThreshold.
+type slowOp struct {
+	a      *adapter
+	name   string
+	start  time.Time
+	filter string // the summary of the filter of the operation
+	rules  int    // the number of rules loaded or changed by the operation
+}
+
+// trackSlow starts tracking an operation changing the given number of rules, or returns
+// nil if slow-operation logging is disabled. Call done when the operation completes.
+func (a *adapter) trackSlow(name string, rules int) *slowOp {
+	if a.config.SlowThreshold <= 0 {
+		return nil
+	}
+	return &slowOp{a: a, name: name, start: time.Now(), rules: rules}
+}
+
+// done logs the operation if it took at least Config.SlowThreshold.
+func (op *slowOp) done() {
+	if op == nil {
+		return
+	}
+	elapsed := time.Since(op.start)
+	if elapsed < op.a.config.SlowThreshold {
+		return
+	}
+	filter := op.filter
+	if filter == "" {
+		filter = "none"
+	}
+	op.a.config.logger().Printf("slow %s took %v (filter: %s, rules: %d)", op.name, elapsed, filter, op.rules)
+}
+
+// addRules counts rules loaded or changed by the operation.
+func (op *slowOp) addRules(n int) {
+	if op != nil {
+		op.rules += n
+	}
+}
+
+// setFilter sets the filter summary of the operation.
+func (op *slowOp) setFilter(summary string) {
+	if op != nil {
+		op.filter = summary
+	}
+}
+
+// summarizeFilters summarizes the fields and operators of the filters. Values are left out,
+// since they may hold subjects, and indexes depend on the fields only.
+func summarizeFilters(filters []Filter) string {
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		parts = append(parts, strings.Join(f.FieldPath, ".")+" "+f.Op)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summarizeFieldValues summarizes the fields matched by the field values of a filtered
+// removal or update, like [summarizeFilters].
+func summarizeFieldValues(fieldIndex int, fieldValues []string) string {
+	parts := []string{"ptype " + EqualOp}
+	for i, value := range fieldValues {
+		if value != "" {
+			parts = append(parts, fmt.Sprintf("v%d %s", fieldIndex+i, EqualOp))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestSlowOperationLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	a, err := NewWithOption(ctx, &Config{
+		URL:           "mem://casbin_rule_slow/id",
+		SlowThreshold: time.Nanosecond,
+		Logger:        log.New(&buf, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "slow AddPolicies") || !strings.Contains(got, "rules: 2") {
+		t.Errorf("Expected the slow AddPolicies() to be logged with its rule count; got %q", got)
+	}
+
+	buf.Reset()
+	m, err := model.NewModelFromFile("testdata/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := Filter{FieldPath: []string{"v0"}, Op: EqualOp, Value: "alice"}
+	if err := a.LoadFilteredPolicy(m, filter); err != nil {
+		t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "slow LoadFilteredPolicy") || !strings.Contains(got, "filter: v0 =") || !strings.Contains(got, "rules: 1") {
+		t.Errorf("Expected the slow LoadFilteredPolicy() to be logged with its filter and rule count; got %q", got)
+	}
+	if strings.Contains(got, "alice") {
+		t.Errorf("Expected filter values not to be logged; got %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+package adapter
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+// snapshotKey is the key of the snapshot in the bucket at Config.SnapshotURL, prefixed by
+// the namespace.
+const snapshotKey = "casbin-policy.json.gz"
+
+// policySnapshot is the gzipped JSON object of the snapshot of the policy.
+type policySnapshot struct {
+	SavedAt time.Time    `json:"saved_at"`
+	Rules   []CasbinRule `json:"rules"`
+}
+
+// snapshotObject returns the key of the snapshot of the configured namespace.
+func (a *adapter) snapshotObject() string {
+	return a.namespacedID(snapshotKey)
+}
+
+// writeSnapshot writes the rules as the snapshot of the policy. The snapshot is a cache of
+// the stored policy, so failures are logged rather than returned.
+func (a *adapter) writeSnapshot(ctx context.Context, lines []CasbinRule) {
+	err := func() error {
+		w, err := a.bucket.NewWriter(ctx, a.snapshotObject(), &blob.WriterOptions{ContentType: "application/gzip"})
+		if err != nil {
+			return err
+		}
+		zw := gzip.NewWriter(w)
+		if err := json.NewEncoder(zw).Encode(policySnapshot{SavedAt: time.Now().UTC(), Rules: lines}); err != nil {
+			zw.Close()
+			w.Close()
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}()
+	if err != nil {
+		a.config.logger().Printf("write snapshot error: %v", a.redact(err))
+	}
+}
+
+// readSnapshot reads the snapshot of the policy, or returns nil if there is none.
+func (a *adapter) readSnapshot(ctx context.Context) (*policySnapshot, error) {
+	r, err := a.bucket.NewReader(ctx, a.snapshotObject(), nil)
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var s policySnapshot
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// loadSnapshot loads the snapshot of the policy into the model, if the snapshot cache is
+// enabled and this is the first unfiltered load of the adapter, and reports whether it did,
+// and whether the snapshot must be written after loading the policy from the collections.
+// The rules recorded in the change log since the snapshot was written, if enabled, are
+// replayed on top of it, and the snapshot is reconciled with the stored policy in the
+// background (see Config.SnapshotURL).
+//
+// If there is no snapshot, or it cannot be read, the policy is loaded from the collections
+// as usual, and the snapshot is written after the load.
+func (a *adapter) loadSnapshot(ctx context.Context, model model.Model) (loaded, write bool, err error) {
+	if a.bucket == nil || a.filtered || a.snapshotLoaded.Swap(true) {
+		return false, false, nil
+	}
+	s, err := a.readSnapshot(ctx)
+	if err != nil {
+		a.config.logger().Printf("read snapshot error: %v", a.redact(err))
+		return false, true, nil
+	}
+	if s == nil {
+		return false, true, nil
+	}
+	lines := s.Rules
+	if a.changes != nil {
+		if lines, err = a.replay(ctx, lines, s.SavedAt, time.Now()); err != nil {
+			a.config.logger().Printf("replay changes since snapshot error: %v", a.redact(err))
+			return false, false, nil
+		}
+	}
+	for _, line := range lines {
+		if err := loadPolicyLine(line, model); err != nil {
+			return false, false, err
+		}
+	}
+	if err := a.begin(); err == nil {
+		go func() {
+			defer a.end()
+			a.reconcileSnapshot(lines)
+		}()
+	}
+	return true, false, nil
+}
+
+// reconcileSnapshot compares the rules loaded from the snapshot with the stored policy. If
+// they differ, it rewrites the snapshot and calls Config.OnSnapshotStale, so that the
+// application reloads the policy.
+func (a *adapter) reconcileSnapshot(lines []CasbinRule) {
+	ctx, cancel := a.withTimeout(context.Background(), opBulk)
+	defer cancel()
+
+	stored, err := a.currentRules(ctx)
+	if err != nil {
+		a.config.logger().Printf("reconcile snapshot error: %v", err)
+		return
+	}
+	if sameRules(lines, stored) {
+		return
+	}
+	a.writeSnapshot(ctx, stored)
+	if a.config.OnSnapshotStale != nil {
+		a.config.OnSnapshotStale()
+	} else {
+		a.config.logger().Printf("the snapshot differs from the stored policy and was rewritten; reload the policy")
+	}
+}
+
+// currentRules reads the active rules of the configured namespace from the rule collections.
+func (a *adapter) currentRules(ctx context.Context) ([]CasbinRule, error) {
+	var lines []CasbinRule
+	now := time.Now()
+	for _, coll := range a.ruleCollections() {
+		_, err := a.loadQuery(ctx, a.scope(coll.Query()), nil, now, -1, func(line CasbinRule) error {
+			lines = append(lines, line)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.config.Priorities {
+		sortByPriority(lines)
+	}
+	return lines, nil
+}
+
+// sameRules reports whether x and y hold the same rules, regardless of their order.
+func sameRules(x, y []CasbinRule) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	counts := make(map[string]int, len(x))
+	for _, line := range x {
+		counts[ruleKey(line)]++
+	}
+	for _, line := range y {
+		key := ruleKey(line)
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+	return true
+}
+
+// openSnapshotBucket opens the bucket at Config.SnapshotURL.
+func openSnapshotBucket(ctx context.Context, config *Config) (*blob.Bucket, error) {
+	bucket, err := blob.OpenBucket(ctx, config.SnapshotURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not open snapshot bucket: %v", redactError(err, config.SnapshotURL))
+	}
+	return bucket, nil
+}
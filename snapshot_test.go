@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+
+	// Enable the file blob driver.
+	_ "gocloud.dev/blob/fileblob"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	config := func() *Config {
+		return &Config{URL: "mem://casbin_rule_snapshot/id", SnapshotURL: "file://" + dir}
+	}
+	a, err := NewWithOption(ctx, config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotKey)); err != nil {
+		t.Fatalf("Expected SavePolicy() to write the snapshot; got %v", err)
+	}
+
+	// Changes that are not saved with SavePolicy make the snapshot stale.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data2", "write"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := make(chan struct{}, 1)
+	c := config()
+	c.OnSnapshotStale = func() { stale <- struct{}{} }
+	b, err := NewWithOption(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(a, b)
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", b)
+	if err != nil {
+		t.Fatalf("Expected the first load to be served from the snapshot; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	select {
+	case <-stale:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the reconciliation to report the stale snapshot")
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "write"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	// The reconciliation rewrote the snapshot, so the next cold start is up to date.
+	s, err := b.readSnapshot(ctx)
+	if err != nil || s == nil || len(s.Rules) != 6 {
+		t.Errorf("Expected the rewritten snapshot to hold 6 rules; got %v, %v", s, err)
+	}
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_snapshot_missing/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_snapshot_missing/id", SnapshotURL: "file://" + dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(a, b)
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+
+	s, err := b.readSnapshot(ctx)
+	if err != nil || s == nil || len(s.Rules) != 1 {
+		t.Errorf("Expected the first load to write the snapshot; got %v, %v", s, err)
+	}
+}
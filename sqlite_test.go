@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/bartventer/casbin-go-cloud-adapter/drivers/sqlitedocstore"
+	"github.com/casbin/casbin/v2"
+)
+
+func TestSQLiteAdapter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dbURL := "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db") + "?table=casbin_rule&key_field=id"
+
+	a, err := New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	a.close()
+
+	// The policy persists in the database file.
+	a, err = NewFilteredAdapter(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	if _, err := e.AddPolicy("alice", "data1", "write"); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if _, err := e.UpdatePolicy([]string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+	if _, err := e.RemovePolicy("data2_admin", "data2", "write"); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+
+	if err := e.LoadFilteredPolicy(Filter{FieldPath: []string{"v0"}, Value: "alice"}); err != nil {
+		t.Fatalf("Expected LoadFilteredPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data1", "write"},
+	})
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data1", "write"},
+		{"bob", "data2", "read"},
+		{"data2_admin", "data2", "read"},
+	})
+}
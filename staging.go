@@ -0,0 +1,238 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// ErrStagingDisabled is returned by the staging APIs when no pending collection is configured.
+var ErrStagingDisabled = errors.New("policy change staging is disabled")
+
+// ChangeOp is the operation of a staged policy change.
+type ChangeOp string
+
+const (
+	// ChangeAdd adds the rules of the change.
+	ChangeAdd ChangeOp = "add"
+	// ChangeRemove removes the rules of the change.
+	ChangeRemove ChangeOp = "remove"
+)
+
+// PendingChange is a policy change that is staged until it is approved or rejected.
+type PendingChange struct {
+	ID        string       `docstore:"id"`
+	Op        ChangeOp     `docstore:"op"`
+	CreatedAt time.Time    `docstore:"created_at"`
+	Rules     []CasbinRule `docstore:"rules,omitempty"`
+	Namespace string       `docstore:"ns,omitempty"`
+}
+
+// stage records a pending change and returns its ID.
+func (a *adapter) stage(ctx context.Context, op ChangeOp, sec, ptype string, rules [][]string) (string, error) {
+	if a.pending == nil {
+		return "", ErrStagingDisabled
+	}
+	if len(rules) == 0 {
+		return "", errors.New("a change must contain at least one rule")
+	}
+
+	change := PendingChange{
+		ID:        randomID(),
+		Op:        op,
+		CreatedAt: time.Now().UTC(),
+		Rules:     make([]CasbinRule, 0, len(rules)),
+		Namespace: a.config.Namespace,
+	}
+	for _, rule := range rules {
+		change.Rules = append(change.Rules, a.newLine(sec, ptype, rule))
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	if err := a.pending.Create(ctx, &change); err != nil {
+		return "", fmt.Errorf("could not stage change: %w", err)
+	}
+	return change.ID, nil
+}
+
+// StageAddPolicies stages the addition of policy rules, and returns the ID of the pending
+// change. The rules are not stored until the change is approved.
+func (a *adapter) StageAddPolicies(ctx context.Context, sec, ptype string, rules [][]string) (string, error) {
+	var id string
+	err := a.intercept(ctx, OpInfo{Name: "StageAddPolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		var err error
+		id, err = a.stageAddPolicies(ctx, sec, ptype, rules)
+		return err
+	})
+	return id, err
+}
+
+// stageAddPolicies runs StageAddPolicies within the interceptors.
+func (a *adapter) stageAddPolicies(ctx context.Context, sec, ptype string, rules [][]string) (string, error) {
+	if err := a.beginWrite(); err != nil {
+		return "", err
+	}
+	defer a.end()
+
+	return a.stage(ctx, ChangeAdd, sec, ptype, rules)
+}
+
+// StageRemovePolicies stages the removal of policy rules, and returns the ID of the pending
+// change. The rules are not removed until the change is approved.
+func (a *adapter) StageRemovePolicies(ctx context.Context, sec, ptype string, rules [][]string) (string, error) {
+	var id string
+	err := a.intercept(ctx, OpInfo{Name: "StageRemovePolicies", Sec: sec, PType: ptype, Rules: len(rules), Write: true}, func(ctx context.Context) error {
+		var err error
+		id, err = a.stageRemovePolicies(ctx, sec, ptype, rules)
+		return err
+	})
+	return id, err
+}
+
+// stageRemovePolicies runs StageRemovePolicies within the interceptors.
+func (a *adapter) stageRemovePolicies(ctx context.Context, sec, ptype string, rules [][]string) (string, error) {
+	if err := a.beginWrite(); err != nil {
+		return "", err
+	}
+	defer a.end()
+
+	return a.stage(ctx, ChangeRemove, sec, ptype, rules)
+}
+
+// ListPending returns the pending changes, oldest first.
+func (a *adapter) ListPending(ctx context.Context) ([]PendingChange, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if a.pending == nil {
+		return nil, ErrStagingDisabled
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+	iter := a.scopeOther(a.pending.Query()).OrderBy("created_at", docstore.Ascending).Get(ctx)
+	defer iter.Stop()
+
+	changes := make([]PendingChange, 0)
+	for {
+		var change PendingChange
+		err := nextDoc(ctx, iter, &change)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if change.Namespace == a.config.Namespace {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// getPending reads a single pending change.
+func (a *adapter) getPending(ctx context.Context, changeID string) (*PendingChange, error) {
+	if a.pending == nil {
+		return nil, ErrStagingDisabled
+	}
+
+	change := PendingChange{ID: changeID}
+	if err := a.pending.Get(ctx, &change); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, fmt.Errorf("change %q not found", changeID)
+		}
+		return nil, err
+	}
+	if change.Namespace != a.config.Namespace {
+		return nil, fmt.Errorf("change %q not found", changeID)
+	}
+	return &change, nil
+}
+
+// Approve applies a pending change to the stored policy and discards it. The policy
+// loaded in enforcers is not changed; they must reload it to see the change.
+//
+// Applying a change is idempotent, so if Approve fails it can be called again.
+func (a *adapter) Approve(ctx context.Context, changeID string) error {
+	return a.intercept(ctx, OpInfo{Name: "Approve", Write: true}, func(ctx context.Context) error {
+		return a.approve(ctx, changeID)
+	})
+}
+
+// approve runs Approve within the interceptors.
+func (a *adapter) approve(ctx context.Context, changeID string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	change, err := a.getPending(ctx, changeID)
+	if err != nil {
+		return err
+	}
+
+	var actions []action
+	var event ChangeEvent
+	switch change.Op {
+	case ChangeAdd:
+		event.Operation = OpAddPolicies
+		for i := range change.Rules {
+			actions = append(actions, action{kind: actionPut, line: &change.Rules[i]})
+		}
+	case ChangeRemove:
+		event.Operation = OpRemovePolicies
+		lines, err := a.resolve(ctx, change.Rules)
+		if err != nil {
+			return err
+		}
+		for i := range lines {
+			actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+		}
+	default:
+		return fmt.Errorf("change %q has unknown operation %q", changeID, change.Op)
+	}
+	for _, line := range change.Rules {
+		event.Sec, event.PType = line.Sec, line.PType
+		event.Rules = append(event.Rules, line.values())
+	}
+	if err := a.commit(ctx, a.do, actions, event); err != nil {
+		return err
+	}
+
+	return a.pending.Delete(ctx, change)
+}
+
+// Reject discards a pending change without applying it.
+func (a *adapter) Reject(ctx context.Context, changeID string) error {
+	return a.intercept(ctx, OpInfo{Name: "Reject", Write: true}, func(ctx context.Context) error {
+		return a.reject(ctx, changeID)
+	})
+}
+
+// reject runs Reject within the interceptors.
+func (a *adapter) reject(ctx context.Context, changeID string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+
+	change, err := a.getPending(ctx, changeID)
+	if err != nil {
+		return err
+	}
+	return a.pending.Delete(ctx, change)
+}
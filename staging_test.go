@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestStaging(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_staging/id",
+		PendingURL: "mem://casbin_pending/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	addID, err := a.StageAddPolicies(ctx, "p", "p", [][]string{{"alice", "data1", "read"}})
+	if err != nil {
+		t.Fatalf("Expected StageAddPolicies() to be successful; got %v", err)
+	}
+	removeID, err := a.StageRemovePolicies(ctx, "p", "p", [][]string{{"bob", "data2", "write"}})
+	if err != nil {
+		t.Fatalf("Expected StageRemovePolicies() to be successful; got %v", err)
+	}
+
+	changes, err := a.ListPending(ctx)
+	if err != nil {
+		t.Fatalf("Expected ListPending() to be successful; got %v", err)
+	}
+	if len(changes) != 2 || changes[0].ID != addID || changes[1].ID != removeID {
+		t.Fatalf("Expected the two staged changes in order; got %+v", changes)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}})
+
+	if err := a.Approve(ctx, addID); err != nil {
+		t.Fatalf("Expected Approve() to be successful; got %v", err)
+	}
+	if err := a.Reject(ctx, removeID); err != nil {
+		t.Fatalf("Expected Reject() to be successful; got %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+
+	if changes, _ := a.ListPending(ctx); len(changes) != 0 {
+		t.Errorf("Expected no pending changes; got %+v", changes)
+	}
+	if err := a.Approve(ctx, removeID); err == nil {
+		t.Error("Expected Approve() of a rejected change to fail")
+	}
+
+	plain, err := New(ctx, "mem://casbin_rule_staging_disabled/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.close()
+	if _, err := plain.StageAddPolicies(ctx, "p", "p", [][]string{{"alice", "data1", "read"}}); !errors.Is(err, ErrStagingDisabled) {
+		t.Errorf("Expected ErrStagingDisabled; got %v", err)
+	}
+}
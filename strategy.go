@@ -0,0 +1,31 @@
+package adapter
+
+import "net/url"
+
+// StrategyFunc sets the settings recommended for a provider in the configuration, e.g. the
+// batch size of its write requests, its use of transactions and its read consistency.
+type StrategyFunc func(config *Config)
+
+// WithStrategy returns the option that applies the settings recommended for the provider of
+// Config.URL, detected from its scheme, instead of the generic settings shared by every
+// provider:
+//
+//   - Amazon DynamoDB: Config.BatchSize 25 (the BatchWriteItem limit) and strong reads.
+//   - Google Cloud Firestore: Config.BatchSize 500 (the commit limit) and strong reads.
+//   - MongoDB: Config.Transactions, which requires a replica set or a sharded cluster.
+//
+// The option must follow the option setting the URL, if any; the settings of the options
+// that follow it override the recommended ones. With NewWithOption, call
+// WithStrategy()(config) before setting overrides. Providers whose [Driver] has no
+// [StrategyFunc] keep the generic settings.
+func WithStrategy() Option {
+	return func(c *Config) {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return // reported by NewWithOption
+		}
+		if fn := driverFor(u.Scheme).Strategy; fn != nil {
+			fn(c)
+		}
+	}
+}
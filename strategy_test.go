@@ -0,0 +1,50 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestWithStrategy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registerDriver(t, "mem", Driver{
+		Strategy: func(config *Config) {
+			config.BatchSize = 2
+			config.ReadConsistency = ConsistencyStrong
+		},
+	})
+
+	a, err := New(ctx, "mem://casbin_rule_strategy/id", WithStrategy(), func(c *Config) {
+		c.ReadConsistency = ConsistencyEventual
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if a.config.BatchSize != 2 || a.config.ReadConsistency != ConsistencyEventual {
+		t.Errorf("Expected the strategy of the provider with the overrides; got batch size %d and %v consistency", a.config.BatchSize, a.config.ReadConsistency)
+	}
+	if got := a.chunkSize(5); got != 2 {
+		t.Errorf("Expected action lists of the batch size; got %d", got)
+	}
+
+	// Batches larger than the batch size are written in several action lists.
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}})
+
+	config := &Config{URL: "unregistered://casbin_rule_strategy/id"}
+	WithStrategy()(config)
+	if config.BatchSize != 0 || config.ReadConsistency != ConsistencyDefault {
+		t.Errorf("Expected the generic settings without a registered strategy; got %+v", config)
+	}
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestImportStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, tt := range []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{"CSV", FormatCSV, "# seed\np, alice, data1, read\n\np, bob, data2, write\ng, alice, data2_admin\n"},
+		{"JSONL", FormatJSONL, `["p","alice","data1","read"]` + "\n" + `["p","bob","data2","write"]` + "\n" + `["g","alice","data2_admin"]` + "\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(ctx, "mem://casbin_rule_import_"+strings.ToLower(tt.name)+"/id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.close()
+
+			var progress []int
+			n, err := a.ImportStream(ctx, strings.NewReader(tt.input), tt.format,
+				WithImportChunkSize(2),
+				WithImportProgress(func(imported int) { progress = append(progress, imported) }),
+			)
+			if err != nil {
+				t.Fatalf("Expected ImportStream() to be successful; got %v", err)
+			}
+			if n != 3 || fmt.Sprint(progress) != "[2 3]" {
+				t.Errorf("Expected 3 rules imported in chunks of 2; got %d, progress %v", n, progress)
+			}
+
+			e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+			if ok, _ := e.HasGroupingPolicy("alice", "data2_admin"); !ok {
+				t.Error("Expected the grouping rule to be imported")
+			}
+		})
+	}
+
+	a, err := New(ctx, "mem://casbin_rule_import_invalid/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if _, err := a.ImportStream(ctx, strings.NewReader("p, alice, data1, read\np\n"), FormatCSV); err == nil {
+		t.Error("Expected ImportStream() to fail on a rule without values")
+	}
+}
+
+func TestExportStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := New(ctx, "mem://casbin_rule_export/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data, with comma", "write"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "data2_admin"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		var first, second strings.Builder
+		if err := a.ExportStream(ctx, &first, format); err != nil {
+			t.Fatalf("Expected ExportStream() to be successful; got %v", err)
+		}
+		if err := a.ExportStream(ctx, &second, format); err != nil {
+			t.Fatalf("Expected ExportStream() to be successful; got %v", err)
+		}
+		if first.String() != second.String() {
+			t.Errorf("Expected a deterministic export; got %q and %q", first.String(), second.String())
+		}
+		if got := strings.Count(first.String(), "\n"); got != 3 {
+			t.Errorf("Expected 3 exported rules; got %d in %q", got, first.String())
+		}
+
+		b, err := New(ctx, fmt.Sprintf("mem://casbin_rule_export_%d/id", format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.close()
+		if _, err := b.ImportStream(ctx, strings.NewReader(first.String()), format); err != nil {
+			t.Fatalf("Expected ImportStream() to be successful; got %v", err)
+		}
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicy(t, e, rules)
+	}
+}
@@ -0,0 +1,194 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gocloud.dev/docstore"
+)
+
+// subjectIndexes returns the indexes of the rule values holding subjects for a policy
+// type: the subject of a "p" rule, and both the user and the role of a "g" rule.
+func subjectIndexes(ptype string) []int {
+	if strings.HasPrefix(ptype, "g") {
+		return []int{0, 1}
+	}
+	return []int{0}
+}
+
+// subjectRules returns the stored rules that hold the subject in one of their subject positions.
+func (a *adapter) subjectRules(ctx context.Context, subject string) ([]CasbinRule, error) {
+	ptypes, err := a.distinctValues(ctx, "ptype")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var lines []CasbinRule
+	for _, ptype := range ptypes {
+		for _, i := range subjectIndexes(ptype) {
+			matched, err := a.filteredRules(ctx, ptype, i, subject)
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range matched {
+				if _, ok := seen[line.ID]; !ok {
+					seen[line.ID] = struct{}{}
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	slices.SortFunc(lines, compareRules)
+	return lines, nil
+}
+
+// RenameSubject replaces the subject oldName with newName in every stored rule: the
+// subject of "p" rules, and the user or role of "g" rules. With content-derived IDs the
+// renamed rules get new IDs; otherwise their IDs are kept.
+//
+// The rules are rewritten in a single batch, which is rolled back if any write fails.
+func (a *adapter) RenameSubject(ctx context.Context, oldName, newName string) error {
+	return a.intercept(ctx, OpInfo{Name: "RenameSubject", Write: true}, func(ctx context.Context) error {
+		return a.renameSubject(ctx, oldName, newName)
+	})
+}
+
+// renameSubject runs RenameSubject within the interceptors.
+func (a *adapter) renameSubject(ctx context.Context, oldName, newName string) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	if oldName == "" || newName == "" {
+		return errors.New("subject names must not be empty")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+
+	oldLines, err := a.subjectRules(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	newLines := make([]CasbinRule, 0, len(oldLines))
+	for _, old := range oldLines {
+		values := slices.Clone(old.values())
+		for _, i := range subjectIndexes(old.PType) {
+			if i < len(values) && (values[i] == oldName || i == 0 && values[i] == a.hashSubject(oldName)) {
+				values[i] = newName
+			}
+		}
+		line := a.ruleLine(old.PType, values)
+		line.Sec = old.Sec
+		if !a.contentIDs() {
+			line.ID = old.ID
+		}
+		newLines = append(newLines, line)
+	}
+
+	if len(oldLines) == 0 {
+		return nil
+	}
+	return a.commit(ctx, a.doWithRollback, swapActions(oldLines, newLines), ChangeEvent{Operation: OpRenameSubject})
+}
+
+// rulesContaining returns the stored rules that hold the value in any position.
+func (a *adapter) rulesContaining(ctx context.Context, value string) ([]CasbinRule, error) {
+	if a.config.Schema == SchemaArray {
+		lines, err := a.collectAll(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		hashed := a.hashSubject(value)
+		return slices.DeleteFunc(lines, func(line CasbinRule) bool {
+			values := line.values()
+			return !slices.Contains(values, value) && (len(values) == 0 || values[0] != hashed)
+		}), nil
+	}
+
+	seen := make(map[string]struct{})
+	var lines []CasbinRule
+	for i := 0; i <= 5; i++ {
+		fieldPath := a.field(fmt.Sprintf("v%d", i))
+		values := []string{value}
+		if hashed := a.hashSubject(value); i == 0 && hashed != value {
+			values = append(values, hashed) // subjects are stored hashed
+		}
+		for _, v := range values {
+			matched, err := a.collectAll(ctx, func(q *docstore.Query) *docstore.Query {
+				return q.Where(fieldPath, EqualOp, v)
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range matched {
+				if _, ok := seen[line.ID]; !ok {
+					seen[line.ID] = struct{}{}
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	return lines, nil
+}
+
+// PurgeSubject deletes every stored rule that holds the subject in any position, across
+// all policy types, and returns the deleted rules prefixed with their policy type. This
+// removes all traces of a subject, e.g. to honor an erasure request.
+//
+// If dryRun is true nothing is deleted, and the rules that would be deleted are returned.
+func (a *adapter) PurgeSubject(ctx context.Context, subject string, dryRun bool) ([][]string, error) {
+	var rules [][]string
+	err := a.intercept(ctx, OpInfo{Name: "PurgeSubject", Write: !dryRun}, func(ctx context.Context) error {
+		var err error
+		rules, err = a.purgeSubject(ctx, subject, dryRun)
+		return err
+	})
+	return rules, err
+}
+
+// purgeSubject runs PurgeSubject within the interceptors.
+func (a *adapter) purgeSubject(ctx context.Context, subject string, dryRun bool) ([][]string, error) {
+	begin := a.beginWrite
+	if dryRun {
+		begin = a.begin
+	}
+	if err := begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	if subject == "" {
+		return nil, errors.New("subject must not be empty")
+	}
+
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+
+	lines, err := a.rulesContaining(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(lines, compareRules)
+
+	rules := make([][]string, 0, len(lines))
+	actions := make([]action, 0, len(lines))
+	for i := range lines {
+		rules = append(rules, lines[i].toStringPolicy())
+		actions = append(actions, action{kind: actionDelete, line: &lines[i]})
+	}
+	if dryRun || len(actions) == 0 {
+		return rules, nil
+	}
+
+	if err := a.commit(ctx, a.do, actions, ChangeEvent{Operation: OpPurgeSubject}); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,108 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestRenameSubject(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, strategy := range []IDStrategy{IDStrategyHash, IDStrategyRandom} {
+		a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_rename/id", IDStrategy: strategy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+
+		if err := a.RenameSubject(ctx, "alice", "carol"); err != nil {
+			t.Fatalf("Expected RenameSubject() to be successful; got %v", err)
+		}
+		if err := a.RenameSubject(ctx, "data2_admin", "data_admin"); err != nil {
+			t.Fatalf("Expected RenameSubject() to be successful; got %v", err)
+		}
+
+		e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testGetPolicy(t, e, [][]string{
+			{"carol", "data1", "read"},
+			{"bob", "data2", "write"},
+			{"data_admin", "data2", "read"},
+			{"data_admin", "data2", "write"},
+		})
+		grouping, _ := e.GetGroupingPolicy()
+		if want := [][]string{{"carol", "data_admin"}}; !arrayEqualsWithoutOrder(want, grouping) {
+			t.Errorf("Expected grouping policy %v; got %v", want, grouping)
+		}
+		if ok, _ := e.Enforce("carol", "data2", "write"); !ok {
+			t.Error("Expected the renamed subject to keep its permissions")
+		}
+
+		lines, err := a.collectRules(ctx, a.collection.Query())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 5 {
+			t.Errorf("Expected the renamed rules to replace the old ones; got %d rules", len(lines))
+		}
+		a.close()
+	}
+}
+
+func TestPurgeSubject(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, schema := range []Schema{SchemaColumns, SchemaArray} {
+		a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_purge_subject/id", Schema: schema})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := a.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+		if err := a.AddPolicy("p", "p", []string{"bob", "alice", "read"}); err != nil {
+			t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+		}
+
+		want := [][]string{{"g", "alice", "data2_admin"}, {"p", "alice", "data1", "read"}, {"p", "bob", "alice", "read"}}
+		rules, err := a.PurgeSubject(ctx, "alice", true)
+		if err != nil {
+			t.Fatalf("Expected PurgeSubject() to be successful; got %v", err)
+		}
+		if !util.Array2DEquals(want, rules) {
+			t.Errorf("Expected a dry run to report %v; got %v", want, rules)
+		}
+		if lines, _ := a.collectRules(ctx, a.collection.Query()); len(lines) != 6 {
+			t.Errorf("Expected a dry run to keep all rules; got %d rules", len(lines))
+		}
+
+		rules, err = a.PurgeSubject(ctx, "alice", false)
+		if err != nil {
+			t.Fatalf("Expected PurgeSubject() to be successful; got %v", err)
+		}
+		if !util.Array2DEquals(want, rules) {
+			t.Errorf("Expected the purged rules to be %v; got %v", want, rules)
+		}
+		if rules, _ := a.PurgeSubject(ctx, "alice", true); len(rules) != 0 {
+			t.Errorf("Expected no rules to reference the purged subject; got %v", rules)
+		}
+		a.close()
+	}
+}
@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// activeAt reports whether the rule is within its validity window at time t. Rules
+// without a window are always active.
+func (c *CasbinRule) activeAt(t time.Time) bool {
+	ms := t.UnixMilli()
+	if c.EffectiveFrom != 0 && ms < c.EffectiveFrom {
+		return false
+	}
+	if c.ExpiresAt != 0 && ms >= c.ExpiresAt {
+		return false
+	}
+	return true
+}
+
+// AddPolicyWithWindow adds a policy rule that is only loaded between from (inclusive) and
+// until (exclusive), e.g. for temporary access grants. A zero time leaves that end of the
+// window open. The window is stored with millisecond precision.
+//
+// Rules outside their window are skipped by LoadPolicy, LoadFilteredPolicy and
+// LoadPolicySection, but stay stored until they are removed or, with Config.RuleTTL, expire.
+// Callers should reload the policy periodically for windows to take effect in a running
+// enforcer.
+//
+// The rule is added like AddPolicy: through the interceptors, with Config.WriteMode, the
+// quotas, Config.WriteBehind and the change notifications. With a zero until, Config.RuleTTL
+// ends the window as it does for AddPolicy.
+func (a *adapter) AddPolicyWithWindow(ctx context.Context, sec, ptype string, rule []string, from, until time.Time) error {
+	if !from.IsZero() && !until.IsZero() && !from.Before(until) {
+		return errors.New("the validity window must end after it starts")
+	}
+
+	var attrs ruleAttrs
+	if !from.IsZero() {
+		attrs.EffectiveFrom = from.UnixMilli()
+	}
+	if !until.IsZero() {
+		attrs.ExpiresAt = until.UnixMilli()
+	}
+	return a.intercept(ctx, OpInfo{Name: "AddPolicyWithWindow", Sec: sec, PType: ptype, Rules: 1, Write: true}, func(ctx context.Context) error {
+		return a.addPolicy(ctx, sec, ptype, rule, attrs)
+	})
+}
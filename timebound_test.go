@@ -0,0 +1,130 @@
+package adapter
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestTimeBoundPolicies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_time_bound/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	now := time.Now()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	for _, tt := range []struct {
+		rule        []string
+		from, until time.Time
+	}{
+		{[]string{"bob", "data1", "read"}, now.Add(-time.Hour), now.Add(time.Hour)},
+		{[]string{"carol", "data1", "read"}, time.Time{}, now.Add(-time.Minute)},
+		{[]string{"dave", "data1", "read"}, now.Add(time.Hour), time.Time{}},
+	} {
+		if err := a.AddPolicyWithWindow(ctx, "p", "p", tt.rule, tt.from, tt.until); err != nil {
+			t.Fatalf("Expected AddPolicyWithWindow() to be successful; got %v", err)
+		}
+	}
+	if err := a.AddPolicyWithWindow(ctx, "p", "p", []string{"eve", "data1", "read"}, now, now); err == nil {
+		t.Error("Expected AddPolicyWithWindow() to fail for an empty window")
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+	})
+
+	added, removed, err := a.Diff(ctx, e.GetModel())
+	if err != nil {
+		t.Fatalf("Expected Diff() to be successful; got %v", err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected rules outside their window not to be reported as drift; got added %v, removed %v", added, removed)
+	}
+
+	// Saving the loaded policy keeps the window of the active temporary grant.
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	line := a.ruleLine("p", []string{"bob", "data1", "read"})
+	if err := a.collection.Get(ctx, &line); err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(time.Hour).UnixMilli(); line.ExpiresAt != want {
+		t.Errorf("Expected SavePolicy() to preserve the validity window; got %d, want %d", line.ExpiresAt, want)
+	}
+}
+
+func TestTimeBoundPoliciesWritePath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	var ops []string
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_time_bound_write_path/id",
+		WriteBehind: time.Hour,
+		RuleTTL:     time.Hour,
+		Notifiers:   []Notifier{n},
+		Interceptors: []Interceptor{func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			ops = append(ops, op.Name)
+			return next(ctx)
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	now := time.Now()
+	from, until := now.Add(-time.Minute), now.Add(time.Minute)
+	if err := a.AddPolicyWithWindow(ctx, "p", "p", []string{"alice", "data1", "read"}, from, until); err != nil {
+		t.Fatalf("Expected AddPolicyWithWindow() to be successful; got %v", err)
+	}
+	if err := a.AddPolicyWithWindow(ctx, "p", "p", []string{"bob", "data1", "read"}, from, time.Time{}); err != nil {
+		t.Fatalf("Expected AddPolicyWithWindow() to be successful; got %v", err)
+	}
+	if !slices.Equal(ops, []string{"AddPolicyWithWindow", "AddPolicyWithWindow"}) {
+		t.Errorf("Expected AddPolicyWithWindow() to run the interceptors; got %v", ops)
+	}
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 0 {
+		t.Fatalf("Expected the rules to be buffered; got %v, %v", lines, err)
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Fatalf("Expected Flush() to be successful; got %v", err)
+	}
+	if len(n.events) != 2 {
+		t.Errorf("Expected an event for each rule; got %v", n.events)
+	}
+
+	for _, tt := range []struct {
+		user      string
+		expiresAt time.Time
+	}{
+		{"alice", until},            // the window ends before the TTL
+		{"bob", now.Add(time.Hour)}, // the TTL ends the open window
+	} {
+		line := a.ruleLine("p", []string{tt.user, "data1", "read"})
+		if err := a.collection.Get(ctx, &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.EffectiveFrom != from.UnixMilli() {
+			t.Errorf("Expected %s to be effective from %d; got %d", tt.user, from.UnixMilli(), line.EffectiveFrom)
+		}
+		if d := line.ExpiresAt - tt.expiresAt.UnixMilli(); d < -1000 || d > 1000 {
+			t.Errorf("Expected %s to expire at %d; got %d", tt.user, tt.expiresAt.UnixMilli(), line.ExpiresAt)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package adapter
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts are the timeouts of the kinds of operations, set by Config.Timeouts. A zero
+// timeout sets no deadline, so that the operations only end with the context of the caller.
+type Timeouts struct {
+	Read  time.Duration // the timeout of loads and queries
+	Write time.Duration // the timeout of changes to a few rules, e.g. AddPolicy or UpdatePolicy
+	Bulk  time.Duration // the timeout of operations that rewrite the policy or many rules, e.g. SavePolicy, Sync, or each chunk of an import or purge
+}
+
+// opKind is the kind of an operation, which selects its timeout.
+type opKind int
+
+const (
+	opRead opKind = iota
+	opWrite
+	opBulk
+)
+
+// withTimeout returns a context for an operation of the given kind, with the timeout of the
+// kind in Config.Timeouts, or Config.Timeout if Config.Timeouts is nil, unless the settings
+// override them.
+func (a *adapter) withTimeout(ctx context.Context, kind opKind) (context.Context, context.CancelFunc) {
+	live := a.live.Load()
+	timeout := live.timeout
+	if t := live.timeouts; t != nil {
+		switch kind {
+		case opRead:
+			timeout = t.Read
+		case opWrite:
+			timeout = t.Write
+		case opBulk:
+			timeout = t.Bulk
+		}
+		if timeout <= 0 {
+			return context.WithCancel(ctx)
+		}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
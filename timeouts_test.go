@@ -0,0 +1,46 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_timeouts/id", Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	deadline := func(kind opKind) time.Duration {
+		opCtx, opCancel := a.withTimeout(ctx, kind)
+		defer opCancel()
+		d, ok := opCtx.Deadline()
+		if !ok {
+			return 0
+		}
+		return time.Until(d).Round(time.Second)
+	}
+	if got := deadline(opBulk); got != time.Minute {
+		t.Errorf("Expected Timeout to apply without Timeouts; got %v", got)
+	}
+
+	a.config.Timeouts = &Timeouts{Read: time.Hour, Write: time.Second}
+	a.live.Store(newSettings(a.config))
+	if got := deadline(opRead); got != time.Hour {
+		t.Errorf("Expected the read timeout; got %v", got)
+	}
+	if got := deadline(opWrite); got != time.Second {
+		t.Errorf("Expected the write timeout; got %v", got)
+	}
+	if got := deadline(opBulk); got != 0 {
+		t.Errorf("Expected no deadline for a zero timeout; got %v", got)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+}
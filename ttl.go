@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"net/url"
+	"time"
+)
+
+// TTLField is the name of the document field read by providers with native TTL support,
+// when Config.RuleTTL is set. The storage deletes a rule once the time in this field has
+// passed:
+//
+//   - MongoDB: the field holds a date; EnsureSchema creates the TTL index.
+//   - Amazon DynamoDB: the field holds the Unix time in seconds; EnsureSchema enables TTL on the table.
+//   - Google Cloud Firestore: the field holds a timestamp; the TTL policy must be created with gcloud.
+//
+// Providers delete expired documents in the background, which can take a while, so expired
+// rules are also skipped on load.
+const TTLField = "expire_at"
+
+// setTTL sets the expiry of a rule added at time now, if Config.RuleTTL is set.
+func (a *adapter) setTTL(line *CasbinRule, now time.Time) {
+	if a.config.RuleTTL <= 0 {
+		return
+	}
+	expiry := now.Add(a.config.RuleTTL)
+	line.ExpiresAt = expiry.UnixMilli()
+	line.ExpireAt = ttlValue(a.config.URL, expiry)
+	line.Annotated = true
+}
+
+// ttlValue returns the value of [TTLField] in the format expected by the provider of the URL.
+func ttlValue(urlstr string, expiry time.Time) interface{} {
+	if u, err := url.Parse(urlstr); err == nil && u.Scheme == "dynamodb" {
+		return expiry.Unix()
+	}
+	return expiry.UTC()
+}
@@ -0,0 +1,71 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gocloud.dev/docstore"
+)
+
+func TestRuleTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_ttl/id", RuleTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	before := time.Now()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	line := a.ruleLine("p", []string{"alice", "data1", "read"})
+	if err := a.collection.Get(ctx, &line); err != nil {
+		t.Fatal(err)
+	}
+	expiry, ok := line.ExpireAt.(time.Time)
+	if !ok || expiry.Before(before.Add(time.Hour)) || expiry.After(time.Now().Add(time.Hour)) {
+		t.Errorf("Expected the TTL field to hold the expiry in an hour; got %v", line.ExpireAt)
+	}
+	if line.ExpiresAt != expiry.UnixMilli() {
+		t.Errorf("Expected expired rules to be skipped on load until the provider deletes them; got expires_at %d", line.ExpiresAt)
+	}
+
+	if got := ttlValue("dynamodb://casbin_rule?partition_key=id", expiry); got != expiry.Unix() {
+		t.Errorf("Expected DynamoDB TTL values in Unix seconds; got %v", got)
+	}
+}
+
+func TestEnsureSchema(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_ensure_schema/id", RuleTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.EnsureSchema(ctx); err != nil {
+		t.Errorf("Expected EnsureSchema() without a schema function to be a no-op; got %v", err)
+	}
+
+	var calls int
+	registerDriver(t, "mem", Driver{
+		Schema: func(_ context.Context, coll *docstore.Collection, config *Config) error {
+			calls++
+			if coll != a.collection || config.RuleTTL != time.Hour {
+				t.Error("Expected the schema function to receive the rules collection and configuration")
+			}
+			return nil
+		},
+	})
+
+	if err := a.EnsureSchema(ctx); err != nil {
+		t.Fatalf("Expected EnsureSchema() to be successful; got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the schema function to be called once; got %d", calls)
+	}
+}
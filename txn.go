@@ -0,0 +1,47 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"gocloud.dev/docstore"
+)
+
+// TxnFunc runs fn in a transaction of the provider of the collection, committing it if fn
+// succeeds and aborting it otherwise. The operations of fn are bound to the transaction by
+// the context passed to fn. fn may be run more than once if the provider retries the
+// transaction.
+type TxnFunc func(ctx context.Context, coll *docstore.Collection, fn func(ctx context.Context) error) error
+
+// txnFunc returns the [TxnFunc] of the driver of the configured URL.
+func txnFunc(config *Config) (TxnFunc, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse url: %w", err)
+	}
+	fn := driverFor(u.Scheme).Txn
+	if fn == nil {
+		return nil, fmt.Errorf("transactions are not supported for scheme %q", u.Scheme)
+	}
+	return fn, nil
+}
+
+// inTxn runs fn in a transaction if transactions are enabled, or directly otherwise.
+func (a *adapter) inTxn(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.txn == nil {
+		return fn(ctx)
+	}
+	return a.txn(ctx, a.collection, fn)
+}
+
+// doAtomically executes the actions in a transaction if transactions are enabled, or
+// otherwise with [adapter.doWithRollback], which undoes the actions if some of them fail.
+func (a *adapter) doAtomically(ctx context.Context, actions []action) error {
+	if a.txn == nil {
+		return a.doWithRollback(ctx, actions)
+	}
+	return a.txn(ctx, a.collection, func(ctx context.Context) error {
+		return a.do(ctx, actions)
+	})
+}
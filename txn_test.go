@@ -0,0 +1,60 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"gocloud.dev/docstore"
+)
+
+func TestTransactions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_txn_unsupported/id", Transactions: true}); err == nil {
+		t.Fatal("Expected transactions without a transaction function to fail")
+	}
+
+	var txns int
+	registerDriver(t, "mem", Driver{
+		Txn: func(ctx context.Context, _ *docstore.Collection, fn func(ctx context.Context) error) error {
+			txns++
+			return fn(ctx)
+		},
+	})
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_txn/id", Transactions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if txns != 1 {
+		t.Errorf("Expected SavePolicy() to write in a transaction; got %d transactions", txns)
+	}
+	if _, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data1", "write"}}, 0, "alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected UpdateFilteredPolicies() to be successful; got %v", err)
+	}
+	if txns != 2 {
+		t.Errorf("Expected UpdateFilteredPolicies() to swap in a transaction; got %d transactions", txns)
+	}
+
+	e, err = casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
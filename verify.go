@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+)
+
+// Divergence describes a policy type whose rules differ between two adapters.
+type Divergence struct {
+	PType            string
+	Fingerprint      string     // the fingerprint of the rules of this adapter (empty if none)
+	OtherFingerprint string     // the fingerprint of the rules of the other adapter (empty if none)
+	OnlyHere         [][]string // the rules stored by this adapter only
+	OnlyOther        [][]string // the rules stored by the other adapter only
+}
+
+// ruleDigest returns the canonical ID of the rule without its namespace, so that adapters
+// of different namespaces can be compared.
+func ruleDigest(line CasbinRule) string {
+	line.Namespace = ""
+	return canonicalID(line)
+}
+
+// fingerprint returns the hash of the sorted digests of the rules of a policy type.
+func fingerprint(ids []string) string {
+	h := sha256.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Fingerprint returns a hash of the stored rules of each policy type. The hashes depend
+// only on the rule content, not on the document IDs, the storage order or duplicates, so
+// two stores hold the same rules of a policy type exactly when its fingerprints match.
+func (a *adapter) Fingerprint(ctx context.Context) (map[string]string, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	sets, err := a.storedDigests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make(map[string]string, len(sets))
+	for ptype, set := range sets {
+		fingerprints[ptype] = fingerprint(set.ids)
+	}
+	return fingerprints, nil
+}
+
+// VerifyAgainst compares the stored rules with those of another adapter of this package,
+// for example the old and new stores of a dual-write migration or the replicas of a
+// multi-region deployment. It returns the policy types whose fingerprints differ, with the
+// rules stored by only one of the adapters, sorted by policy type. The stores are consistent
+// if the result is empty.
+//
+// Stored subjects are compared as stored, so adapters with Config.SubjectKey set must use
+// the same key.
+func (a *adapter) VerifyAgainst(ctx context.Context, other Adapter) ([]Divergence, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+
+	o, ok := other.(*adapter)
+	if !ok {
+		return nil, fmt.Errorf("cannot verify against adapter of type %T", other)
+	}
+	if err := o.begin(); err != nil {
+		return nil, fmt.Errorf("could not read the other adapter: %w", err)
+	}
+	defer o.end()
+
+	here, err := a.storedDigests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	there, err := o.storedDigests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the other adapter: %w", err)
+	}
+
+	ptypes := make([]string, 0, len(here)+len(there))
+	for ptype := range here {
+		ptypes = append(ptypes, ptype)
+	}
+	for ptype := range there {
+		ptypes = append(ptypes, ptype)
+	}
+	slices.Sort(ptypes)
+	ptypes = slices.Compact(ptypes)
+
+	divergences := make([]Divergence, 0)
+	for _, ptype := range ptypes {
+		d := Divergence{PType: ptype}
+		if ids := here[ptype].ids; len(ids) > 0 {
+			d.Fingerprint = fingerprint(ids)
+		}
+		if ids := there[ptype].ids; len(ids) > 0 {
+			d.OtherFingerprint = fingerprint(ids)
+		}
+		if d.Fingerprint == d.OtherFingerprint {
+			continue
+		}
+		d.OnlyHere = here[ptype].missingFrom(there[ptype])
+		d.OnlyOther = there[ptype].missingFrom(here[ptype])
+		divergences = append(divergences, d)
+	}
+
+	return divergences, nil
+}
+
+// digestSet is the rules of a policy type, by digest.
+type digestSet struct {
+	ids   []string              // sorted, without duplicates
+	rules map[string]CasbinRule // the rule of each digest
+}
+
+// missingFrom returns the sorted values of the rules of s that are not in other.
+func (s digestSet) missingFrom(other digestSet) [][]string {
+	missing := make([]CasbinRule, 0)
+	for _, id := range s.ids {
+		if _, ok := other.rules[id]; !ok {
+			missing = append(missing, s.rules[id])
+		}
+	}
+	slices.SortFunc(missing, compareRules)
+	rules := make([][]string, 0, len(missing))
+	for _, line := range missing {
+		rules = append(rules, line.values())
+	}
+	return rules
+}
+
+// storedDigests returns the stored rules grouped by policy type, by digest.
+func (a *adapter) storedDigests(ctx context.Context) (map[string]digestSet, error) {
+	ctx, cancel := a.withTimeout(ctx, opRead)
+	defer cancel()
+
+	lines, err := a.collectAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	sets := make(map[string]digestSet)
+	for _, line := range lines {
+		set, ok := sets[line.PType]
+		if !ok {
+			set = digestSet{rules: make(map[string]CasbinRule)}
+		}
+		digest := ruleDigest(line)
+		if _, ok := set.rules[digest]; !ok {
+			set.ids = append(set.ids, digest)
+			set.rules[digest] = line
+		}
+		sets[line.PType] = set
+	}
+	for _, set := range sets {
+		slices.Sort(set.ids)
+	}
+	return sets, nil
+}
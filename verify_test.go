@@ -0,0 +1,74 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestVerifyAgainst(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := New(ctx, "mem://casbin_rule_verify_a/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_verify_b/id", IDStrategy: IDStrategyRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, store := range []*adapter{a, b} {
+		if err := store.SavePolicy(e.GetModel()); err != nil {
+			t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+		}
+	}
+
+	divergences, err := a.VerifyAgainst(ctx, b)
+	if err != nil {
+		t.Fatalf("Expected VerifyAgainst() to be successful; got %v", err)
+	}
+	if len(divergences) != 0 {
+		t.Fatalf("Expected no divergences between stores with different ID strategies; got %+v", divergences)
+	}
+	fa, _ := a.Fingerprint(ctx)
+	fb, _ := b.Fingerprint(ctx)
+	if len(fa) != 2 || fa["p"] != fb["p"] || fa["g"] != fb["g"] {
+		t.Errorf("Expected matching fingerprints; got %v and %v", fa, fb)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := b.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := b.AddPolicy("g2", "g2", []string{"data1", "group1"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	divergences, err = a.VerifyAgainst(ctx, b)
+	if err != nil {
+		t.Fatalf("Expected VerifyAgainst() to be successful; got %v", err)
+	}
+	if len(divergences) != 2 {
+		t.Fatalf("Expected 2 divergent policy types; got %+v", divergences)
+	}
+	if d := divergences[0]; d.PType != "g2" || d.Fingerprint != "" || len(d.OnlyHere) != 0 || !util.Array2DEquals(d.OnlyOther, [][]string{{"data1", "group1"}}) {
+		t.Errorf("Expected g2 to be stored by the other adapter only; got %+v", d)
+	}
+	if d := divergences[1]; d.PType != "p" ||
+		!util.Array2DEquals(d.OnlyHere, [][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}}) ||
+		len(d.OnlyOther) != 0 {
+		t.Errorf("Expected the divergent p rules; got %+v", d)
+	}
+}
@@ -0,0 +1,304 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// ErrVersioningDisabled is returned by the versioning APIs when no history collection is configured.
+var ErrVersioningDisabled = errors.New("policy versioning is disabled")
+
+// ErrHistoryIncomplete is returned by LoadPolicyAt when the policy at the requested time
+// cannot be reconstructed, because a bulk change whose event carries no rules (e.g.
+// RenameSubject or PurgeSubject) was made between the latest version and that time.
+var ErrHistoryIncomplete = errors.New("policy history is incomplete")
+
+// PolicyVersion is a snapshot of the policy set, recorded on each SavePolicy.
+type PolicyVersion struct {
+	ID        string       `docstore:"id"`
+	Version   int64        `docstore:"version"`
+	CreatedAt time.Time    `docstore:"created_at"`
+	Rules     []CasbinRule `docstore:"rules,omitempty"`
+	Namespace string       `docstore:"ns,omitempty"`
+}
+
+// versionID returns the document ID for a version of the configured namespace, padded so
+// that IDs sort by version.
+func (a *adapter) versionID(version int64) string {
+	return a.namespacedID(fmt.Sprintf("%020d", version))
+}
+
+// latestVersion returns the most recent version number in the history collection, or 0 if there is none.
+func (a *adapter) latestVersion(ctx context.Context) (int64, error) {
+	iter := a.scopeOther(a.history.Query()).OrderBy("version", docstore.Descending).Limit(1).Get(ctx, "id", "version")
+	defer iter.Stop()
+
+	var v PolicyVersion
+	err := nextDoc(ctx, iter, &v)
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return v.Version, nil
+}
+
+// snapshot records the given rules as a new version in the history collection.
+func (a *adapter) snapshot(ctx context.Context, rules []CasbinRule) (int64, error) {
+	latest, err := a.latestVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not determine latest version: %w", err)
+	}
+
+	v := PolicyVersion{
+		ID:        a.versionID(latest + 1),
+		Version:   latest + 1,
+		CreatedAt: time.Now().UTC(),
+		Rules:     rules,
+		Namespace: a.config.Namespace,
+	}
+	// Create fails if a concurrent snapshot claimed the same version.
+	if err := a.history.Create(ctx, &v); err != nil {
+		return 0, fmt.Errorf("could not record version %d: %w", v.Version, err)
+	}
+
+	return v.Version, nil
+}
+
+// ListVersions returns the recorded policy versions, oldest first. The rules of each
+// version are not populated; use [adapter.LoadVersion] to read them.
+func (a *adapter) ListVersions(ctx context.Context) ([]PolicyVersion, error) {
+	if err := a.begin(); err != nil {
+		return nil, err
+	}
+	defer a.end()
+	return a.listVersions(ctx)
+}
+
+// listVersions reads the recorded policy versions, oldest first, without their rules.
+func (a *adapter) listVersions(ctx context.Context) ([]PolicyVersion, error) {
+	if a.history == nil {
+		return nil, ErrVersioningDisabled
+	}
+
+	iter := a.scopeOther(a.history.Query()).OrderBy("version", docstore.Ascending).Get(ctx, "id", "version", "created_at", "ns")
+	defer iter.Stop()
+
+	versions := make([]PolicyVersion, 0)
+	for {
+		var v PolicyVersion
+		err := nextDoc(ctx, iter, &v)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if v.Namespace == a.config.Namespace {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+// getVersion reads a single version, including its rules.
+func (a *adapter) getVersion(ctx context.Context, version int64) (*PolicyVersion, error) {
+	if a.history == nil {
+		return nil, ErrVersioningDisabled
+	}
+
+	v := PolicyVersion{ID: a.versionID(version)}
+	if err := a.history.Get(ctx, &v); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, fmt.Errorf("version %d not found", version)
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// LoadVersion loads the policy as it was recorded in the given version into the model.
+// The stored policy is not modified.
+func (a *adapter) LoadVersion(ctx context.Context, model model.Model, version int64) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	v, err := a.getVersion(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range v.Rules {
+		if err := a.loadLine(ctx, line, model); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rollback replaces the stored policy with the rules recorded in the given version.
+// The rollback itself is recorded as a new version.
+func (a *adapter) Rollback(ctx context.Context, version int64) error {
+	return a.intercept(ctx, OpInfo{Name: "Rollback", Write: true}, func(ctx context.Context) error {
+		return a.rollback(ctx, version)
+	})
+}
+
+// rollback runs Rollback within the interceptors.
+func (a *adapter) rollback(ctx context.Context, version int64) error {
+	if err := a.beginWrite(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	v, err := a.getVersion(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	if err := a.replaceRules(ctx, ChangeEvent{Operation: OpRollback}, v.Rules); err != nil {
+		return err
+	}
+
+	_, err = a.snapshot(ctx, v.Rules)
+	return err
+}
+
+// LoadPolicyAt loads the policy as it was at time t into the model, for example to find out
+// what a subject was allowed to do during an incident. The stored policy is not modified.
+//
+// The policy is reconstructed from the latest version recorded at or before t. If the change
+// log is enabled (see Config.ChangeLogURL), the changes recorded after that version up to t
+// are replayed on top of it, so changes made without SavePolicy are included; otherwise the
+// result is only as recent as the version. Bulk changes that are not recorded as a version
+// carry no rules (see [OpRenameSubject]), so if one of them was made in between, LoadPolicyAt
+// fails with ErrHistoryIncomplete rather than load a policy that never existed.
+func (a *adapter) LoadPolicyAt(ctx context.Context, model model.Model, t time.Time) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	lines, err := a.policyAt(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if err := a.loadLine(ctx, line, model); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// policyAt returns the rules stored at time t.
+func (a *adapter) policyAt(ctx context.Context, t time.Time) ([]CasbinRule, error) {
+	versions, err := a.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var base *PolicyVersion
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].CreatedAt.After(t) {
+			if base, err = a.getVersion(ctx, versions[i].Version); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if a.changes == nil {
+		if base == nil {
+			return nil, fmt.Errorf("no version recorded at or before %s", t.Format(time.RFC3339))
+		}
+		return base.Rules, nil
+	}
+	if base == nil {
+		base = &PolicyVersion{}
+	}
+	return a.replay(ctx, base.Rules, base.CreatedAt, t)
+}
+
+// replay applies the changes recorded after from up to and including to on the rules.
+// SavePolicy, Sync and Rollback changes are skipped, since their effect is recorded as a
+// version, and so are the changes that keep the rules, such as MigrateIDs. It fails with
+// ErrHistoryIncomplete on the other bulk changes, whose events carry no rules.
+func (a *adapter) replay(ctx context.Context, lines []CasbinRule, from, to time.Time) ([]CasbinRule, error) {
+	since := ""
+	if !from.IsZero() {
+		since = fmt.Sprintf("%020d", from.UnixNano())
+	}
+	it, err := a.changesSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Stop()
+
+	rules := make(map[string]CasbinRule, len(lines))
+	keys := make([]string, 0, len(lines))
+	put := func(line CasbinRule) {
+		key := ruleKey(line)
+		if _, ok := rules[key]; !ok {
+			keys = append(keys, key)
+		}
+		rules[key] = line
+	}
+	for _, line := range lines {
+		put(line)
+	}
+
+	for {
+		change, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		event := change.Event
+		if event.Timestamp.After(to) {
+			break
+		}
+		switch event.Operation {
+		case OpAddPolicy, OpAddPolicies:
+			for _, rule := range event.Rules {
+				put(a.newLine(event.Sec, event.PType, rule))
+			}
+		case OpRemovePolicy, OpRemovePolicies, OpRemoveFilteredPolicy:
+			for _, rule := range event.Rules {
+				delete(rules, ruleKey(a.ruleLine(event.PType, rule)))
+			}
+		case OpUpdatePolicy, OpUpdatePolicies, OpUpdateFilteredPolicies:
+			for _, rule := range event.OldRules {
+				delete(rules, ruleKey(a.ruleLine(event.PType, rule)))
+			}
+			for _, rule := range event.Rules {
+				put(a.newLine(event.Sec, event.PType, rule))
+			}
+		case OpSavePolicy, OpSync, OpRollback, OpMigrateIDs, OpUpdatePriority:
+		default:
+			return nil, fmt.Errorf("%w: %s at %s changed unrecorded rules", ErrHistoryIncomplete, event.Operation, event.Timestamp.Format(time.RFC3339Nano))
+		}
+	}
+
+	result := make([]CasbinRule, 0, len(rules))
+	for _, key := range keys {
+		if line, ok := rules[key]; ok {
+			result = append(result, line)
+			delete(rules, key) // the key is listed again if the rule was removed and added back
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,226 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestVersioning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:        "mem://casbin_rule_versioning/id",
+		HistoryURL: "mem://casbin_history/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	if _, err := e.AddPolicy("alice", "data2", "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+
+	versions, err := a.ListVersions(ctx)
+	if err != nil {
+		t.Fatalf("Expected ListVersions() to be successful; got %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+		t.Fatalf("Expected versions 1 and 2; got %+v", versions)
+	}
+
+	e.ClearPolicy()
+	if err := a.LoadVersion(ctx, e.GetModel(), 1); err != nil {
+		t.Fatalf("Expected LoadVersion() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	if err := a.Rollback(ctx, 1); err != nil {
+		t.Fatalf("Expected Rollback() to be successful; got %v", err)
+	}
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+
+	versions, err = a.ListVersions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Errorf("Expected the rollback to be recorded as a new version; got %d versions", len(versions))
+	}
+
+	if err := a.Rollback(ctx, 42); err == nil {
+		t.Error("Expected Rollback() to an unknown version to fail")
+	}
+}
+
+func TestVersioningDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_versioning_disabled/id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if _, err := a.ListVersions(ctx); !errors.Is(err, ErrVersioningDisabled) {
+		t.Errorf("Expected ErrVersioningDisabled; got %v", err)
+	}
+}
+
+func TestLoadPolicyAt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:          "mem://casbin_rule_policy_at/id",
+		HistoryURL:   "mem://casbin_history_policy_at/id",
+		ChangeLogURL: "mem://casbin_changes_policy_at/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.LoadPolicyAt(ctx, nil, time.Now()); err != nil {
+		t.Fatalf("Expected LoadPolicyAt() before any change to be successful; got %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	saved := time.Now()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data2", "write"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	added := time.Now()
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data3", "write"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		at   time.Time
+		want [][]string
+	}{
+		{"saved", saved, [][]string{
+			{"alice", "data1", "read"},
+			{"bob", "data2", "write"},
+			{"data2_admin", "data2", "read"},
+			{"data2_admin", "data2", "write"},
+		}},
+		{"added", added, [][]string{
+			{"alice", "data1", "read"},
+			{"alice", "data2", "write"},
+			{"bob", "data2", "write"},
+			{"data2_admin", "data2", "read"},
+			{"data2_admin", "data2", "write"},
+		}},
+		{"now", time.Now(), [][]string{
+			{"alice", "data2", "write"},
+			{"bob", "data3", "write"},
+			{"data2_admin", "data2", "read"},
+			{"data2_admin", "data2", "write"},
+		}},
+	} {
+		e.ClearPolicy()
+		if err := a.LoadPolicyAt(ctx, e.GetModel(), tt.at); err != nil {
+			t.Fatalf("Expected LoadPolicyAt(%s) to be successful; got %v", tt.name, err)
+		}
+		testGetPolicyWithoutOrder(t, e, tt.want)
+	}
+}
+
+func TestLoadPolicyAtBulkChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:          "mem://casbin_rule_policy_at_bulk/id",
+		HistoryURL:   "mem://casbin_history_policy_at_bulk/id",
+		ChangeLogURL: "mem://casbin_changes_policy_at_bulk/id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", "testdata/rbac_policy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	before := time.Now()
+	if err := a.RenameSubject(ctx, "bob", "robert"); err != nil {
+		t.Fatalf("Expected RenameSubject() to be successful; got %v", err)
+	}
+	renamed := time.Now()
+
+	e.ClearPolicy()
+	if err := a.LoadPolicyAt(ctx, e.GetModel(), before); err != nil {
+		t.Fatalf("Expected LoadPolicyAt() before the rename to be successful; got %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+	e.ClearPolicy()
+	if err := a.LoadPolicyAt(ctx, e.GetModel(), renamed); !errors.Is(err, ErrHistoryIncomplete) {
+		t.Errorf("Expected ErrHistoryIncomplete after the rename; got %v", err)
+	}
+
+	// Once the policy is saved again, the version records the renamed subject.
+	e.ClearPolicy()
+	if err := a.LoadPolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected LoadPolicy() to be successful; got %v", err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("Expected SavePolicy() to be successful; got %v", err)
+	}
+	e.ClearPolicy()
+	if err := a.LoadPolicyAt(ctx, e.GetModel(), time.Now()); err != nil {
+		t.Fatalf("Expected LoadPolicyAt() after the save to be successful; got %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"robert", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+	})
+}
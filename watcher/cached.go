@@ -0,0 +1,32 @@
+package watcher
+
+import "github.com/casbin/casbin/v2"
+
+// CachedEnforcer is an enforcer caching its Enforce decisions, like [casbin.CachedEnforcer]
+// and [casbin.SyncedCachedEnforcer].
+type CachedEnforcer interface {
+	LoadPolicy() error
+	InvalidateCache() error
+}
+
+var (
+	_ CachedEnforcer = (*casbin.CachedEnforcer)(nil)
+	_ CachedEnforcer = (*casbin.SyncedCachedEnforcer)(nil)
+)
+
+// BindCachedEnforcer sets the update callback of the watcher to reload the policy of the
+// enforcer and invalidate its cache, so that cached decisions never outlive a change made
+// by another instance. Call it after the SetWatcher of the enforcer.
+//
+// The cache is invalidated after the reload, as the LoadPolicy of the casbin enforcers
+// clears it before loading, so that decisions cached during the reload would be stale.
+func BindCachedEnforcer(w *Watcher, e CachedEnforcer) error {
+	return w.SetUpdateCallback(func(string) {
+		if err := e.LoadPolicy(); err != nil {
+			w.logger.Printf("watcher reload policy error: %v", err)
+		}
+		if err := e.InvalidateCache(); err != nil {
+			w.logger.Printf("watcher invalidate cache error: %v", err)
+		}
+	})
+}
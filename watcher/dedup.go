@@ -0,0 +1,42 @@
+package watcher
+
+import "container/list"
+
+// defaultDedupSize is the default number of message IDs remembered by a watcher.
+const defaultDedupSize = 1024
+
+// WithDedupSize sets the number of recently processed message IDs the watcher remembers
+// (defaults to 1024), so that messages redelivered by at-least-once transports, e.g. Amazon
+// SQS or RabbitMQ, do not call the update callback twice. Zero disables deduplication.
+func WithDedupSize(n int) Option {
+	return func(w *Watcher) {
+		w.dedupSize = n
+	}
+}
+
+// seenIDs is a set of the most recently added IDs, which evicts the least recently added ID
+// when it is full.
+type seenIDs struct {
+	size  int
+	order *list.List // of IDs, most recent first
+	ids   map[string]*list.Element
+}
+
+func newSeenIDs(size int) *seenIDs {
+	return &seenIDs{size: size, order: list.New(), ids: make(map[string]*list.Element, size)}
+}
+
+// add adds the ID to the set, and reports whether it was already in the set.
+func (s *seenIDs) add(id string) bool {
+	if e, ok := s.ids[id]; ok {
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.ids[id] = s.order.PushFront(id)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.ids, oldest.Value.(string))
+	}
+	return false
+}
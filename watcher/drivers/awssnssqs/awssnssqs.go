@@ -0,0 +1,7 @@
+// Package awssnssqs registers the [awssnssqs] driver with the pubsub package, for the watcher.
+package awssnssqs
+
+import (
+	// Import the pubsub package to register the awssnssqs driver.
+	_ "gocloud.dev/pubsub/awssnssqs"
+)
@@ -0,0 +1,7 @@
+// Package azuresb registers the [azuresb] driver with the pubsub package, for the watcher.
+package azuresb
+
+import (
+	// Import the pubsub package to register the azuresb driver.
+	_ "gocloud.dev/pubsub/azuresb"
+)
@@ -0,0 +1,7 @@
+// Package gcppubsub registers the [gcppubsub] driver with the pubsub package, for the watcher.
+package gcppubsub
+
+import (
+	// Import the pubsub package to register the gcppubsub driver.
+	_ "gocloud.dev/pubsub/gcppubsub"
+)
@@ -0,0 +1,7 @@
+// Package kafkapubsub registers the [kafkapubsub] driver with the pubsub package, for the watcher.
+package kafkapubsub
+
+import (
+	// Import the pubsub package to register the kafkapubsub driver.
+	_ "gocloud.dev/pubsub/kafkapubsub"
+)
@@ -0,0 +1,7 @@
+// Package mempubsub registers the [mempubsub] driver with the pubsub package, for the watcher.
+package mempubsub
+
+import (
+	// Import the pubsub package to register the mempubsub driver.
+	_ "gocloud.dev/pubsub/mempubsub"
+)
@@ -0,0 +1,7 @@
+// Package natspubsub registers the [natspubsub] driver with the pubsub package, for the watcher.
+package natspubsub
+
+import (
+	// Import the pubsub package to register the natspubsub driver.
+	_ "gocloud.dev/pubsub/natspubsub"
+)
@@ -0,0 +1,7 @@
+// Package rabbitpubsub registers the [rabbitpubsub] driver with the pubsub package, for the watcher.
+package rabbitpubsub
+
+import (
+	// Import the pubsub package to register the rabbitpubsub driver.
+	_ "gocloud.dev/pubsub/rabbitpubsub"
+)
@@ -0,0 +1,152 @@
+package watcher
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"github.com/casbin/casbin/v2"
+)
+
+// EnforcerOption configures the enforcer created by NewSyncedEnforcer.
+type EnforcerOption func(*enforcerConfig)
+
+type enforcerConfig struct {
+	subscriptionURL string
+	adapterOpts     []adapter.Option
+	watcherOpts     []Option
+}
+
+// WithSubscriptionURL sets the URL of the subscription the watcher receives messages from
+// (defaults to the pubsub URL, which only suits providers like mempubsub where topics and
+// subscriptions share URLs).
+func WithSubscriptionURL(url string) EnforcerOption {
+	return func(c *enforcerConfig) {
+		c.subscriptionURL = url
+	}
+}
+
+// WithAdapterOptions sets the options of the adapter.
+func WithAdapterOptions(opts ...adapter.Option) EnforcerOption {
+	return func(c *enforcerConfig) {
+		c.adapterOpts = append(c.adapterOpts, opts...)
+	}
+}
+
+// WithWatcherOptions sets the options of the watcher.
+func WithWatcherOptions(opts ...Option) EnforcerOption {
+	return func(c *enforcerConfig) {
+		c.watcherOpts = append(c.watcherOpts, opts...)
+	}
+}
+
+// SyncedEnforcer is a [casbin.SyncedEnforcer] over an adapter, which is kept in sync with
+// the enforcers of other instances by a watcher.
+type SyncedEnforcer struct {
+	*casbin.SyncedEnforcer
+	Adapter adapter.Adapter
+	Watcher *Watcher
+
+	adapterOnce sync.Once   // shuts the adapter down once, by Shutdown or Close
+	logger      *log.Logger // the logger of adapter close errors
+}
+
+// NewSyncedEnforcer creates the adapter of the docstore URL, a [casbin.SyncedEnforcer] with
+// the model at modelPath over the adapter, and a watcher publishing to the topic at
+// pubsubURL. The policy is loaded, changes made through the enforcer are published, and the
+// policy is reloaded when other instances publish changes. The drivers of both URLs must be
+// registered by blank imports.
+func NewSyncedEnforcer(ctx context.Context, modelPath, docstoreURL, pubsubURL string, opts ...EnforcerOption) (*SyncedEnforcer, error) {
+	var config enforcerConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	// Adapter close errors are logged with the Config.Logger of the adapter options.
+	var adapterConfig adapter.Config
+	for _, opt := range config.adapterOpts {
+		opt(&adapterConfig)
+	}
+	logger := cmp.Or(adapterConfig.Logger, log.Default())
+
+	a, err := adapter.New(ctx, docstoreURL, config.adapterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create adapter: %w", err)
+	}
+	e, err := casbin.NewSyncedEnforcer(modelPath, a)
+	if err != nil {
+		closeAdapter(a, logger)
+		return nil, fmt.Errorf("could not create enforcer: %w", err)
+	}
+	w, err := New(ctx, pubsubURL, cmp.Or(config.subscriptionURL, pubsubURL), config.watcherOpts...)
+	if err != nil {
+		closeAdapter(a, logger)
+		return nil, err
+	}
+	if err := e.SetWatcher(w); err != nil {
+		w.Close()
+		closeAdapter(a, logger)
+		return nil, err
+	}
+	// Casbin registers no callback for a persist.WatcherEx, and the callback it registers for
+	// other watchers reloads the embedded Enforcer without the lock of the SyncedEnforcer.
+	if err := w.SetUpdateCallback(func(string) {
+		if err := e.LoadPolicy(); err != nil {
+			w.logger.Printf("watcher reload policy error: %v", err)
+		}
+	}); err != nil {
+		w.Close()
+		closeAdapter(a, logger)
+		return nil, err
+	}
+	return &SyncedEnforcer{SyncedEnforcer: e, Adapter: a, Watcher: w, logger: logger}, nil
+}
+
+// Close closes the watcher and shuts the adapter down, logging errors.
+func (e *SyncedEnforcer) Close() {
+	e.Watcher.Close()
+	e.adapterOnce.Do(func() {
+		closeAdapter(e.Adapter, e.logger)
+	})
+}
+
+// closeAdapter shuts the adapter down, logging errors with logger.
+func closeAdapter(a adapter.Adapter, logger *log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	if err := shutdownAdapter(ctx, a); err != nil {
+		logger.Printf("adapter close error: %v", err)
+	}
+}
+
+// shutdownAdapter shuts the adapter down, if it supports it.
+func shutdownAdapter(ctx context.Context, a adapter.Adapter) error {
+	if s, ok := a.(interface{ Shutdown(context.Context) error }); ok {
+		return s.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts the enforcer down, e.g. on pod termination. It stops reloading
+// the policy on updates, shuts the adapter down, which rejects new changes and waits for
+// the changes in progress, waits for the enforcer to publish the changes in progress, and
+// shuts the watcher down, which flushes the messages being published.
+func (e *SyncedEnforcer) Shutdown(ctx context.Context) error {
+	if err := e.Watcher.SetUpdateCallback(nil); err != nil {
+		return err
+	}
+	var err error
+	e.adapterOnce.Do(func() {
+		err = shutdownAdapter(ctx, e.Adapter)
+	})
+	if err != nil {
+		return err
+	}
+	// The enforcer publishes a change while holding its lock, after the adapter stored it.
+	e.GetLock().Lock() //nolint:staticcheck // the empty critical section awaits the changes in progress
+	e.GetLock().Unlock()
+	return e.Watcher.Shutdown(ctx)
+}
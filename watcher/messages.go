@@ -0,0 +1,159 @@
+package watcher
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"time"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+var _ persist.WatcherEx = (*Watcher)(nil)
+
+// MessageVersion is the version of the [Message] format published by the watcher. It is
+// bumped on incompatible changes; compatible additions, e.g. new fields or operations, keep
+// the version.
+const MessageVersion = 1
+
+// OpUpdate is the operation of the messages published by [Watcher.Update], which reload the
+// whole policy.
+const OpUpdate adapter.Operation = "update"
+
+// Message is the JSON body of the messages published by the watcher, which describes the
+// change so that other enforcers may apply it without reloading the whole policy. Decode
+// message bodies with [DecodeMessage].
+type Message struct {
+	Version     int               `json:"version"`
+	ID          string            `json:"id,omitempty"` // unique per published message
+	Operation   adapter.Operation `json:"op"`
+	Sec         string            `json:"sec,omitempty"`
+	PType       string            `json:"ptype,omitempty"`
+	Rules       [][]string        `json:"rules,omitempty"`        // the added or removed rules
+	FieldIndex  int               `json:"field_index,omitempty"`  // of a filtered removal
+	FieldValues []string          `json:"field_values,omitempty"` // of a filtered removal
+	Origin      string            `json:"origin,omitempty"`       // the instance ID of the publishing watcher
+	Actor       string            `json:"actor,omitempty"`        // who made the change (see WithActor and Publish)
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// DecodeMessage decodes a message body passed to the update callback. Decoding is forward
+// compatible: unknown fields are ignored, and bodies that are not messages, e.g. from
+// watchers of other versions, decode as an [OpUpdate] message. Consumers must reload the
+// whole policy for operations they do not know.
+func DecodeMessage(body string) Message {
+	var m Message
+	if err := json.Unmarshal([]byte(body), &m); err != nil || m.Operation == "" {
+		return Message{Version: MessageVersion, Operation: OpUpdate}
+	}
+	return m
+}
+
+// Publish publishes a message describing a change made with ctx, e.g. with the
+// context-aware methods of the adapter rather than through the enforcer, which Casbin
+// notifies without a context. The message records the actor set on ctx with
+// [adapter.WithActor], or the actor of the watcher; the version, ID, origin and timestamp
+// of m are set by the watcher.
+func (w *Watcher) Publish(ctx context.Context, m Message) error {
+	m.Actor = cmp.Or(adapter.ActorFrom(ctx), w.actor)
+	return w.publish(m)
+}
+
+// publish publishes the message to the topic.
+func (w *Watcher) publish(m Message) error {
+	m.Version = MessageVersion
+	m.ID = randomID()
+	m.Origin = w.id
+	if m.Actor == "" {
+		m.Actor = w.actor
+	}
+	m.Timestamp = time.Now().UTC()
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return w.send(body)
+}
+
+// UpdateForAddPolicy implements [persist.WatcherEx].
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(Message{Operation: adapter.OpAddPolicy, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemovePolicy implements [persist.WatcherEx].
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(Message{Operation: adapter.OpRemovePolicy, Sec: sec, PType: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemoveFilteredPolicy implements [persist.WatcherEx].
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(Message{
+		Operation:   adapter.OpRemoveFilteredPolicy,
+		Sec:         sec,
+		PType:       ptype,
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	})
+}
+
+// UpdateForSavePolicy implements [persist.WatcherEx].
+func (w *Watcher) UpdateForSavePolicy(model.Model) error {
+	return w.publish(Message{Operation: adapter.OpSavePolicy})
+}
+
+// UpdateForAddPolicies implements [persist.WatcherEx].
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(Message{Operation: adapter.OpAddPolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies implements [persist.WatcherEx].
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(Message{Operation: adapter.OpRemovePolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// coalesce returns the body of one callback for the message bodies of a debounce window.
+// Rules added or removed by messages of the same operation and policy type are merged;
+// other messages are coalesced into an [OpUpdate] message.
+func coalesce(bodies []string) string {
+	if len(bodies) == 1 {
+		return bodies[0]
+	}
+	var merged *Message
+	for _, body := range bodies {
+		m := DecodeMessage(body)
+		var op adapter.Operation
+		switch m.Operation {
+		case adapter.OpAddPolicy, adapter.OpAddPolicies:
+			op = adapter.OpAddPolicies
+		case adapter.OpRemovePolicy, adapter.OpRemovePolicies:
+			op = adapter.OpRemovePolicies
+		}
+		if op == "" || (merged != nil && (merged.Operation != op || merged.Sec != m.Sec || merged.PType != m.PType)) {
+			merged = &Message{Operation: OpUpdate}
+			break
+		}
+		if merged == nil {
+			merged = &Message{Operation: op, Sec: m.Sec, PType: m.PType, Origin: m.Origin, Actor: m.Actor}
+		} else {
+			if merged.Origin != m.Origin {
+				merged.Origin = ""
+			}
+			if merged.Actor != m.Actor {
+				merged.Actor = ""
+			}
+		}
+		merged.Rules = append(merged.Rules, m.Rules...)
+		if m.Timestamp.After(merged.Timestamp) {
+			merged.Timestamp = m.Timestamp
+		}
+	}
+	merged.Version = MessageVersion
+	merged.ID = randomID()
+	if merged.Timestamp.IsZero() {
+		merged.Timestamp = time.Now().UTC()
+	}
+	body, _ := json.Marshal(merged)
+	return string(body)
+}
@@ -0,0 +1,321 @@
+// Package watcher provides a Casbin watcher built on top of gocloud.dev/pubsub, which keeps
+// the policies of several enforcers in sync. Each enforcer publishes a message to a topic
+// when it changes the policy, and reloads the policy when it receives a message from its
+// subscription to the topic.
+//
+// Like the docstore drivers of the adapter, the pubsub drivers are opt-in: blank-import the
+// subpackage of the provider from watcher/drivers, e.g.
+//
+//	import _ "github.com/bartventer/casbin-go-cloud-adapter/watcher/drivers/gcppubsub"
+package watcher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/persist"
+	"gocloud.dev/pubsub"
+)
+
+const (
+	defaultTimeout    time.Duration = 30 * time.Second
+	receiveBackoff    time.Duration = 100 * time.Millisecond // the backoff before the first retry of a failed receive, doubled on each retry
+	maxReceiveBackoff time.Duration = 30 * time.Second
+)
+
+// OriginMetadataKey is the metadata key of the instance ID of the watcher that published a
+// message. A watcher does not call the update callback for its own messages, so that an
+// enforcer does not reload its own changes.
+const OriginMetadataKey = "casbin-origin"
+
+// Watcher is a Casbin watcher that publishes policy updates to a pubsub topic, and calls
+// the update callback for the messages received from a subscription.
+type Watcher struct {
+	id     string
+	actor  string // the actor of the published messages, unless set per change
+	logger *log.Logger
+	topic  *pubsub.Topic
+	sub    *pubsub.Subscription
+	// reopens the subscription after it failed, if it was opened from a URL
+	reopen func(ctx context.Context) (*pubsub.Subscription, error)
+
+	debounce  time.Duration
+	dedupSize int
+	seen      *seenIDs // the IDs of the processed messages, if deduplicating
+
+	mu       sync.Mutex
+	callback func(string)
+	pending  []string    // the message bodies received in the debounce window
+	acks     []func()    // the acks of the messages of the debounce window
+	timer    *time.Timer // the timer of the debounce window
+	closed   bool
+	callMu   sync.Mutex // serializes the callbacks
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+var _ persist.Watcher = (*Watcher)(nil)
+
+// Option configures a watcher.
+type Option func(*Watcher)
+
+// WithInstanceID sets the instance ID of the watcher, which identifies its messages (defaults
+// to a random ID). Watchers must not share an instance ID.
+func WithInstanceID(id string) Option {
+	return func(w *Watcher) {
+		w.id = id
+	}
+}
+
+// WithActor sets the actor recorded in the messages published by the watcher, e.g. the
+// service name (defaults to none). Messages published with [Watcher.Publish] record the
+// actor of their context instead, if set with [adapter.WithActor].
+func WithActor(actor string) Option {
+	return func(w *Watcher) {
+		w.actor = actor
+	}
+}
+
+// WithLogger sets the logger of the receive, callback and close errors of the watcher
+// (defaults to the standard logger).
+func WithLogger(logger *log.Logger) Option {
+	return func(w *Watcher) {
+		w.logger = logger
+	}
+}
+
+// WithDebounce sets the debounce window of the watcher (defaults to none). The messages
+// received within the window after a message are coalesced into one callback, so that a
+// burst of changes triggers one reload: rules added or removed by messages of the same
+// operation and policy type are merged into one [Message], and other bursts are delivered
+// as an [OpUpdate] message, which reloads the whole policy.
+func WithDebounce(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// New opens the topic and the subscription at the URLs, and starts receiving messages. The
+// URL schemes must be registered by blank-importing their watcher/drivers subpackages, e.g.
+// "gcppubsub://projects/my-project/topics/casbin" and
+// "gcppubsub://projects/my-project/subscriptions/casbin-node-1". Each enforcer needs its
+// own subscription, so that every enforcer receives every update.
+func New(ctx context.Context, topicURL, subscriptionURL string, opts ...Option) (*Watcher, error) {
+	topic, err := pubsub.OpenTopic(ctx, topicURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not open topic: %w", err)
+	}
+	sub, err := pubsub.OpenSubscription(ctx, subscriptionURL)
+	if err != nil {
+		_ = topic.Shutdown(ctx)
+		return nil, fmt.Errorf("could not open subscription: %w", err)
+	}
+	reopen := func(ctx context.Context) (*pubsub.Subscription, error) {
+		return pubsub.OpenSubscription(ctx, subscriptionURL)
+	}
+	return newWatcher(topic, sub, reopen, opts...), nil
+}
+
+// NewWithTopic returns a watcher over an opened topic and subscription, and starts
+// receiving messages. The watcher shuts them down when it is closed.
+func NewWithTopic(topic *pubsub.Topic, sub *pubsub.Subscription, opts ...Option) *Watcher {
+	return newWatcher(topic, sub, nil, opts...)
+}
+
+// newWatcher returns a watcher over an opened topic and subscription, which is reopened
+// with reopen after it fails if reopen is not nil, and starts receiving messages.
+func newWatcher(topic *pubsub.Topic, sub *pubsub.Subscription, reopen func(ctx context.Context) (*pubsub.Subscription, error), opts ...Option) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{topic: topic, sub: sub, reopen: reopen, dedupSize: defaultDedupSize, cancel: cancel, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.id == "" {
+		w.id = randomID()
+	}
+	if w.logger == nil {
+		w.logger = log.Default()
+	}
+	if w.dedupSize > 0 {
+		w.seen = newSeenIDs(w.dedupSize)
+	}
+	go w.receive(ctx)
+	return w
+}
+
+// ID returns the instance ID of the watcher.
+func (w *Watcher) ID() string {
+	return w.id
+}
+
+// randomID returns a random hex ID.
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// receive calls the update callback for each message of the subscription published by
+// other watchers, skipping redelivered messages, until ctx is done. Messages are acked once
+// the callback returns, so that a message is redelivered if the watcher stops before. Failed
+// receives are logged and retried with exponential backoff; since a subscription returns
+// the same error once it failed, a subscription opened by New is reopened before retrying.
+func (w *Watcher) receive(ctx context.Context) {
+	defer close(w.done)
+	backoff := receiveBackoff
+	for {
+		msg, err := w.sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.logger.Printf("watcher receive error: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxReceiveBackoff)
+			if w.reopen != nil {
+				sub, err := w.reopen(ctx)
+				if err != nil {
+					w.logger.Printf("watcher reopen subscription error: %v", err)
+					continue
+				}
+				_ = w.sub.Shutdown(ctx)
+				w.sub = sub
+			}
+			continue
+		}
+		backoff = receiveBackoff
+
+		body := string(msg.Body)
+		m := DecodeMessage(body)
+		origin, ok := msg.Metadata[OriginMetadataKey]
+		if !ok {
+			origin = m.Origin
+		}
+		if origin == w.id || (w.seen != nil && m.ID != "" && w.seen.add(m.ID)) {
+			msg.Ack()
+			continue
+		}
+		w.deliver(body, msg.Ack)
+	}
+}
+
+// deliver calls the update callback with the message body, or adds it to the debounce
+// window, and then acks the message.
+func (w *Watcher) deliver(body string, ack func()) {
+	if w.debounce <= 0 {
+		w.call(body)
+		ack()
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.pending = append(w.pending, body)
+	w.acks = append(w.acks, ack)
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.debounce, w.flush)
+	}
+}
+
+// flush calls the update callback with the coalesced messages of the debounce window, and
+// then acks them.
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	bodies, acks := w.pending, w.acks
+	w.pending, w.acks, w.timer = nil, nil, nil
+	closed := w.closed
+	w.mu.Unlock()
+	if closed || len(bodies) == 0 {
+		return
+	}
+	w.call(coalesce(bodies))
+	for _, ack := range acks {
+		ack()
+	}
+}
+
+// call calls the update callback, if any, unless the watcher is shut down.
+func (w *Watcher) call(body string) {
+	w.callMu.Lock()
+	defer w.callMu.Unlock()
+	w.mu.Lock()
+	callback, closed := w.callback, w.closed
+	w.mu.Unlock()
+	if callback != nil && !closed {
+		callback(body)
+	}
+}
+
+// SetUpdateCallback implements [persist.Watcher]. The callback is called with the body of
+// every message received from other watchers, a [Message] in JSON.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update implements [persist.Watcher]. It publishes an [OpUpdate] message to the topic.
+func (w *Watcher) Update() error {
+	return w.publish(Message{Operation: OpUpdate})
+}
+
+// send publishes the message body to the topic, with the instance ID of the watcher.
+func (w *Watcher) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	return w.topic.Send(ctx, &pubsub.Message{
+		Body:     body,
+		Metadata: map[string]string{OriginMetadataKey: w.id},
+	})
+}
+
+// Close implements [persist.Watcher]. It shuts the watcher down, logging errors.
+func (w *Watcher) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	if err := w.Shutdown(ctx); err != nil {
+		w.logger.Printf("watcher close error: %v", err)
+	}
+}
+
+// Shutdown stops receiving messages, drops the messages of the debounce window without
+// acking them, and shuts down the subscription and the topic, which flushes the messages
+// being published. It waits for a running callback to return, and the callback is not
+// called after Shutdown returns, so it must not be called from the callback. Calls after
+// the first return nil.
+func (w *Watcher) Shutdown(ctx context.Context) error {
+	var err error
+	w.once.Do(func() {
+		w.cancel()
+		<-w.done
+		w.callMu.Lock()
+		w.mu.Lock()
+		w.closed = true
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.pending, w.acks, w.timer = nil, nil, nil
+		w.mu.Unlock()
+		w.callMu.Unlock()
+
+		err = errors.Join(w.sub.Shutdown(ctx), w.topic.Shutdown(ctx))
+	})
+	return err
+}
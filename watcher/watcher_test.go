@@ -0,0 +1,497 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	adapter "github.com/bartventer/casbin-go-cloud-adapter"
+	_ "github.com/bartventer/casbin-go-cloud-adapter/drivers/sqlitedocstore"
+	_ "github.com/bartventer/casbin-go-cloud-adapter/watcher/drivers/mempubsub"
+	"github.com/casbin/casbin/v2"
+	"gocloud.dev/docstore"
+	"gocloud.dev/docstore/memdocstore"
+	"gocloud.dev/gcerrors"
+)
+
+func TestWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-" + randomID()
+	w1, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url, WithInstanceID("node-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	updates := make(chan string, 1)
+	if err := w2.SetUpdateCallback(func(msg string) { updates <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	own := make(chan string, 1)
+	if err := w1.SetUpdateCallback(func(msg string) { own <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w1.Update(); err != nil {
+		t.Fatalf("Expected Update() to be successful; got %v", err)
+	}
+	select {
+	case msg := <-updates:
+		if m := DecodeMessage(msg); m.Operation != OpUpdate || m.Version != MessageVersion || m.Origin != w1.ID() || m.Timestamp.IsZero() {
+			t.Errorf("Expected an update message of w1; got %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the update callback to be called")
+	}
+	select {
+	case <-own:
+		t.Error("Expected the watcher to skip its own update")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if w2.ID() != "node-2" || w1.ID() == "" {
+		t.Errorf("Expected the configured and random instance IDs; got %q and %q", w2.ID(), w1.ID())
+	}
+	w2.Close()
+	w2.Close() // Close is idempotent.
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-debounce-" + randomID()
+	w1, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url, WithDebounce(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	updates := make(chan string, 10)
+	if err := w2.SetUpdateCallback(func(msg string) { updates <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	receive := func() string {
+		t.Helper()
+		select {
+		case msg := <-updates:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the update callback to be called")
+		}
+		return ""
+	}
+
+	// A burst of added rules is coalesced into one message.
+	for _, sub := range []string{"alice", "bob", "carol"} {
+		if err := w1.UpdateForAddPolicy("p", "p", sub, "data1", "read"); err != nil {
+			t.Fatalf("Expected UpdateForAddPolicy() to be successful; got %v", err)
+		}
+	}
+	if err := w1.UpdateForAddPolicies("p", "p", []string{"dave", "data1", "read"}); err != nil {
+		t.Fatalf("Expected UpdateForAddPolicies() to be successful; got %v", err)
+	}
+	if m := DecodeMessage(receive()); m.Operation != adapter.OpAddPolicies || len(m.Rules) != 4 {
+		t.Errorf("Expected the added rules to be coalesced; got %+v", m)
+	}
+
+	// Other bursts are coalesced into a reload.
+	if err := w1.UpdateForAddPolicy("p", "p", "alice", "data2", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w1.UpdateForRemovePolicy("p", "p", "bob", "data1", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if m := DecodeMessage(receive()); m.Operation != OpUpdate {
+		t.Errorf("Expected mixed changes to be coalesced into a reload; got %+v", m)
+	}
+	select {
+	case msg := <-updates:
+		t.Errorf("Expected one callback per burst; got %q", msg)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatcherShutdownCallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-shutdown-" + randomID()
+	w1, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url, WithDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	if err := w2.SetUpdateCallback(func(string) {
+		calls++
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w1.Update(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the update callback to be called")
+	}
+
+	// Shutdown waits for the running callback.
+	done := make(chan error, 1)
+	go func() { done <- w2.Shutdown(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Shutdown() to wait for the running callback; got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected Shutdown() to be successful; got %v", err)
+	}
+
+	// Callbacks racing with Shutdown are dropped.
+	w2.call("late")
+	if calls != 1 {
+		t.Errorf("Expected no callback after Shutdown(); got %d calls", calls)
+	}
+}
+
+func TestWatcherActor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-" + randomID()
+	w1, err := New(ctx, url, url, WithActor("policy-service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	updates := make(chan string, 2)
+	if err := w2.SetUpdateCallback(func(msg string) { updates <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w1.UpdateForAddPolicy("p", "p", "alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected UpdateForAddPolicy() to be successful; got %v", err)
+	}
+	m := Message{Operation: adapter.OpRemovePolicy, Sec: "p", PType: "p", Rules: [][]string{{"alice", "data1", "read"}}}
+	if err := w1.Publish(adapter.WithActor(ctx, "alice@corp"), m); err != nil {
+		t.Fatalf("Expected Publish() to be successful; got %v", err)
+	}
+	actors := make(map[adapter.Operation]string)
+	for len(actors) < 2 {
+		select {
+		case msg := <-updates:
+			m := DecodeMessage(msg)
+			actors[m.Operation] = m.Actor
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the update callback to be called")
+		}
+	}
+	want := map[adapter.Operation]string{adapter.OpAddPolicy: "policy-service", adapter.OpRemovePolicy: "alice@corp"}
+	if !reflect.DeepEqual(actors, want) {
+		t.Errorf("Expected the actors %v; got %v", want, actors)
+	}
+}
+
+func TestWatcherReceiveRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-retry-" + randomID()
+	w1, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	updates := make(chan string, 1)
+	if err := w2.SetUpdateCallback(func(msg string) {
+		select {
+		case updates <- msg:
+		default:
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed subscription is reopened, rather than ending the receive loop.
+	if err := w2.sub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := w1.Update(); err != nil {
+			t.Fatalf("Expected Update() to be successful; got %v", err)
+		}
+		select {
+		case <-updates:
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Expected the update callback to be called after the subscription was reopened")
+		}
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Message
+	}{
+		{
+			name: "Message",
+			body: `{"version":1,"op":"add_policy","sec":"p","ptype":"p","rules":[["alice","data1","read"]],"origin":"node-1","timestamp":"2024-01-02T03:04:05Z"}`,
+			want: Message{Version: 1, Operation: adapter.OpAddPolicy, Sec: "p", PType: "p", Rules: [][]string{{"alice", "data1", "read"}}, Origin: "node-1", Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		{
+			name: "NewerVersion",
+			body: `{"version":2,"op":"add_policy","sec":"p","ptype":"p","rules":[["alice","data1","read"]],"tenant":"acme"}`,
+			want: Message{Version: 2, Operation: adapter.OpAddPolicy, Sec: "p", PType: "p", Rules: [][]string{{"alice", "data1", "read"}}},
+		},
+		{
+			name: "Unversioned",
+			body: "casbin policy updated",
+			want: Message{Version: MessageVersion, Operation: OpUpdate},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeMessage(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v; got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWatcherDedup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := "mem://casbin-watcher-dedup-" + randomID()
+	w1, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w1.Close()
+	w2, err := New(ctx, url, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Close()
+
+	updates := make(chan string, 10)
+	if err := w2.SetUpdateCallback(func(msg string) { updates <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	// Redeliveries of a message have the same body.
+	body := `{"version":1,"id":"msg-1","op":"update","origin":"node-1"}`
+	for i := 0; i < 3; i++ {
+		if err := w1.send([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the update callback to be called")
+	}
+	select {
+	case <-updates:
+		t.Error("Expected redelivered messages to be skipped")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	seen := newSeenIDs(2)
+	for _, id := range []string{"a", "b", "c"} {
+		if seen.add(id) {
+			t.Errorf("Expected %q to be new", id)
+		}
+	}
+	if seen.add("a") {
+		t.Error("Expected the least recently added ID to be evicted")
+	}
+}
+
+func TestNewSyncedEnforcer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db") + "?table=casbin_rule&key_field=id"
+	pubsubURL := "mem://casbin-watcher-enforcer-" + randomID()
+	e1, err := NewSyncedEnforcer(ctx, "../testdata/rbac_model.conf", dbURL, pubsubURL)
+	if err != nil {
+		t.Fatalf("Expected NewSyncedEnforcer() to be successful; got %v", err)
+	}
+	defer e1.Close()
+	e2, err := NewSyncedEnforcer(ctx, "../testdata/rbac_model.conf", dbURL, pubsubURL)
+	if err != nil {
+		t.Fatalf("Expected NewSyncedEnforcer() to be successful; got %v", err)
+	}
+	defer e2.Close()
+
+	if _, err := e1.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if ok, _ := e2.Enforce("alice", "data1", "read"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the other enforcer to reload the added policy")
+		}
+	}
+
+	if err := e1.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected Shutdown() to be successful; got %v", err)
+	}
+	if _, err := e1.AddPolicy("bob", "data2", "write"); !errors.Is(err, adapter.ErrShutdown) {
+		t.Errorf("Expected changes after Shutdown() to fail with ErrShutdown; got %v", err)
+	}
+}
+
+// recordingOpener opens memdocstore collections and records them.
+type recordingOpener struct {
+	colls []*docstore.Collection
+}
+
+func (o *recordingOpener) OpenCollectionURL(ctx context.Context, u *url.URL) (*docstore.Collection, error) {
+	coll, err := (&memdocstore.URLOpener{}).OpenCollectionURL(ctx, u)
+	if err == nil {
+		o.colls = append(o.colls, coll)
+	}
+	return coll, err
+}
+
+func TestSyncedEnforcerClosesAdapter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	closed := func(o *recordingOpener) bool {
+		t.Helper()
+		if len(o.colls) != 1 {
+			t.Fatalf("Expected the adapter to open a collection; got %d", len(o.colls))
+		}
+		return gcerrors.Code(o.colls[0].Get(ctx, &adapter.CasbinRule{ID: "x"})) == gcerrors.FailedPrecondition
+	}
+	withOpener := func(o *recordingOpener) EnforcerOption {
+		return WithAdapterOptions(func(c *adapter.Config) {
+			c.Openers = adapter.URLOpeners{"mem": o}
+		})
+	}
+	pubsubURL := "mem://casbin-watcher-close-" + randomID()
+
+	for name, newEnforcer := range map[string]func(o *recordingOpener) error{
+		"model": func(o *recordingOpener) error {
+			_, err := NewSyncedEnforcer(ctx, "../testdata/missing.conf", "mem://casbin_rule_close_model/id", pubsubURL, withOpener(o))
+			return err
+		},
+		"watcher": func(o *recordingOpener) error {
+			_, err := NewSyncedEnforcer(ctx, "../testdata/rbac_model.conf", "mem://casbin_rule_close_watcher/id", "unknown://topic", withOpener(o))
+			return err
+		},
+	} {
+		o := &recordingOpener{}
+		if err := newEnforcer(o); err == nil {
+			t.Fatalf("Expected NewSyncedEnforcer() to fail for the %s", name)
+		}
+		if !closed(o) {
+			t.Errorf("Expected NewSyncedEnforcer() to close the adapter when creating the %s fails", name)
+		}
+	}
+
+	o := &recordingOpener{}
+	e, err := NewSyncedEnforcer(ctx, "../testdata/rbac_model.conf", "mem://casbin_rule_close/id", pubsubURL, withOpener(o))
+	if err != nil {
+		t.Fatalf("Expected NewSyncedEnforcer() to be successful; got %v", err)
+	}
+	e.Close()
+	if !closed(o) {
+		t.Error("Expected Close() to close the adapter")
+	}
+	e.Close() // Close is idempotent.
+}
+
+func TestBindCachedEnforcer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := "sqlitedoc://" + filepath.Join(t.TempDir(), "policy.db") + "?table=casbin_rule&key_field=id"
+	pubsubURL := "mem://casbin-watcher-cached-" + randomID()
+	e1, err := NewSyncedEnforcer(ctx, "../testdata/rbac_model.conf", dbURL, pubsubURL)
+	if err != nil {
+		t.Fatalf("Expected NewSyncedEnforcer() to be successful; got %v", err)
+	}
+	defer e1.Close()
+
+	a, err := adapter.New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := casbin.NewSyncedCachedEnforcer("../testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(ctx, pubsubURL, pubsubURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := e2.SetWatcher(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := BindCachedEnforcer(w, e2); err != nil {
+		t.Fatalf("Expected BindCachedEnforcer() to be successful; got %v", err)
+	}
+
+	// The denial is cached.
+	if ok, _ := e2.Enforce("alice", "data1", "read"); ok {
+		t.Fatal("Expected the request to be denied")
+	}
+	if _, err := e1.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if ok, _ := e2.Enforce("alice", "data1", "read"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the cached decision to be invalidated")
+		}
+	}
+}
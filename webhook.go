@@ -0,0 +1,138 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhookRetries int           = 3
+	defaultWebhookBackoff time.Duration = 200 * time.Millisecond
+	defaultWebhookTimeout time.Duration = 5 * time.Second
+	// SignatureHeader is the request header holding the HMAC-SHA256 signature of a webhook payload,
+	// in the form "sha256=<hex digest>".
+	SignatureHeader = "X-Casbin-Signature"
+)
+
+// WebhookNotifier is a [Notifier] that POSTs each [ChangeEvent] as JSON to webhook URLs.
+//
+// Failed deliveries are retried with exponential backoff on network errors, 429 and 5xx
+// responses. If Secret is set, each request carries the signature of its body in
+// [SignatureHeader], so receivers can verify that the payload is authentic.
+//
+// Notifiers are called in the policy change that produced the event, so unless
+// Config.Outbox is set, each change waits for its delivery. The URLs are delivered to
+// concurrently, and the delivery, retries included, is bounded by Timeout (and by
+// Config.Timeout), after which the change returns and the failure is logged. With
+// Config.Outbox the events are written with the changes and delivered in the background by
+// RunOutbox instead, so slow receivers do not delay changes and events are not lost.
+type WebhookNotifier struct {
+	URLs       []string      // the webhook URLs
+	Secret     []byte        // the HMAC-SHA256 key used to sign payloads (unsigned if empty)
+	Client     *http.Client  // the HTTP client (defaults to http.DefaultClient)
+	MaxRetries int           // the maximum number of retries per URL (defaults to 3, negative disables retries)
+	Backoff    time.Duration // the delay before the first retry, doubled on each retry (defaults to 200ms)
+	Timeout    time.Duration // the maximum duration of the delivery of an event, retries included (defaults to 5s)
+}
+
+var _ Notifier = (*WebhookNotifier)(nil)
+
+// Notify implements [Notifier]. It delivers the event to every URL concurrently, within
+// Timeout, and returns the combined errors of the URLs the event could not be delivered to.
+func (w *WebhookNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errs := make([]error, len(w.URLs))
+	var wg sync.WaitGroup
+	for i, url := range w.URLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.deliver(ctx, url, "application/json", body); err != nil {
+				errs[i] = fmt.Errorf("webhook %s: %w", url, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// deliver POSTs the body to the URL, retrying transient failures.
+func (w *WebhookNotifier) deliver(ctx context.Context, url, contentType string, body []byte) error {
+	retries := w.MaxRetries
+	if retries == 0 {
+		retries = defaultWebhookRetries
+	}
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, url, contentType, body)
+		if err == nil || !retry || attempt >= retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure may be retried.
+func (w *WebhookNotifier) post(ctx context.Context, url, contentType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(w.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// Sign returns the signature of a webhook payload for [SignatureHeader].
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	secret := []byte("s3cret")
+	var (
+		mu       sync.Mutex
+		attempts int
+		events   []ChangeEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign(secret, body); got != want {
+			t.Errorf("Expected signature %s; got %s", want, got)
+		}
+		var event ChangeEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:       "mem://casbin_rule_webhook/id",
+		Actor:     "policy-admin",
+		Notifiers: []Notifier{&WebhookNotifier{URLs: []string{srv.URL}, Secret: secret, Backoff: time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("Expected UpdatePolicy() to be successful; got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 || len(events) != 2 {
+		t.Fatalf("Expected 2 events delivered in 3 attempts; got %d events in %d attempts", len(events), attempts)
+	}
+	if e := events[0]; e.Operation != OpAddPolicy || e.PType != "p" || e.Actor != "policy-admin" || e.Timestamp.IsZero() || len(e.Rules) != 1 {
+		t.Errorf("Unexpected add event %+v", e)
+	}
+	if e := events[1]; e.Operation != OpUpdatePolicy || e.Rules[0][2] != "write" || e.OldRules[0][2] != "read" {
+		t.Errorf("Unexpected update event %+v", e)
+	}
+}
+
+func TestWebhookNotifierClientError(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	n := &WebhookNotifier{URLs: []string{srv.URL}, Backoff: time.Millisecond}
+	if err := n.Notify(context.Background(), ChangeEvent{Operation: OpSavePolicy}); err == nil {
+		t.Error("Expected Notify() to fail on a client error")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected client errors not to be retried; got %d attempts", attempts)
+	}
+}
+
+func TestWebhookNotifierTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL: "mem://casbin_rule_webhook_timeout/id",
+		Notifiers: []Notifier{&WebhookNotifier{
+			URLs:    []string{srv.URL, srv.URL, srv.URL},
+			Backoff: time.Millisecond,
+			Timeout: 100 * time.Millisecond,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	// The URLs are delivered to concurrently, so the change waits for a single timeout.
+	start := time.Now()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the delivery to be bounded by the timeout; took %v", elapsed)
+	}
+}
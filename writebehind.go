@@ -0,0 +1,282 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultWriteBehindLimit is the number of buffered rules that triggers an early flush.
+const defaultWriteBehindLimit = 1000
+
+// bufferedChange is a rule added or removed while Config.WriteBehind is set, which is not
+// written yet.
+type bufferedChange struct {
+	remove bool
+	sec    string
+	ptype  string
+	rule   []string
+	attrs  ruleAttrs // the attributes of the added rule
+	at     time.Time // when the rule was added, for Config.RuleTTL
+	create bool      // whether the added rule is written with the write mode, rather than upserted
+}
+
+// bufferedBatch is a batch of buffered changes, which are written together.
+type bufferedBatch struct {
+	changes   map[string]bufferedChange // the latest change of each rule, by [ruleKey]
+	events    []ChangeEvent             // the events of the buffered changes, in order
+	journaled []string                  // the IDs of the journal entries of the changes, if Config.JournalURL is set
+}
+
+// writeBuffer holds the changes buffered while Config.WriteBehind is set.
+type writeBuffer struct {
+	flushMu sync.Mutex    // serializes flushes, so buffered changes are written in order
+	mu      sync.Mutex    // guards the fields below
+	batch   bufferedBatch // the buffered changes
+	timer   *time.Timer   // flushes the buffer, if changes are buffered
+}
+
+// newWriteBuffer returns the write buffer for the configuration, or nil if write-behind
+// is disabled.
+func newWriteBuffer(config *Config) *writeBuffer {
+	if config.WriteBehind <= 0 {
+		return nil
+	}
+	if config.WriteBehindLimit <= 0 {
+		config.WriteBehindLimit = defaultWriteBehindLimit
+	}
+	return &writeBuffer{batch: bufferedBatch{changes: make(map[string]bufferedChange)}}
+}
+
+// WithWriteBehind returns the option that sets Config.WriteBehind, so that rules added and
+// removed through the adapter are written in batches every interval.
+func WithWriteBehind(interval time.Duration) Option {
+	return func(c *Config) {
+		c.WriteBehind = interval
+	}
+}
+
+// bufferChanges buffers the rules added or removed by a change, replacing earlier buffered
+// changes of the same rules, and flushes the buffer once Config.WriteBehindLimit rules are
+// buffered. If Config.JournalURL is set, the change is journaled first. The event records
+// the actor of ctx, since it is delivered when the buffer is flushed. Added rules are written
+// with the attributes.
+func (a *adapter) bufferChanges(ctx context.Context, event ChangeEvent, attrs ruleAttrs) error {
+	event.Actor = a.actor(ctx)
+	now := time.Now()
+	var journalID string
+	if a.journal != nil {
+		var err error
+		if journalID, err = a.journalChange(ctx, event, attrs, now); err != nil {
+			return err
+		}
+	}
+	if !a.bufferEvent(event, attrs, now, journalID, true) {
+		return nil
+	}
+	ctx, cancel := a.withTimeout(ctx, opWrite)
+	defer cancel()
+	return a.flush(ctx)
+}
+
+// bufferEvent adds the rules of the change event, with the attributes of added rules, to the
+// buffer, and reports whether the buffer is full. Added rules are written with the write
+// mode if create is set, and upserted otherwise.
+func (a *adapter) bufferEvent(event ChangeEvent, attrs ruleAttrs, at time.Time, journalID string, create bool) bool {
+	remove := event.Operation == OpRemovePolicy || event.Operation == OpRemovePolicies
+	b := a.buffer
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, rule := range event.Rules {
+		key := ruleKey(a.ruleLine(event.PType, rule))
+		change := bufferedChange{remove: remove, sec: event.Sec, ptype: event.PType, rule: rule, attrs: attrs, at: at, create: create && !remove}
+		// A rule added again after a buffered removal or a failed flush may be stored, so it
+		// is upserted.
+		if prev, ok := b.batch.changes[key]; ok && (prev.remove || !prev.create) {
+			change.create = false
+		}
+		b.batch.changes[key] = change
+	}
+	b.batch.events = append(b.batch.events, event)
+	if journalID != "" {
+		b.batch.journaled = append(b.batch.journaled, journalID)
+	}
+	b.schedule(a.config.WriteBehind, a.flushBuffered)
+	return len(b.batch.changes) >= a.config.WriteBehindLimit
+}
+
+// schedule starts the timer that flushes the buffer, unless it is running. b.mu must be held.
+func (b *writeBuffer) schedule(interval time.Duration, flush func()) {
+	if b.timer == nil {
+		b.timer = time.AfterFunc(interval, flush)
+	}
+}
+
+// take removes the buffered changes from the buffer and returns them.
+func (b *writeBuffer) take() bufferedBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.batch
+	b.batch = bufferedBatch{changes: make(map[string]bufferedChange)}
+	return batch
+}
+
+// restore puts back a batch that could not be written, except for the changes of rules
+// that were changed again in the meantime. The added rules may have been written by the
+// failed flush, so they are upserted when the batch is written again.
+func (b *writeBuffer) restore(batch bufferedBatch, interval time.Duration, flush func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, change := range batch.changes {
+		if _, ok := b.batch.changes[key]; !ok {
+			change.create = false
+			b.batch.changes[key] = change
+		}
+	}
+	b.batch.events = append(batch.events, b.batch.events...)
+	b.batch.journaled = append(batch.journaled, b.batch.journaled...)
+	b.schedule(interval, flush)
+}
+
+// Flush writes the changes buffered with Config.WriteBehind in batched action lists, and
+// notifies the configured notifiers of them. It is a no-op if write-behind is disabled.
+//
+// If the changes cannot be written, they stay buffered and Flush returns the error; the
+// next flush writes them again. With WriteCreate, rules that are stored by the time they are
+// flushed, e.g. because another instance added them concurrently, are not written: the
+// other changes are, and Flush returns a [*BatchError] of the stored rules wrapped with
+// ErrRuleExists.
+func (a *adapter) Flush(ctx context.Context) error {
+	if err := a.begin(); err != nil {
+		return err
+	}
+	defer a.end()
+
+	return a.flush(ctx)
+}
+
+// flush writes the buffered changes, if write-behind is enabled.
+func (a *adapter) flush(ctx context.Context) error {
+	b := a.buffer
+	if b == nil {
+		return nil
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	if a.collection == nil {
+		return errors.New("collection is closed")
+	}
+
+	batch := b.take()
+	if len(batch.changes) == 0 && len(batch.events) == 0 {
+		return nil
+	}
+	ctx, cancel := a.withTimeout(ctx, opBulk)
+	defer cancel()
+	existing, err := a.writeBuffered(ctx, batch.changes, batch.events)
+	if err != nil {
+		b.restore(batch, a.config.WriteBehind, a.flushBuffered)
+		return err
+	}
+	a.deleteJournaled(ctx, batch.journaled)
+	if len(existing) > 0 {
+		return existsError(&BatchError{Err: existing[0].Err, Failures: existing})
+	}
+	return nil
+}
+
+// writeBuffered writes the buffered changes in one batch of actions, and notifies their
+// events. Added rules are written with the write mode (see [adapter.WriteMode]); it returns
+// the failures of the rules that were not written because they are already stored.
+func (a *adapter) writeBuffered(ctx context.Context, changes map[string]bufferedChange, events []ChangeEvent) ([]RuleError, error) {
+	var actions []action
+	var removed []CasbinRule
+	for _, key := range sortedKeys(changes) {
+		change := changes[key]
+		if change.remove {
+			removed = append(removed, a.ruleLine(change.ptype, change.rule))
+			continue
+		}
+		line := a.newLine(change.sec, change.ptype, change.rule)
+		a.setTTL(&line, change.at)
+		a.setAttrs(&line, change.attrs)
+		kind := actionPut
+		if change.create {
+			kind = a.addKind()
+		}
+		actions = append(actions, action{kind: kind, line: &line})
+	}
+	removed, err := a.resolve(ctx, removed)
+	if err != nil {
+		return nil, err
+	}
+	for i := range removed {
+		actions = append(actions, action{kind: actionDelete, line: &removed[i]})
+	}
+
+	var existing []RuleError
+	err = a.commit(ctx, func(ctx context.Context, actions []action) error {
+		existing, err = a.runBuffered(ctx, actions)
+		return err
+	}, actions, events...)
+	return existing, err
+}
+
+// runBuffered runs the actions of a flush like [adapter.do], except that rules created
+// with WriteCreate that are already stored are skipped: the actions that were not applied
+// because of them are run again, and their failures are returned.
+func (a *adapter) runBuffered(ctx context.Context, actions []action) ([]RuleError, error) {
+	indexes := make([]int, len(actions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var existing []RuleError
+	for len(actions) > 0 {
+		applied, err := a.run(ctx, actions)
+		if err == nil {
+			break
+		}
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			return nil, err
+		}
+		exists := make(map[int]bool)
+		for _, f := range batchErr.Failures {
+			switch {
+			case errors.Is(f.Err, ErrRuleExists):
+				exists[f.Index] = true
+				f.Index = indexes[f.Index]
+				existing = append(existing, f)
+			case !errors.Is(f.Err, ErrNotAttempted):
+				return nil, err
+			}
+		}
+		var remaining []action
+		var remainingIndexes []int
+		for i := range actions {
+			if !applied[i] && !exists[i] {
+				remaining = append(remaining, actions[i])
+				remainingIndexes = append(remainingIndexes, indexes[i])
+			}
+		}
+		actions, indexes = remaining, remainingIndexes
+	}
+	return existing, nil
+}
+
+// flushBuffered flushes the buffer when its timer fires, logging errors.
+func (a *adapter) flushBuffered() {
+	if err := a.begin(); err != nil {
+		return // Shutdown flushes the buffer
+	}
+	defer a.end()
+
+	if err := a.flush(context.Background()); err != nil {
+		a.config.logger().Printf("flush buffered changes error: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestWriteBehind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &recordingNotifier{}
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_write_behind/id",
+		WriteBehind: time.Hour,
+		Notifiers:   []Notifier{n},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 0 {
+		t.Fatalf("Expected the changes to be buffered; got %v, %v", lines, err)
+	}
+	if len(n.events) != 0 {
+		t.Fatalf("Expected no events before the changes are written; got %v", n.events)
+	}
+
+	if err := a.Flush(ctx); err != nil {
+		t.Fatalf("Expected Flush() to be successful; got %v", err)
+	}
+	lines, err := a.collectAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Errorf("Expected the coalesced changes to store 2 rules; got %v", lines)
+	}
+	if len(n.events) != 3 {
+		t.Errorf("Expected an event for each change; got %v", n.events)
+	}
+
+	// Loads flush the buffer first, so the adapter reads its own writes.
+	if err := a.RemovePolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	e, err := casbin.NewEnforcer("testdata/rbac_model.conf", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ := e.GetPolicy(); len(policy) != 1 {
+		t.Errorf("Expected the buffered removal to be loaded; got %v", policy)
+	}
+}
+
+func TestWriteBehindInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := New(ctx, "mem://casbin_rule_write_behind_interval/id", WithWriteBehind(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lines, err := a.collectAll(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the buffered change to be written after the interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWriteBehindLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:              "mem://casbin_rule_write_behind_limit/id",
+		WriteBehind:      time.Hour,
+		WriteBehindLimit: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 2 {
+		t.Errorf("Expected a full buffer to be flushed; got %v, %v", lines, err)
+	}
+}
+
+func TestWriteBehindWriteMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_write_behind_mode/id",
+		WriteBehind: time.Hour,
+		WriteMode:   WriteCreate,
+		BatchSize:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_write_behind_mode/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(a, other)
+
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("Expected AddPolicies() to be successful; got %v", err)
+	}
+	// Another instance adds a buffered rule before the buffer is flushed.
+	if err := other.AddPolicy("p", "p", rules[0]); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	err = a.Flush(ctx)
+	if !errors.Is(err, ErrRuleExists) {
+		t.Fatalf("Expected ErrRuleExists for the stored rule; got %v", err)
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failures) != 1 || batchErr.Failures[0].Rule[0] != "alice" {
+		t.Errorf("Expected the stored rule to be reported; got %v", err)
+	}
+	if lines, err := a.collectAll(ctx, nil); err != nil || len(lines) != 3 {
+		t.Errorf("Expected the other buffered rules to be written; got %v, %v", lines, err)
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Errorf("Expected the stored rule not to stay buffered; got %v", err)
+	}
+
+	// A rule added again after a buffered removal is upserted.
+	if err := a.RemovePolicy("p", "p", rules[0]); err != nil {
+		t.Fatalf("Expected RemovePolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("p", "p", rules[0]); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.Flush(ctx); err != nil {
+		t.Errorf("Expected Flush() to be successful; got %v", err)
+	}
+}
+
+func TestWriteBehindDiscardLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	a, err := NewWithOption(ctx, &Config{
+		URL:         "mem://casbin_rule_write_behind_discard/id",
+		WriteBehind: time.Hour,
+		Logger:      log.New(&buf, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+
+	// Closing without a flush discards the buffered changes, which is logged with Config.Logger.
+	a.close()
+	if got := buf.String(); !strings.Contains(got, "close discarded 1 buffered changes") {
+		t.Errorf("Expected the discarded changes to be logged with the configured logger; got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRuleExists is returned by AddPolicy and AddPolicies with WriteCreate when a rule is
+// already stored, e.g. because another instance added it concurrently.
+var ErrRuleExists = errors.New("rule already exists")
+
+// WriteMode determines how AddPolicy and AddPolicies write rules.
+type WriteMode int
+
+const (
+	// WriteUpsert writes rules with docstore Put, which silently overwrites stored rules
+	// with the same ID. This is the default.
+	WriteUpsert WriteMode = iota
+	// WriteCreate writes rules with docstore Create, which fails for stored rules, so that
+	// concurrent duplicate additions are detected: the rules are reported with ErrRuleExists.
+	// Every provider implements Create atomically (a conditional put on DynamoDB, a
+	// precondition on Firestore, an insert on MongoDB).
+	//
+	// Duplicates are detected by ID, so WriteCreate requires content-derived IDs
+	// (IDStrategyHash or IDStrategyCanonical); with IDStrategyRandom rules are upserted.
+	// Rules buffered with Config.WriteBehind are created when the buffer is flushed, and
+	// the stored ones are reported by the flush (see Flush).
+	WriteCreate
+)
+
+// String returns the name of the write mode.
+func (m WriteMode) String() string {
+	switch m {
+	case WriteUpsert:
+		return "upsert"
+	case WriteCreate:
+		return "create"
+	default:
+		return fmt.Sprintf("WriteMode(%d)", int(m))
+	}
+}
+
+// WriteMode returns the write mode in effect for AddPolicy and AddPolicies: Config.WriteMode,
+// or WriteUpsert if the configured ID strategy cannot detect duplicates.
+func (a *adapter) WriteMode() WriteMode {
+	if a.config.WriteMode == WriteCreate && a.contentIDs() {
+		return WriteCreate
+	}
+	return WriteUpsert
+}
+
+// addKind returns the kind of the actions adding rules with AddPolicy and AddPolicies.
+func (a *adapter) addKind() actionKind {
+	if a.WriteMode() == WriteCreate {
+		return actionCreate
+	}
+	return actionPut
+}
+
+// existsError returns err, wrapping ErrRuleExists if a rule of the failed batch was
+// already stored.
+func existsError(err error) error {
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		return err
+	}
+	for _, f := range batchErr.Failures {
+		if errors.Is(f.Err, ErrRuleExists) {
+			return fmt.Errorf("%w: %w", ErrRuleExists, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWriteMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_write_mode/id", WriteMode: WriteCreate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.close()
+	if got := a.WriteMode(); got != WriteCreate {
+		t.Errorf("Expected the create write mode; got %v", got)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("Expected AddPolicy() to be successful; got %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, ErrRuleExists) {
+		t.Errorf("Expected ErrRuleExists for a duplicate rule; got %v", err)
+	}
+	err = a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"alice", "data1", "read"}})
+	if !errors.Is(err, ErrRuleExists) {
+		t.Fatalf("Expected ErrRuleExists for a duplicate rule; got %v", err)
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failures) != 1 || batchErr.Failures[0].Index != 1 {
+		t.Errorf("Expected the duplicate rule to be reported; got %v", err)
+	}
+
+	b, err := NewWithOption(ctx, &Config{URL: "mem://casbin_rule_write_mode_random/id", WriteMode: WriteCreate, IDStrategy: IDStrategyRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.close()
+	if got := b.WriteMode(); got != WriteUpsert {
+		t.Errorf("Expected random IDs to be upserted; got %v", got)
+	}
+}